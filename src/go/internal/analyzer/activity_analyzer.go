@@ -1,8 +1,13 @@
 package analyzer
 
 import (
+	"fmt"
+	"math"
 	"regexp"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"token-monitor/internal/types"
@@ -12,13 +17,20 @@ import (
 type ActivityAnalyzer struct {
 	patterns map[string]*regexp.Regexp
 	keywords map[string][]string
+
+	// durationTrimFraction 計算修剪平均時間時各去除頭尾的比例
+	durationTrimFraction float64
 }
 
+// DefaultDurationTrimFraction 預設的時間修剪比例（頭尾各 10%）
+const DefaultDurationTrimFraction = 0.1
+
 // NewActivityAnalyzer 建立新的活動分析器實例
 func NewActivityAnalyzer() *ActivityAnalyzer {
 	analyzer := &ActivityAnalyzer{
-		patterns: make(map[string]*regexp.Regexp),
-		keywords: make(map[string][]string),
+		patterns:             make(map[string]*regexp.Regexp),
+		keywords:             make(map[string][]string),
+		durationTrimFraction: DefaultDurationTrimFraction,
 	}
 
 	analyzer.initializePatterns()
@@ -71,19 +83,80 @@ func (aa *ActivityAnalyzer) initializeKeywords() {
 	}
 }
 
+// 分類評分權重
+const (
+	patternMatchWeight = 3 // 模式匹配權重較高
+	keywordMatchWeight = 1
+)
+
+// PatternHit 分類時命中的模式或關鍵字
+type PatternHit struct {
+	ActivityType types.ActivityType `json:"activity_type"`
+	Kind         string             `json:"kind"`  // pattern 或 keyword
+	Match        string             `json:"match"` // 命中的文字（模式為實際匹配的片段）
+	Weight       int                `json:"weight"`
+}
+
 // ClassifyActivity 分析內容並分類活動類型
 func (aa *ActivityAnalyzer) ClassifyActivity(content string) types.ActivityType {
 	if content == "" {
 		return types.ActivityTypeChat // 預設為聊天類型
 	}
 
+	scores := make(map[types.ActivityType]int)
+	for _, hit := range aa.collectPatternHits(content) {
+		scores[hit.ActivityType] += hit.Weight
+	}
+
+	// 找出得分最高的活動類型
+	maxScore := 0
+	bestActivity := types.ActivityChat // 預設活動類型
+
+	for activityType, score := range scores {
+		if score > maxScore {
+			maxScore = score
+			bestActivity = activityType
+		}
+	}
+
+	return bestActivity
+}
+
+// ExplainClassification 列出內容命中的模式與關鍵字及其權重，依權重由高至低排序
+func (aa *ActivityAnalyzer) ExplainClassification(content string) []PatternHit {
+	hits := aa.collectPatternHits(content)
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Weight != hits[j].Weight {
+			return hits[i].Weight > hits[j].Weight
+		}
+		if hits[i].ActivityType != hits[j].ActivityType {
+			return hits[i].ActivityType < hits[j].ActivityType
+		}
+		return hits[i].Match < hits[j].Match
+	})
+
+	return hits
+}
+
+// collectPatternHits 收集內容命中的模式與關鍵字
+func (aa *ActivityAnalyzer) collectPatternHits(content string) []PatternHit {
+	hits := []PatternHit{}
+	if content == "" {
+		return hits
+	}
+
 	content = strings.ToLower(content)
-	scores := make(map[string]int)
 
 	// 使用正規表達式模式評分
 	for activityType, pattern := range aa.patterns {
-		if pattern.MatchString(content) {
-			scores[activityType] += 3 // 模式匹配權重較高
+		if match := pattern.FindString(content); match != "" {
+			hits = append(hits, PatternHit{
+				ActivityType: types.StringToActivityType(activityType),
+				Kind:         "pattern",
+				Match:        match,
+				Weight:       patternMatchWeight,
+			})
 		}
 	}
 
@@ -91,23 +164,17 @@ func (aa *ActivityAnalyzer) ClassifyActivity(content string) types.ActivityType
 	for activityType, keywords := range aa.keywords {
 		for _, keyword := range keywords {
 			if strings.Contains(content, strings.ToLower(keyword)) {
-				scores[activityType]++
+				hits = append(hits, PatternHit{
+					ActivityType: types.StringToActivityType(activityType),
+					Kind:         "keyword",
+					Match:        keyword,
+					Weight:       keywordMatchWeight,
+				})
 			}
 		}
 	}
 
-	// 找出得分最高的活動類型
-	maxScore := 0
-	bestActivity := "chat" // 預設活動類型
-
-	for activityType, score := range scores {
-		if score > maxScore {
-			maxScore = score
-			bestActivity = activityType
-		}
-	}
-
-	return types.StringToActivityType(bestActivity)
+	return hits
 }
 
 // AnalyzeActivityBatch 批次分析多個活動
@@ -121,6 +188,42 @@ func (aa *ActivityAnalyzer) AnalyzeActivityBatch(contents []string) []types.Acti
 	return results
 }
 
+// AnalyzeActivityBatchParallel 以多個 worker 並行分析多個活動，結果順序與輸入相同（workers <= 0 時使用 CPU 數量）。
+// 模式與關鍵字在初始化後只會被讀取，編譯後的正規表達式可安全地並行使用。
+func (aa *ActivityAnalyzer) AnalyzeActivityBatchParallel(contents []string, workers int) []types.ActivityType {
+	results := make([]types.ActivityType, len(contents))
+	if len(contents) == 0 {
+		return results
+	}
+
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(contents) {
+		workers = len(contents)
+	}
+
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				results[i] = aa.ClassifyActivity(contents[i])
+			}
+		}()
+	}
+
+	for i := range contents {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+
+	return results
+}
+
 // GenerateActivitySummary 生成活動摘要統計
 func (aa *ActivityAnalyzer) GenerateActivitySummary(activities []types.Activity) types.ActivitySummary {
 	summary := types.ActivitySummary{
@@ -129,6 +232,9 @@ func (aa *ActivityAnalyzer) GenerateActivitySummary(activities []types.Activity)
 		TokenUsage:      make(map[types.ActivityType]types.TokenUsage),
 		TimeSpent:       make(map[types.ActivityType]time.Duration),
 		GeneratedAt:     time.Now(),
+
+		TokenPercentages: make(map[types.ActivityType]float64),
+		CountPercentages: make(map[types.ActivityType]float64),
 	}
 
 	// 統計各活動類型的數量和 Token 使用量
@@ -156,6 +262,41 @@ func (aa *ActivityAnalyzer) GenerateActivitySummary(activities []types.Activity)
 		summary.TotalTokens.TotalTokens += usage.TotalTokens
 	}
 
+	// 計算各活動類型的 Token 與數量佔比
+	for activityType, count := range summary.ActivityCounts {
+		summary.CountPercentages[activityType] = float64(count) / float64(summary.TotalActivities) * 100
+		if summary.TotalTokens.TotalTokens > 0 {
+			tokens := summary.TokenUsage[activityType].TotalTokens
+			summary.TokenPercentages[activityType] = float64(tokens) / float64(summary.TotalTokens.TotalTokens) * 100
+		}
+	}
+
+	return summary
+}
+
+// DefaultActivityWeight 未設定權重的活動類型使用的預設權重
+const DefaultActivityWeight = 1.0
+
+// GenerateWeightedSummary 生成套用活動權重的摘要統計，加權 Token 總量為各活動 Token 數乘以其類型權重之和
+func (aa *ActivityAnalyzer) GenerateWeightedSummary(activities []types.Activity, weights map[types.ActivityType]float64) types.WeightedActivitySummary {
+	summary := types.WeightedActivitySummary{
+		ActivitySummary:      aa.GenerateActivitySummary(activities),
+		Weights:              make(map[types.ActivityType]float64),
+		WeightedTokensByType: make(map[types.ActivityType]float64),
+	}
+
+	for activityType, usage := range summary.TokenUsage {
+		weight, exists := weights[activityType]
+		if !exists {
+			weight = DefaultActivityWeight
+		}
+
+		weighted := float64(usage.TotalTokens) * weight
+		summary.Weights[activityType] = weight
+		summary.WeightedTokensByType[activityType] = weighted
+		summary.WeightedTokenTotal += weighted
+	}
+
 	return summary
 }
 
@@ -166,6 +307,8 @@ func (aa *ActivityAnalyzer) CalculateEfficiencyMetrics(data types.ActivityData)
 		AverageTokensPerActivity: make(map[types.ActivityType]float64),
 		AverageTimePerActivity:   make(map[types.ActivityType]time.Duration),
 		TokensPerMinute:          make(map[types.ActivityType]float64),
+
+		AverageTimePerActivityTrimmed: make(map[types.ActivityType]time.Duration),
 	}
 
 	// 計算各活動類型的平均指標
@@ -196,11 +339,54 @@ func (aa *ActivityAnalyzer) CalculateEfficiencyMetrics(data types.ActivityData)
 		if totalTime.Minutes() > 0 {
 			metrics.TokensPerMinute[activityType] = float64(totalTokens) / totalTime.Minutes()
 		}
+
+		// 修剪後的平均時間
+		if trimmed, ok := trimmedMeanDuration(activities, aa.durationTrimFraction); ok {
+			metrics.AverageTimePerActivityTrimmed[activityType] = trimmed
+		}
 	}
 
 	return metrics
 }
 
+// SetDurationTrimFraction 設定修剪平均時間時頭尾各去除的比例（0 <= fraction < 0.5）
+func (aa *ActivityAnalyzer) SetDurationTrimFraction(fraction float64) error {
+	if math.IsNaN(fraction) || fraction < 0 || fraction >= 0.5 {
+		return fmt.Errorf("trim fraction must be in [0, 0.5): %v", fraction)
+	}
+	aa.durationTrimFraction = fraction
+	return nil
+}
+
+// trimmedMeanDuration 計算修剪平均時間：忽略缺少時間或非正值的活動，並各去除頭尾 fraction 比例
+func trimmedMeanDuration(activities []types.Activity, fraction float64) (time.Duration, bool) {
+	durations := make([]time.Duration, 0, len(activities))
+	for _, activity := range activities {
+		if activity.StartTime.IsZero() || activity.EndTime.IsZero() {
+			continue
+		}
+		if duration := activity.EndTime.Sub(activity.StartTime); duration > 0 {
+			durations = append(durations, duration)
+		}
+	}
+
+	if len(durations) == 0 {
+		return 0, false
+	}
+
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	trim := int(float64(len(durations)) * fraction)
+	kept := durations[trim : len(durations)-trim]
+
+	total := time.Duration(0)
+	for _, duration := range kept {
+		total += duration
+	}
+
+	return total / time.Duration(len(kept)), true
+}
+
 // GetActivityTypeDistribution 獲取活動類型分佈
 func (aa *ActivityAnalyzer) GetActivityTypeDistribution(activities []types.Activity) map[types.ActivityType]float64 {
 	if len(activities) == 0 {
@@ -221,3 +407,44 @@ func (aa *ActivityAnalyzer) GetActivityTypeDistribution(activities []types.Activ
 
 	return distribution
 }
+
+// BuildActivityData 將使用記錄轉換為活動資料，Token 使用量以記錄的 Tokens 為準，
+// 活動未設定時間戳記時沿用記錄的時間戳記
+func BuildActivityData(records []types.UsageRecord) types.ActivityData {
+	data := types.ActivityData{
+		Activities:       make([]types.Activity, 0, len(records)),
+		ActivitiesByType: make(map[types.ActivityType][]types.Activity),
+	}
+
+	for _, record := range records {
+		activity := record.Activity
+		if activity.Timestamp.IsZero() {
+			activity.Timestamp = record.Timestamp
+		}
+
+		total := record.Tokens.Total
+		if total == 0 {
+			total = record.Tokens.Input + record.Tokens.Output
+		}
+		activity.Tokens = types.TokenUsage{
+			InputTokens:  record.Tokens.Input,
+			OutputTokens: record.Tokens.Output,
+			TotalTokens:  total,
+		}
+
+		data.Activities = append(data.Activities, activity)
+		data.ActivitiesByType[activity.Type] = append(data.ActivitiesByType[activity.Type], activity)
+
+		if activity.Timestamp.IsZero() {
+			continue
+		}
+		if data.TimeRange.Start.IsZero() || activity.Timestamp.Before(data.TimeRange.Start) {
+			data.TimeRange.Start = activity.Timestamp
+		}
+		if activity.Timestamp.After(data.TimeRange.End) {
+			data.TimeRange.End = activity.Timestamp
+		}
+	}
+
+	return data
+}
@@ -1,6 +1,7 @@
 package analyzer
 
 import (
+	"math"
 	"testing"
 	"time"
 
@@ -135,6 +136,41 @@ func TestAnalyzeActivityBatch(t *testing.T) {
 	}
 }
 
+func TestAnalyzeActivityBatchParallel(t *testing.T) {
+	analyzer := NewActivityAnalyzer()
+
+	base := []string{
+		"請幫我實作一個函數來計算陣列總和",
+		"修復這個錯誤",
+		"更新 README 文件",
+		"設計系統架構",
+		"這個問題怎麼解決",
+		"",
+	}
+	contents := make([]string, 0, len(base)*50)
+	for i := 0; i < 50; i++ {
+		contents = append(contents, base...)
+	}
+
+	expected := analyzer.AnalyzeActivityBatch(contents)
+	for _, workers := range []int{0, 1, 4, len(contents) * 2} {
+		results := analyzer.AnalyzeActivityBatchParallel(contents, workers)
+		if len(results) != len(expected) {
+			t.Fatalf("workers=%d: expected %d results, got %d", workers, len(expected), len(results))
+		}
+		for i := range expected {
+			if results[i] != expected[i] {
+				t.Errorf("workers=%d: result %d expected %s, got %s", workers, i, expected[i], results[i])
+				break
+			}
+		}
+	}
+
+	if results := analyzer.AnalyzeActivityBatchParallel(nil, 4); len(results) != 0 {
+		t.Errorf("Expected empty results for empty input, got %d", len(results))
+	}
+}
+
 func TestGenerateActivitySummary(t *testing.T) {
 	analyzer := NewActivityAnalyzer()
 
@@ -206,6 +242,57 @@ func TestGenerateActivitySummary(t *testing.T) {
 	if summary.TotalTokens.TotalTokens != 850 {
 		t.Errorf("Expected 850 total tokens, got %d", summary.TotalTokens.TotalTokens)
 	}
+
+	// 檢查百分比
+	if math.Abs(summary.TokenPercentages[types.ActivityCoding]-700.0/850*100) > 1e-9 {
+		t.Errorf("Expected coding token percentage %f, got %f", 700.0/850*100, summary.TokenPercentages[types.ActivityCoding])
+	}
+	if math.Abs(summary.CountPercentages[types.ActivityDebugging]-100.0/3) > 1e-9 {
+		t.Errorf("Expected debugging count percentage %f, got %f", 100.0/3, summary.CountPercentages[types.ActivityDebugging])
+	}
+
+	tokenSum, countSum := 0.0, 0.0
+	for activityType := range summary.ActivityCounts {
+		tokenSum += summary.TokenPercentages[activityType]
+		countSum += summary.CountPercentages[activityType]
+	}
+	if math.Abs(tokenSum-100) > 1e-6 || math.Abs(countSum-100) > 1e-6 {
+		t.Errorf("Expected percentages to sum to 100, got tokens %f and counts %f", tokenSum, countSum)
+	}
+}
+
+func TestGenerateWeightedSummary(t *testing.T) {
+	analyzer := NewActivityAnalyzer()
+
+	activities := []types.Activity{
+		{Type: types.ActivityCoding, Tokens: types.TokenUsage{TotalTokens: 100}},
+		{Type: types.ActivityDebugging, Tokens: types.TokenUsage{TotalTokens: 200}},
+		{Type: types.ActivityChat, Tokens: types.TokenUsage{TotalTokens: 50}},
+	}
+	weights := map[types.ActivityType]float64{
+		types.ActivityCoding:    1.0,
+		types.ActivityDebugging: 1.5,
+	}
+
+	summary := analyzer.GenerateWeightedSummary(activities, weights)
+
+	// 未加權摘要應與 GenerateActivitySummary 一致
+	if summary.TotalTokens.TotalTokens != 350 {
+		t.Errorf("Expected 350 unweighted total tokens, got %d", summary.TotalTokens.TotalTokens)
+	}
+
+	if summary.WeightedTokensByType[types.ActivityDebugging] != 300 {
+		t.Errorf("Expected 300 weighted debugging tokens, got %f", summary.WeightedTokensByType[types.ActivityDebugging])
+	}
+
+	// 未設定權重的類型使用預設權重
+	if summary.Weights[types.ActivityChat] != DefaultActivityWeight {
+		t.Errorf("Expected default weight for chat, got %f", summary.Weights[types.ActivityChat])
+	}
+
+	if summary.WeightedTokenTotal != 450 {
+		t.Errorf("Expected 450 weighted token total, got %f", summary.WeightedTokenTotal)
+	}
 }
 
 func TestCalculateEfficiencyMetrics(t *testing.T) {
@@ -263,6 +350,60 @@ func TestCalculateEfficiencyMetrics(t *testing.T) {
 	}
 }
 
+func TestCalculateEfficiencyMetrics_TrimmedAverage(t *testing.T) {
+	analyzer := NewActivityAnalyzer()
+	if err := analyzer.SetDurationTrimFraction(0.2); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	now := time.Now()
+	minutes := []int{1, 10, 10, 10, 100}
+	var activities []types.Activity
+	for _, m := range minutes {
+		activities = append(activities, types.Activity{
+			Type:      types.ActivityCoding,
+			StartTime: now,
+			EndTime:   now.Add(time.Duration(m) * time.Minute),
+		})
+	}
+	// 缺少時間與非正值的活動應被忽略
+	activities = append(activities,
+		types.Activity{Type: types.ActivityCoding},
+		types.Activity{Type: types.ActivityCoding, StartTime: now, EndTime: now.Add(-time.Minute)},
+	)
+
+	data := types.ActivityData{
+		ActivitiesByType: map[types.ActivityType][]types.Activity{
+			types.ActivityCoding: activities,
+		},
+	}
+
+	metrics := analyzer.CalculateEfficiencyMetrics(data)
+
+	if got := metrics.AverageTimePerActivityTrimmed[types.ActivityCoding]; got != 10*time.Minute {
+		t.Errorf("Expected 10m trimmed average, got %v", got)
+	}
+
+	// 不修剪時為所有正值的平均
+	analyzer.SetDurationTrimFraction(0)
+	metrics = analyzer.CalculateEfficiencyMetrics(data)
+	if got := metrics.AverageTimePerActivityTrimmed[types.ActivityCoding]; got != 131*time.Minute/5 {
+		t.Errorf("Expected %v untrimmed average, got %v", 131*time.Minute/5, got)
+	}
+
+	for _, invalid := range []float64{-0.1, 0.5, 1, math.NaN()} {
+		if err := analyzer.SetDurationTrimFraction(invalid); err == nil {
+			t.Errorf("Expected error for trim fraction %v", invalid)
+		}
+	}
+
+	// 無效值不改變目前設定，計算不會 panic
+	metrics = analyzer.CalculateEfficiencyMetrics(data)
+	if got := metrics.AverageTimePerActivityTrimmed[types.ActivityCoding]; got != 131*time.Minute/5 {
+		t.Errorf("Expected previous trim fraction to be kept after NaN, got %v", got)
+	}
+}
+
 func TestGetActivityTypeDistribution(t *testing.T) {
 	analyzer := NewActivityAnalyzer()
 
@@ -309,3 +450,84 @@ func TestGetActivityTypeDistribution_EmptyInput(t *testing.T) {
 		t.Errorf("Expected empty distribution for empty input, got %v", distribution)
 	}
 }
+
+func TestExplainClassification(t *testing.T) {
+	analyzer := NewActivityAnalyzer()
+
+	content := "Please fix bug in the login function"
+	hits := analyzer.ExplainClassification(content)
+	if len(hits) == 0 {
+		t.Fatal("Expected pattern hits")
+	}
+
+	// 依權重排序，模式匹配在前
+	if hits[0].Kind != "pattern" || hits[0].ActivityType != types.ActivityDebugging || hits[0].Weight != patternMatchWeight {
+		t.Errorf("Expected debugging pattern hit first, got %+v", hits[0])
+	}
+	for i := 1; i < len(hits); i++ {
+		if hits[i].Weight > hits[i-1].Weight {
+			t.Errorf("Expected hits sorted by weight, got %+v", hits)
+		}
+	}
+
+	// 各類型權重總和應與分類結果一致
+	scores := make(map[types.ActivityType]int)
+	for _, hit := range hits {
+		scores[hit.ActivityType] += hit.Weight
+	}
+	classified := analyzer.ClassifyActivity(content)
+	for activityType, score := range scores {
+		if score > scores[classified] {
+			t.Errorf("Expected %s to have the highest score, but %s scored %d", classified, activityType, score)
+		}
+	}
+
+	if hits := analyzer.ExplainClassification(""); len(hits) != 0 {
+		t.Errorf("Expected no hits for empty content, got %+v", hits)
+	}
+}
+
+func TestBuildActivityData(t *testing.T) {
+	base := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	records := make([]types.UsageRecord, 3)
+	records[0].Timestamp = base.Add(time.Hour)
+	records[0].Activity = types.Activity{ID: "a", Type: types.ActivityCoding}
+	records[0].Tokens.Input = 100
+	records[0].Tokens.Output = 50
+	records[0].Tokens.Total = 150
+
+	records[1].Timestamp = base
+	records[1].Activity = types.Activity{ID: "b", Type: types.ActivityChat, Tokens: types.TokenUsage{TotalTokens: 999}}
+	records[1].Tokens.Input = 20
+	records[1].Tokens.Output = 10
+
+	records[2].Timestamp = base.Add(3 * time.Hour)
+	records[2].Activity = types.Activity{ID: "c", Type: types.ActivityCoding, Timestamp: base.Add(2 * time.Hour)}
+	records[2].Tokens.Input = 5
+	records[2].Tokens.Output = 5
+	records[2].Tokens.Total = 10
+
+	data := BuildActivityData(records)
+
+	if len(data.Activities) != 3 {
+		t.Fatalf("Expected 3 activities, got %d", len(data.Activities))
+	}
+	if data.Activities[1].Tokens != (types.TokenUsage{InputTokens: 20, OutputTokens: 10, TotalTokens: 30}) {
+		t.Errorf("Expected record tokens to replace activity tokens, got %+v", data.Activities[1].Tokens)
+	}
+	if !data.Activities[0].Timestamp.Equal(records[0].Timestamp) {
+		t.Errorf("Expected record timestamp to fill missing activity timestamp, got %v", data.Activities[0].Timestamp)
+	}
+	if len(data.ActivitiesByType[types.ActivityCoding]) != 2 || len(data.ActivitiesByType[types.ActivityChat]) != 1 {
+		t.Errorf("Unexpected grouping: %+v", data.ActivitiesByType)
+	}
+	if !data.TimeRange.Start.Equal(base) || !data.TimeRange.End.Equal(base.Add(2*time.Hour)) {
+		t.Errorf("Unexpected time range: %+v", data.TimeRange)
+	}
+
+	empty := BuildActivityData(nil)
+	if len(empty.Activities) != 0 || len(empty.ActivitiesByType) != 0 || !empty.TimeRange.Start.IsZero() {
+		t.Errorf("Expected empty activity data, got %+v", empty)
+	}
+}
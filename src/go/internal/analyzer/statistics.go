@@ -10,6 +10,9 @@ import (
 // ActivityStatistics 活動統計分析器
 type ActivityStatistics struct {
 	analyzer *ActivityAnalyzer
+
+	// 單一活動計入的最長時間（0 表示不限制）
+	maxActivityDuration time.Duration
 }
 
 // NewActivityStatistics 建立新的活動統計分析器
@@ -19,6 +22,31 @@ func NewActivityStatistics(analyzer *ActivityAnalyzer) *ActivityStatistics {
 	}
 }
 
+// SetMaxActivityDuration 設定單一活動計入統計的最長時間，超過者以上限計算（<= 0 表示不限制）
+func (as *ActivityStatistics) SetMaxActivityDuration(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	as.maxActivityDuration = d
+}
+
+// activityDuration 取得活動計入統計的時間：缺少起訖時間時為 0 且 ok 為 false；
+// 結束早於開始視為 0，超過上限以上限計算，兩者 clamped 皆為 true
+func (as *ActivityStatistics) activityDuration(activity types.Activity) (duration time.Duration, ok, clamped bool) {
+	if activity.StartTime.IsZero() || activity.EndTime.IsZero() {
+		return 0, false, false
+	}
+
+	duration = activity.EndTime.Sub(activity.StartTime)
+	if duration < 0 {
+		return 0, true, true
+	}
+	if as.maxActivityDuration > 0 && duration > as.maxActivityDuration {
+		return as.maxActivityDuration, true, true
+	}
+	return duration, true, false
+}
+
 // CalculateTokenUsageByActivity 計算各活動類型的 Token 使用統計
 func (as *ActivityStatistics) CalculateTokenUsageByActivity(activities []types.Activity) map[types.ActivityType]types.TokenUsage {
 	usage := make(map[types.ActivityType]types.TokenUsage)
@@ -52,10 +80,10 @@ func (as *ActivityStatistics) CalculateActivityTotals(activities []types.Activit
 		totals.TotalTokens.TotalTokens += activity.Tokens.TotalTokens
 
 		// 計算活動時間
-		var duration time.Duration
-		if !activity.StartTime.IsZero() && !activity.EndTime.IsZero() {
-			duration = activity.EndTime.Sub(activity.StartTime)
-			totals.TotalTime += duration
+		duration, _, clamped := as.activityDuration(activity)
+		totals.TotalTime += duration
+		if clamped {
+			totals.ClampedActivities++
 		}
 
 		// 更新各類型統計
@@ -123,8 +151,7 @@ func (as *ActivityStatistics) analyzeActivityTypePattern(activityType types.Acti
 		pattern.TotalTokens += activity.Tokens.TotalTokens
 		tokenCounts[i] = activity.Tokens.TotalTokens
 
-		if !activity.StartTime.IsZero() && !activity.EndTime.IsZero() {
-			duration := activity.EndTime.Sub(activity.StartTime)
+		if duration, ok, _ := as.activityDuration(activity); ok {
 			pattern.TotalTime += duration
 			durations = append(durations, duration)
 		}
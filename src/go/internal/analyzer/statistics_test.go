@@ -313,3 +313,46 @@ func TestEmptyInputHandling(t *testing.T) {
 		t.Error("Expected empty frequency map for empty input")
 	}
 }
+
+func TestSetMaxActivityDuration(t *testing.T) {
+	stats := NewActivityStatistics(NewActivityAnalyzer())
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	activities := []types.Activity{
+		{Type: types.ActivityCoding, StartTime: start, EndTime: start.Add(10 * time.Minute)},
+		{Type: types.ActivityCoding, StartTime: start, EndTime: start.Add(8 * time.Hour)},       // 閒置過久
+		{Type: types.ActivityDebugging, StartTime: start, EndTime: start.Add(-5 * time.Minute)}, // 起訖顛倒
+		{Type: types.ActivityChat},
+	}
+
+	// 未設定上限時只將顛倒的時間視為 0
+	totals := stats.CalculateActivityTotals(activities)
+	if totals.TotalTime != 8*time.Hour+10*time.Minute {
+		t.Errorf("Expected total time %v, got %v", 8*time.Hour+10*time.Minute, totals.TotalTime)
+	}
+	if totals.ClampedActivities != 1 {
+		t.Errorf("Expected 1 clamped activity, got %d", totals.ClampedActivities)
+	}
+
+	stats.SetMaxActivityDuration(30 * time.Minute)
+	totals = stats.CalculateActivityTotals(activities)
+	if totals.TotalTime != 40*time.Minute {
+		t.Errorf("Expected capped total time %v, got %v", 40*time.Minute, totals.TotalTime)
+	}
+	if totals.ClampedActivities != 2 {
+		t.Errorf("Expected 2 clamped activities, got %d", totals.ClampedActivities)
+	}
+	if totals.ByType[types.ActivityDebugging].TotalTime != 0 {
+		t.Errorf("Expected inverted range to count as zero, got %v", totals.ByType[types.ActivityDebugging].TotalTime)
+	}
+
+	pattern := stats.AnalyzeUsagePatterns(activities).Patterns[string(types.ActivityCoding)]
+	if pattern.TotalTime != 40*time.Minute || pattern.AverageTime != 20*time.Minute {
+		t.Errorf("Expected capped pattern times, got total %v average %v", pattern.TotalTime, pattern.AverageTime)
+	}
+
+	stats.SetMaxActivityDuration(0)
+	if totals := stats.CalculateActivityTotals(activities); totals.TotalTime != 8*time.Hour+10*time.Minute {
+		t.Errorf("Expected cap to be removed, got %v", totals.TotalTime)
+	}
+}
@@ -0,0 +1,107 @@
+package calculator
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"token-monitor/internal/types"
+)
+
+// defaultActivityCharsPerToken 各活動類型預設的英文字符每 Token 比例（程式碼符號密集，每 Token 字符較少）
+func defaultActivityCharsPerToken() map[types.ActivityType]float64 {
+	return map[types.ActivityType]float64{
+		types.ActivityCoding:    3.0,
+		types.ActivityDebugging: 3.2,
+	}
+}
+
+// SetActivityCharsPerToken 設定指定活動類型的英文字符每 Token 比例，0 表示移除設定並沿用一般估算參數
+func (tc *TokenCalculatorImpl) SetActivityCharsPerToken(activityType types.ActivityType, charsPerToken float64) error {
+	if charsPerToken < 0 || math.IsNaN(charsPerToken) || math.IsInf(charsPerToken, 0) {
+		return fmt.Errorf("chars per token must be a non-negative finite number: %v", charsPerToken)
+	}
+
+	tc.activityRatioMutex.Lock()
+	defer tc.activityRatioMutex.Unlock()
+
+	if charsPerToken == 0 {
+		delete(tc.activityCharsPerToken, activityType)
+		return nil
+	}
+	tc.activityCharsPerToken[activityType] = charsPerToken
+	return nil
+}
+
+// GetActivityCharsPerToken 取得各活動類型的英文字符每 Token 比例
+func (tc *TokenCalculatorImpl) GetActivityCharsPerToken() map[types.ActivityType]float64 {
+	tc.activityRatioMutex.RLock()
+	defer tc.activityRatioMutex.RUnlock()
+
+	ratios := make(map[types.ActivityType]float64, len(tc.activityCharsPerToken))
+	for activityType, ratio := range tc.activityCharsPerToken {
+		ratios[activityType] = ratio
+	}
+	return ratios
+}
+
+// CalculateTokensTyped 依活動類型提示計算 Token 數量。
+//
+// 使用估算方法時以該活動類型的英文字符比例計算（中文比例不變）；未設定比例的類型、
+// tiktoken 或自訂方法則與 CalculateTokens 相同。快取鍵包含活動類型與比例。
+func (tc *TokenCalculatorImpl) CalculateTokensTyped(text string, activityType types.ActivityType, method string) (int, error) {
+	charsPerToken, ok := tc.activityRatio(activityType)
+	if !ok || tc.ResolveMethod(text, method) != MethodEstimation || tc.fallbackChain(method) != nil {
+		return tc.CalculateTokens(text, method)
+	}
+
+	ctx := context.Background()
+	if text == "" {
+		return 0, nil
+	}
+
+	text, err := tc.prepareText(ctx, text, method)
+	if err != nil {
+		return 0, err
+	}
+
+	text, binaryTokens := tc.applyBinaryContentPolicy(text)
+	if text == "" {
+		return binaryTokens, nil
+	}
+
+	if tokens, ok := tc.whitespaceOnlyCount(text); ok {
+		return tokens + binaryTokens, nil
+	}
+
+	cacheKey := activityCacheKey(activityType, charsPerToken, text)
+	if tokens, found := tc.getCachedTokens(cacheKey); found {
+		tc.setLastMethod(MethodCache)
+		return tokens + binaryTokens, nil
+	}
+
+	start := time.Now()
+	englishChars, chineseChars := countEstimationChars(text)
+	tokens := tc.estimateWithRatio(englishChars, chineseChars, charsPerToken, true)
+
+	tc.setLastMethod(MethodEstimation)
+	tc.reportSlowCalculation(ctx, text, MethodEstimation, time.Since(start))
+	tc.setCachedTokens(cacheKey, tokens)
+
+	return tokens + binaryTokens, nil
+}
+
+// activityRatio 取得活動類型的英文字符每 Token 比例
+func (tc *TokenCalculatorImpl) activityRatio(activityType types.ActivityType) (float64, bool) {
+	tc.activityRatioMutex.RLock()
+	defer tc.activityRatioMutex.RUnlock()
+
+	ratio, ok := tc.activityCharsPerToken[activityType]
+	return ratio, ok
+}
+
+// activityCacheKey 產生依活動類型估算的快取鍵，與一般文本快取區隔
+func activityCacheKey(activityType types.ActivityType, charsPerToken float64, text string) string {
+	return fmt.Sprintf("\x00activity:%s:%g\x00%s", activityType, charsPerToken, text)
+}
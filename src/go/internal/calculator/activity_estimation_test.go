@@ -0,0 +1,82 @@
+package calculator
+
+import (
+	"testing"
+
+	"token-monitor/internal/types"
+)
+
+func TestTokenCalculatorImpl_CalculateTokensTyped(t *testing.T) {
+	calculator := NewTokenCalculator(100).(*TokenCalculatorImpl)
+	text := "func main() { fmt.Println(\"hello world\") }" // 42 個 ASCII 字符
+
+	plain, err := calculator.CalculateTokens(text, "estimation")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// 程式碼使用較低的字符比例：42 / 3.0 = 14
+	coding, err := calculator.CalculateTokensTyped(text, types.ActivityCoding, "estimation")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if coding != 14 || coding <= plain {
+		t.Errorf("Expected denser coding estimate 14 (> %d), got %d", plain, coding)
+	}
+
+	// 未設定比例的類型與 CalculateTokens 相同
+	chat, err := calculator.CalculateTokensTyped(text, types.ActivityChat, "estimation")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if chat != plain {
+		t.Errorf("Expected chat estimate to match plain estimate %d, got %d", plain, chat)
+	}
+
+	// 快取命中且與一般文本快取區隔
+	if cached, _ := calculator.CalculateTokensTyped(text, types.ActivityCoding, "estimation"); cached != coding || calculator.LastMethodUsed() != MethodCache {
+		t.Errorf("Expected cached coding estimate %d, got %d via %s", coding, cached, calculator.LastMethodUsed())
+	}
+	if again, _ := calculator.CalculateTokens(text, "estimation"); again != plain {
+		t.Errorf("Expected plain cache entry to be unaffected, got %d", again)
+	}
+
+	// 調整比例後重新計算
+	if err := calculator.SetActivityCharsPerToken(types.ActivityChat, 2.0); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if chat, _ := calculator.CalculateTokensTyped(text, types.ActivityChat, "estimation"); chat != 21 {
+		t.Errorf("Expected chat estimate 21 with ratio 2.0, got %d", chat)
+	}
+
+	if err := calculator.SetActivityCharsPerToken(types.ActivityCoding, 0); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, exists := calculator.GetActivityCharsPerToken()[types.ActivityCoding]; exists {
+		t.Error("Expected coding ratio to be removed")
+	}
+	if err := calculator.SetActivityCharsPerToken(types.ActivityCoding, -1); err == nil {
+		t.Error("Expected error for negative ratio")
+	}
+}
+
+func TestTokenCalculatorImpl_CalculateTokensTypedTiktoken(t *testing.T) {
+	calculator := NewTokenCalculator(100).(*TokenCalculatorImpl)
+	calculator.tiktokenEncoder = newTestTiktokenEncoder(t)
+	calculator.tiktokenEnabled = true
+
+	text := "func main() { fmt.Println(\"hello world\") }"
+	expected, err := calculator.CalculateTokens(text, "tiktoken")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// tiktoken 為精確計算，不套用活動類型比例
+	tokens, err := calculator.CalculateTokensTyped(text, types.ActivityCoding, "tiktoken")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tokens != expected {
+		t.Errorf("Expected tiktoken count %d, got %d", expected, tokens)
+	}
+}
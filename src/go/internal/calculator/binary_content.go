@@ -0,0 +1,78 @@
+package calculator
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// base64 / data URI 內容處理策略
+const (
+	BinaryContentCount      = "count"        // 視為一般文本計算（預設）
+	BinaryContentSkip       = "skip"         // 不計入 Token
+	BinaryContentFixedPerKB = "fixed-per-kb" // 每 KB 以固定 Token 數計算
+)
+
+// MinBinaryRunLength 視為 base64 內容的最短連續字符數
+const MinBinaryRunLength = 256
+
+// DefaultBinaryTokensPerKB fixed-per-kb 策略預設每 KB 的 Token 數
+const DefaultBinaryTokensPerKB = 64
+
+// binaryContentPattern 比對 data URI 或長串 base64 內容
+var binaryContentPattern = regexp.MustCompile(fmt.Sprintf(
+	`data:[A-Za-z0-9.+/-]*(?:;[A-Za-z0-9.+=-]+)*;base64,[A-Za-z0-9+/]+=*|[A-Za-z0-9+/]{%d,}={0,2}`, MinBinaryRunLength))
+
+// SetBinaryContentPolicy 設定 base64 / data URI 內容的處理策略（count、skip、fixed-per-kb）
+func (tc *TokenCalculatorImpl) SetBinaryContentPolicy(policy string) error {
+	switch policy {
+	case BinaryContentCount, BinaryContentSkip, BinaryContentFixedPerKB:
+		tc.binaryMutex.Lock()
+		tc.binaryContentPolicy = policy
+		tc.binaryMutex.Unlock()
+		return nil
+	default:
+		return fmt.Errorf("unsupported binary content policy: %s", policy)
+	}
+}
+
+// SetBinaryTokensPerKB 設定 fixed-per-kb 策略每 KB 內容計算的 Token 數
+func (tc *TokenCalculatorImpl) SetBinaryTokensPerKB(tokens int) error {
+	if tokens < 0 {
+		return fmt.Errorf("binary tokens per KB cannot be negative: %d", tokens)
+	}
+	tc.binaryMutex.Lock()
+	defer tc.binaryMutex.Unlock()
+
+	tc.binaryTokensPerKB = tokens
+	return nil
+}
+
+// hasBinaryContentPolicy 檢查是否設定了會改變計數的二進位內容策略（非 count）
+func (tc *TokenCalculatorImpl) hasBinaryContentPolicy() bool {
+	tc.binaryMutex.RLock()
+	defer tc.binaryMutex.RUnlock()
+
+	return tc.binaryContentPolicy != BinaryContentCount
+}
+
+// applyBinaryContentPolicy 依策略移除 base64 / data URI 內容，回傳剩餘文本與該內容另計的 Token 數
+func (tc *TokenCalculatorImpl) applyBinaryContentPolicy(text string) (string, int) {
+	tc.binaryMutex.RLock()
+	policy, tokensPerKB := tc.binaryContentPolicy, tc.binaryTokensPerKB
+	tc.binaryMutex.RUnlock()
+
+	if policy == BinaryContentCount {
+		return text, 0
+	}
+
+	binaryBytes := 0
+	remaining := binaryContentPattern.ReplaceAllStringFunc(text, func(match string) string {
+		binaryBytes += len(match)
+		return ""
+	})
+
+	if policy != BinaryContentFixedPerKB || binaryBytes == 0 {
+		return remaining, 0
+	}
+	return remaining, (binaryBytes*tokensPerKB + 1023) / 1024
+}
@@ -0,0 +1,124 @@
+package calculator
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pkoukk/tiktoken-go"
+)
+
+func TestTokenCalculatorImpl_BinaryContentPolicy(t *testing.T) {
+	calculator := NewTokenCalculator(100).(*TokenCalculatorImpl)
+
+	prose := "Here is the screenshot you asked for: "
+	blob := strings.Repeat("iVBORw0KGgoAAAANSUhEUgAA", 128) // 3072 個 base64 字符
+	text := prose + "![img](data:image/png;base64," + blob + "==)"
+	withoutBlob := prose + "![img]()"
+
+	// 預設視為一般文本
+	counted, err := calculator.CalculateTokens(text, "estimation")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectedProse, _ := calculator.CalculateTokens(withoutBlob, "estimation")
+	if counted <= expectedProse*10 {
+		t.Errorf("Expected base64 to inflate count by default, got %d vs %d", counted, expectedProse)
+	}
+
+	if err := calculator.SetBinaryContentPolicy(BinaryContentSkip); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	skipped, err := calculator.CalculateTokens(text, "estimation")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if skipped != expectedProse {
+		t.Errorf("Expected skipped count %d, got %d", expectedProse, skipped)
+	}
+
+	// 長串 base64（無 data URI）同樣會被偵測
+	if tokens, _ := calculator.CalculateTokens(blob, "estimation"); tokens != 0 {
+		t.Errorf("Expected bare base64 run to be skipped, got %d", tokens)
+	}
+
+	// 每 KB 固定 Token 數：data URI 約 3.1 KB
+	if err := calculator.SetBinaryContentPolicy(BinaryContentFixedPerKB); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := calculator.SetBinaryTokensPerKB(100); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	binaryBytes := len("data:image/png;base64,") + len(blob) + 2
+	fixed, err := calculator.CalculateTokens(text, "estimation")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if want := expectedProse + (binaryBytes*100+1023)/1024; fixed != want {
+		t.Errorf("Expected fixed-per-kb count %d, got %d", want, fixed)
+	}
+
+	// 短的 base64 樣式字串不受影響
+	short := "token abc123XYZ+/ is fine"
+	plain, _ := NewTokenCalculator(100).CalculateTokens(short, "estimation")
+	if tokens, _ := calculator.CalculateTokens(short, "estimation"); tokens != plain {
+		t.Errorf("Expected short text to be counted normally, got %d vs %d", tokens, plain)
+	}
+
+	if err := calculator.SetBinaryContentPolicy("compress"); err == nil {
+		t.Error("Expected error for unsupported policy")
+	}
+	if err := calculator.SetBinaryTokensPerKB(-1); err == nil {
+		t.Error("Expected error for negative tokens per KB")
+	}
+}
+
+func TestTokenCalculatorImpl_BinaryContentPolicyForModel(t *testing.T) {
+	calculator := NewTokenCalculator(100).(*TokenCalculatorImpl)
+	calculator.loadEncoding = func(string) (*tiktoken.Tiktoken, error) { return newTestTiktokenEncoder(t), nil }
+
+	prose := "Here is the screenshot you asked for: "
+	blob := strings.Repeat("iVBORw0KGgoAAAANSUhEUgAA", 128)
+	if err := calculator.SetBinaryContentPolicy(BinaryContentSkip); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// 依模型計算同樣套用二進位內容策略
+	expected, err := calculator.CalculateTokensForModel(prose, "gpt-4")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	tokens, err := calculator.CalculateTokensForModel(prose+blob, "gpt-4")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tokens != expected {
+		t.Errorf("Expected skipped base64 count %d, got %d", expected, tokens)
+	}
+	if tokens, _ := calculator.CalculateTokensForModel(blob, "gpt-4"); tokens != 0 {
+		t.Errorf("Expected bare base64 run to be skipped, got %d", tokens)
+	}
+}
+
+func TestTokenCalculatorImpl_BinaryContentPolicyConcurrent(t *testing.T) {
+	calculator := NewTokenCalculator(100).(*TokenCalculatorImpl)
+	text := "prefix " + strings.Repeat("QUJD", 100)
+
+	// 計算期間變更策略不應發生資料競爭（以 -race 執行）
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			_ = calculator.SetBinaryContentPolicy(BinaryContentFixedPerKB)
+			_ = calculator.SetBinaryTokensPerKB(i)
+		}(i)
+		go func() {
+			defer wg.Done()
+			if _, err := calculator.CalculateTokens(text, "estimation"); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -3,6 +3,7 @@ package calculator
 import (
 	"strings"
 	"testing"
+	"token-monitor/internal/mathutil"
 )
 
 // TestComprehensiveTokenCalculation 全面的 Token 計算測試
@@ -178,7 +179,7 @@ func TestMethodConsistency(t *testing.T) {
 
 				// 計算差異百分比
 				if tiktokenTokens > 0 {
-					diff := abs(tiktokenTokens - estimationTokens)
+					diff := mathutil.AbsInt(tiktokenTokens - estimationTokens)
 					accuracy := 100.0 - (float64(diff)/float64(tiktokenTokens))*100.0
 					t.Logf("  準確度: %.1f%%", accuracy)
 				}
@@ -0,0 +1,65 @@
+package calculator
+
+import (
+	"fmt"
+
+	"token-monitor/internal/errors"
+)
+
+// defaultContextLimits 已知模型的上下文視窗大小（Token 數）
+func defaultContextLimits() map[string]int {
+	return map[string]int{
+		"claude-sonnet-4.0": 200000,
+		"claude-opus-4.0":   200000,
+		"claude-haiku-3.5":  200000,
+		"gpt-4":             8192,
+		"gpt-4o":            128000,
+		"gpt-3.5-turbo":     16385,
+	}
+}
+
+// SetContextLimit 設定模型的上下文視窗大小，0 表示移除設定
+func (tc *TokenCalculatorImpl) SetContextLimit(model string, limit int) error {
+	if limit < 0 {
+		return fmt.Errorf("context limit cannot be negative: %d", limit)
+	}
+
+	tc.contextMutex.Lock()
+	defer tc.contextMutex.Unlock()
+
+	if limit == 0 {
+		delete(tc.contextLimits, model)
+		return nil
+	}
+	tc.contextLimits[model] = limit
+	return nil
+}
+
+// GetContextLimit 取得模型的上下文視窗大小
+func (tc *TokenCalculatorImpl) GetContextLimit(model string) (int, bool) {
+	tc.contextMutex.RLock()
+	defer tc.contextMutex.RUnlock()
+
+	limit, ok := tc.contextLimits[model]
+	return limit, ok
+}
+
+// ContextUtilization 計算文本佔上下文視窗的比例及 Token 數。contextLimit <= 0 時使用模型已知的上限；
+// 比例不做上限截斷，超過上限時大於 1.0。
+func (tc *TokenCalculatorImpl) ContextUtilization(text string, model string, contextLimit int, method string) (float64, int, error) {
+	limit := contextLimit
+	if limit <= 0 {
+		known, ok := tc.GetContextLimit(model)
+		if !ok {
+			return 0, 0, errors.Newf(errors.ErrCodeInvalidTokenCount, "未知模型的上下文上限: %s", model)
+		}
+		limit = known
+	}
+
+	tokens, err := tc.CalculateTokens(text, method)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return float64(tokens) / float64(limit), tokens, nil
+}
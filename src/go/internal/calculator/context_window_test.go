@@ -0,0 +1,52 @@
+package calculator
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestTokenCalculatorImpl_ContextUtilization(t *testing.T) {
+	calculator := NewTokenCalculator(100).(*TokenCalculatorImpl)
+	text := strings.Repeat("abcd", 100) // 估算 100 個 Token
+
+	fraction, tokens, err := calculator.ContextUtilization(text, "gpt-4", 400, "estimation")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tokens != 100 || math.Abs(fraction-0.25) > 1e-9 {
+		t.Errorf("Expected 100 tokens at 0.25, got %d at %v", tokens, fraction)
+	}
+
+	// 使用已知的模型上限
+	fraction, _, err = calculator.ContextUtilization(text, "gpt-4", 0, "estimation")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if math.Abs(fraction-100.0/8192) > 1e-9 {
+		t.Errorf("Expected utilization against 8192 tokens, got %v", fraction)
+	}
+
+	// 超過上限時大於 1.0
+	if err := calculator.SetContextLimit("tiny-model", 50); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	fraction, _, err = calculator.ContextUtilization(text, "tiny-model", 0, "estimation")
+	if err != nil || fraction != 2.0 {
+		t.Errorf("Expected over-limit utilization 2.0, got %v (%v)", fraction, err)
+	}
+
+	if _, _, err := calculator.ContextUtilization(text, "unknown-model", 0, "estimation"); err == nil {
+		t.Error("Expected error for unknown model without explicit limit")
+	}
+
+	if err := calculator.SetContextLimit("tiny-model", 0); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := calculator.GetContextLimit("tiny-model"); ok {
+		t.Error("Expected context limit to be removed")
+	}
+	if err := calculator.SetContextLimit("tiny-model", -1); err == nil {
+		t.Error("Expected error for negative limit")
+	}
+}
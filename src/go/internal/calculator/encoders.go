@@ -0,0 +1,173 @@
+package calculator
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"token-monitor/internal/errors"
+
+	"github.com/pkoukk/tiktoken-go"
+)
+
+// defaultEncoding 預設的 tiktoken 編碼（GPT-3.5/GPT-4）
+const defaultEncoding = "cl100k_base"
+
+// RegisterModelEncoding 指定模型使用的 tiktoken 編碼；編碼器會在該模型第一次計算時才載入
+func (tc *TokenCalculatorImpl) RegisterModelEncoding(model, encoding string) {
+	tc.encoderMutex.Lock()
+	defer tc.encoderMutex.Unlock()
+
+	tc.modelEncodings[model] = encoding
+}
+
+// CalculateTokensForModel 使用模型對應的 tiktoken 編碼計算 Token，
+// 編碼無法載入時回退到估算方法。結果依模型快取，並與其他計算相同記錄慢速計算
+func (tc *TokenCalculatorImpl) CalculateTokensForModel(text string, model string) (int, error) {
+	ctx := context.Background()
+
+	if text == "" {
+		return 0, nil
+	}
+
+	encoder, err := tc.encoderForModel(model)
+	if err != nil {
+		return tc.CalculateTokens(text, "estimation")
+	}
+
+	text, err = tc.prepareText(ctx, text, "tiktoken")
+	if err != nil {
+		return 0, err
+	}
+
+	// 依二進位內容策略處理 base64 / data URI 片段
+	text, binaryTokens := tc.applyBinaryContentPolicy(text)
+	if text == "" {
+		return binaryTokens, nil
+	}
+
+	if tokens, ok := tc.whitespaceOnlyCount(text); ok {
+		return tokens + binaryTokens, nil
+	}
+
+	// 不同模型的編碼結果不同，快取鍵需包含模型
+	cacheKey := modelCacheKey(model, text)
+	if tokens, found := tc.getCachedTokens(cacheKey); found {
+		tc.setLastMethod(MethodCache)
+		return tokens + binaryTokens, nil
+	}
+
+	start := time.Now()
+	tokens, err := tc.encodeWithTiktoken(encoder, text)
+	if err != nil {
+		appErr := errors.Wrap(err, errors.ErrCodeTokenCalculation, "Token 計算失敗")
+		appErr = appErr.WithContext(errors.ErrorContext{
+			Operation: "calculate_tokens_for_model",
+			Component: "token_calculator",
+			Parameters: map[string]interface{}{
+				"text_length": len(text),
+				"model":       model,
+			},
+		})
+		return 0, tc.errorHandler.Handle(ctx, appErr)
+	}
+
+	tc.setLastMethod(MethodTiktoken)
+	tc.reportSlowCalculation(ctx, text, MethodTiktoken, time.Since(start))
+	tc.setCachedTokens(cacheKey, tokens)
+
+	return tokens + binaryTokens, nil
+}
+
+// modelCacheKey 產生依模型區分的快取鍵
+func modelCacheKey(model, text string) string {
+	return "model\x00" + model + "\x00" + text
+}
+
+// encoderForModel 取得模型對應的編碼器，必要時延遲載入；tiktoken 已停用時回傳錯誤，由呼叫者改用估算
+func (tc *TokenCalculatorImpl) encoderForModel(model string) (*tiktoken.Tiktoken, error) {
+	tc.encoderMutex.Lock()
+	defer tc.encoderMutex.Unlock()
+
+	if !tc.tiktokenEnabled {
+		return nil, errors.New(errors.ErrCodeTiktokenUnavailable, "Tiktoken 不可用")
+	}
+
+	encoding := tc.resolveEncoding(model)
+
+	// 預設編碼器與 tiktokenEncoder 共用，同樣延遲載入；載入失敗時 tiktoken 會被停用
+	if encoding == defaultEncoding {
+		if encoder := tc.defaultEncoderLocked(); encoder != nil {
+			return encoder, nil
+		}
+		return nil, errors.New(errors.ErrCodeTiktokenUnavailable, "Tiktoken 不可用")
+	}
+
+	if encoder, exists := tc.encoders[encoding]; exists {
+		return encoder, nil
+	}
+	if err, failed := tc.encoderFailures[encoding]; failed {
+		return nil, err
+	}
+
+	encoder, err := tc.loadEncoding(encoding)
+	if err != nil {
+		warnErr := errors.Wrap(err, errors.ErrCodeTiktokenUnavailable, "Tiktoken 編碼載入失敗")
+		warnErr = warnErr.WithContext(errors.ErrorContext{
+			Operation: "load_encoding",
+			Component: "token_calculator",
+			Parameters: map[string]interface{}{
+				"encoding": encoding,
+				"model":    model,
+			},
+		})
+		tc.errorHandler.Handle(context.Background(), warnErr)
+		tc.encoderFailures[encoding] = warnErr
+		return nil, warnErr
+	}
+
+	tc.encoders[encoding] = encoder
+	return encoder, nil
+}
+
+// resolveEncoding 取得模型的編碼名稱（呼叫者須持有 encoderMutex）
+func (tc *TokenCalculatorImpl) resolveEncoding(model string) string {
+	if encoding, exists := tc.modelEncodings[model]; exists {
+		return encoding
+	}
+	if encoding, exists := tiktoken.MODEL_TO_ENCODING[model]; exists {
+		return encoding
+	}
+	for prefix, encoding := range tiktoken.MODEL_PREFIX_TO_ENCODING {
+		if strings.HasPrefix(model, prefix) {
+			return encoding
+		}
+	}
+	return defaultEncoding
+}
+
+// encodingStatus 取得已載入與載入失敗的編碼名稱
+func (tc *TokenCalculatorImpl) encodingStatus() ([]string, []string) {
+	tc.encoderMutex.Lock()
+	defer tc.encoderMutex.Unlock()
+
+	loaded := make([]string, 0, len(tc.encoders)+1)
+	if tc.tiktokenEnabled && tc.tiktokenEncoder != nil {
+		loaded = append(loaded, defaultEncoding)
+	}
+	for encoding := range tc.encoders {
+		if encoding != defaultEncoding {
+			loaded = append(loaded, encoding)
+		}
+	}
+
+	failed := make([]string, 0, len(tc.encoderFailures))
+	for encoding := range tc.encoderFailures {
+		failed = append(failed, encoding)
+	}
+
+	sort.Strings(loaded)
+	sort.Strings(failed)
+	return loaded, failed
+}
@@ -2,6 +2,7 @@ package calculator
 
 import (
 	"token-monitor/internal/interfaces"
+	"token-monitor/internal/types"
 
 	"github.com/spf13/viper"
 )
@@ -30,6 +31,14 @@ func NewTokenCalculatorFromConfig() interfaces.TokenCalculator {
 	// 設定估算參數
 	calculator.SetEstimationParameters(englishCharsPerToken, chineseCharsPerToken)
 
+	// 依活動類型的估算比例（例如 token_calculation.estimation.activity_chars_per_token.coding: 3.0）
+	for activityType := range viper.GetStringMap("token_calculation.estimation.activity_chars_per_token") {
+		ratio := viper.GetFloat64("token_calculation.estimation.activity_chars_per_token." + activityType)
+		if ratio > 0 {
+			calculator.SetActivityCharsPerToken(types.ActivityType(activityType), ratio)
+		}
+	}
+
 	return calculator
 }
 
@@ -0,0 +1,192 @@
+package calculator
+
+import (
+	"fmt"
+	"sort"
+
+	"token-monitor/internal/errors"
+)
+
+// 內建計算方法
+const (
+	MethodTiktoken   = "tiktoken"
+	MethodEstimation = "estimation"
+
+	// MethodCache 結果來自快取時 LastMethodUsed 回傳的值
+	MethodCache = "cache"
+)
+
+// MethodFunc 自訂 Token 計算方法
+type MethodFunc func(text string) (int, error)
+
+// MethodInfo 計算方法及其可用狀態
+type MethodInfo struct {
+	Name      string `json:"name"`
+	Available bool   `json:"available"`
+	Reason    string `json:"reason,omitempty"` // 不可用的原因或方法來源說明
+}
+
+// GetMethodsDetailed 取得所有計算方法（含自訂方法）及其可用狀態
+func (tc *TokenCalculatorImpl) GetMethodsDetailed() []MethodInfo {
+	available := tc.tiktokenAvailable()
+	tiktokenInfo := MethodInfo{Name: MethodTiktoken, Available: available}
+	if !available {
+		tc.encoderMutex.Lock()
+		initErr := tc.tiktokenInitErr
+		tc.encoderMutex.Unlock()
+
+		tiktokenInfo.Reason = "disabled"
+		if initErr != nil {
+			tiktokenInfo.Reason = fmt.Sprintf("init failed: %v", initErr)
+		}
+	}
+
+	methods := []MethodInfo{
+		{Name: MethodEstimation, Available: true},
+		tiktokenInfo,
+	}
+	for _, name := range tc.customMethodNames() {
+		methods = append(methods, MethodInfo{Name: name, Available: true, Reason: "custom backend"})
+	}
+	return methods
+}
+
+// RegisterMethod 註冊自訂計算方法，可直接指定或加入備援鏈（名稱不可與內建方法相同）
+func (tc *TokenCalculatorImpl) RegisterMethod(name string, fn MethodFunc) error {
+	if name == "" || fn == nil {
+		return fmt.Errorf("method name and function cannot be empty")
+	}
+	if name == MethodTiktoken || name == MethodEstimation || name == MethodCache {
+		return fmt.Errorf("cannot override built-in method: %s", name)
+	}
+
+	tc.methodMutex.Lock()
+	defer tc.methodMutex.Unlock()
+
+	tc.customMethods[name] = fn
+	return nil
+}
+
+// SetMethodFallback 設定計算方法的備援鏈，依序嘗試直到某個方法成功；
+// 方法不可用（如 tiktoken 未啟用）或回傳錯誤時改用下一個。傳入空鏈恢復預設行為。
+func (tc *TokenCalculatorImpl) SetMethodFallback(chain []string) error {
+	tc.methodMutex.Lock()
+	defer tc.methodMutex.Unlock()
+
+	for _, method := range chain {
+		if !tc.isKnownMethodLocked(method) {
+			return fmt.Errorf("unknown calculation method: %s", method)
+		}
+	}
+
+	tc.methodFallback = append([]string(nil), chain...)
+
+	// 方法順序變更可能改變已快取的結果
+	tc.ClearCache()
+	return nil
+}
+
+// GetMethodFallback 取得目前的備援鏈
+func (tc *TokenCalculatorImpl) GetMethodFallback() []string {
+	tc.methodMutex.RLock()
+	defer tc.methodMutex.RUnlock()
+
+	return append([]string(nil), tc.methodFallback...)
+}
+
+// LastMethodUsed 取得最近一次 CalculateTokens 實際產生結果的方法（快取命中時為 MethodCache）。
+// 僅供診斷參考：並行計算時可能是其他呼叫的結果，需要可靠的方法時請使用 CalculateTokensWithMethod。
+func (tc *TokenCalculatorImpl) LastMethodUsed() string {
+	tc.methodMutex.RLock()
+	defer tc.methodMutex.RUnlock()
+
+	return tc.lastMethod
+}
+
+// setLastMethod 記錄最近一次產生結果的方法
+func (tc *TokenCalculatorImpl) setLastMethod(method string) {
+	tc.methodMutex.Lock()
+	defer tc.methodMutex.Unlock()
+
+	tc.lastMethod = method
+}
+
+// isKnownMethodLocked 檢查是否為內建或已註冊的方法（呼叫者需持有 methodMutex）
+func (tc *TokenCalculatorImpl) isKnownMethodLocked(method string) bool {
+	if method == MethodTiktoken || method == MethodEstimation {
+		return true
+	}
+	_, exists := tc.customMethods[method]
+	return exists
+}
+
+// fallbackChain 取得本次計算要依序嘗試的方法；未設定備援鏈且未指定自訂方法時回傳 nil（沿用預設行為）。
+// 指定的方法為內建或自訂方法時優先嘗試，之後依備援鏈順序嘗試。
+func (tc *TokenCalculatorImpl) fallbackChain(method string) []string {
+	tc.methodMutex.RLock()
+	defer tc.methodMutex.RUnlock()
+
+	_, custom := tc.customMethods[method]
+	if len(tc.methodFallback) == 0 && !custom {
+		return nil
+	}
+
+	chain := make([]string, 0, len(tc.methodFallback)+1)
+	if tc.isKnownMethodLocked(method) {
+		chain = append(chain, method)
+	}
+	for _, fallback := range tc.methodFallback {
+		if fallback != method {
+			chain = append(chain, fallback)
+		}
+	}
+	return chain
+}
+
+// calculateWithFallback 依序嘗試備援鏈中的方法，回傳結果與產生結果的方法
+func (tc *TokenCalculatorImpl) calculateWithFallback(text string, chain []string) (int, string, error) {
+	var lastErr error
+	for _, method := range chain {
+		var tokens int
+		var err error
+
+		switch method {
+		case MethodTiktoken:
+			if !tc.tiktokenAvailable() {
+				lastErr = errors.New(errors.ErrCodeTiktokenUnavailable, "Tiktoken 不可用")
+				continue
+			}
+			tokens, err = tc.calculateWithTiktoken(text)
+		case MethodEstimation:
+			tokens, err = tc.calculateWithEstimation(text)
+		default:
+			tc.methodMutex.RLock()
+			fn := tc.customMethods[method]
+			tc.methodMutex.RUnlock()
+			tokens, err = fn(text)
+		}
+
+		if err == nil {
+			return tokens, method, nil
+		}
+		lastErr = fmt.Errorf("%s: %w", method, err)
+	}
+
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no calculation method available")
+	}
+	return 0, "", lastErr
+}
+
+// customMethodNames 取得排序後的自訂方法名稱
+func (tc *TokenCalculatorImpl) customMethodNames() []string {
+	tc.methodMutex.RLock()
+	defer tc.methodMutex.RUnlock()
+
+	names := make([]string, 0, len(tc.customMethods))
+	for name := range tc.customMethods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
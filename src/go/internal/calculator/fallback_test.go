@@ -0,0 +1,205 @@
+package calculator
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestTokenCalculatorImpl_MethodFallback(t *testing.T) {
+	calculator := NewTokenCalculator(100).(*TokenCalculatorImpl)
+	calculator.tiktokenEncoder = newTestTiktokenEncoder(t)
+	calculator.tiktokenEnabled = true
+
+	backendUp := false
+	if err := calculator.RegisterMethod("custom-backend", func(text string) (int, error) {
+		if !backendUp {
+			return 0, fmt.Errorf("backend unavailable")
+		}
+		return 42, nil
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := calculator.SetMethodFallback([]string{"custom-backend", "tiktoken", "estimation"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// 自訂後端失敗時改用 tiktoken
+	expected, err := calculator.calculateWithTiktoken("hello world")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	tokens, err := calculator.CalculateTokens("hello world", "auto")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tokens != expected || calculator.LastMethodUsed() != MethodTiktoken {
+		t.Errorf("Expected tiktoken result %d, got %d via %s", expected, tokens, calculator.LastMethodUsed())
+	}
+
+	// tiktoken 不可用時改用估算
+	calculator.tiktokenEnabled = false
+	if _, err := calculator.CalculateTokens("another text", "auto"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if calculator.LastMethodUsed() != MethodEstimation {
+		t.Errorf("Expected estimation, got %s", calculator.LastMethodUsed())
+	}
+
+	// 後端恢復後優先使用
+	backendUp = true
+	tokens, err = calculator.CalculateTokens("third text", "auto")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tokens != 42 || calculator.LastMethodUsed() != "custom-backend" {
+		t.Errorf("Expected custom backend result 42, got %d via %s", tokens, calculator.LastMethodUsed())
+	}
+
+	// 快取命中
+	if _, err := calculator.CalculateTokens("third text", "auto"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if calculator.LastMethodUsed() != MethodCache {
+		t.Errorf("Expected cache, got %s", calculator.LastMethodUsed())
+	}
+}
+
+func TestTokenCalculatorImpl_MethodFallbackValidation(t *testing.T) {
+	calculator := NewTokenCalculator(100).(*TokenCalculatorImpl)
+
+	if err := calculator.SetMethodFallback([]string{"unknown", "estimation"}); err == nil {
+		t.Errorf("Expected error for unknown method")
+	}
+	if err := calculator.RegisterMethod("tiktoken", func(string) (int, error) { return 0, nil }); err == nil {
+		t.Errorf("Expected error when overriding built-in method")
+	}
+
+	// 所有方法都失敗時回傳錯誤
+	if err := calculator.RegisterMethod("broken", func(string) (int, error) { return 0, fmt.Errorf("down") }); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := calculator.SetMethodFallback([]string{"broken"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := calculator.CalculateTokens("hello", "auto"); err == nil {
+		t.Errorf("Expected error when all methods fail")
+	}
+
+	// 清除備援鏈後恢復預設行為
+	if err := calculator.SetMethodFallback(nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := calculator.CalculateTokens("hello", "auto"); err != nil {
+		t.Errorf("Unexpected error after clearing fallback: %v", err)
+	}
+	if calculator.LastMethodUsed() != MethodEstimation {
+		t.Errorf("Expected estimation without fallback chain, got %s", calculator.LastMethodUsed())
+	}
+
+	methods := calculator.GetSupportedMethods()
+	if methods[len(methods)-1] != "broken" {
+		t.Errorf("Expected registered method in supported methods, got %v", methods)
+	}
+}
+
+func TestTokenCalculatorImpl_CacheScopedByMethod(t *testing.T) {
+	calculator := NewTokenCalculator(100).(*TokenCalculatorImpl)
+	if err := calculator.RegisterMethod("fixed", func(string) (int, error) { return 42, nil }); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	text := "hello world"
+	tokens, err := calculator.CalculateTokens(text, "fixed")
+	if err != nil || tokens != 42 {
+		t.Fatalf("Expected custom method result 42, got %d (%v)", tokens, err)
+	}
+
+	// 相同文本改用估算時不應取得自訂方法的快取結果
+	estimated, err := calculator.CalculateTokens(text, MethodEstimation)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if estimated == 42 || calculator.LastMethodUsed() != MethodEstimation {
+		t.Errorf("Expected estimation result, got %d via %s", estimated, calculator.LastMethodUsed())
+	}
+
+	// 同一方法重複計算仍使用快取
+	if tokens, err := calculator.CalculateTokens(text, "fixed"); err != nil || tokens != 42 {
+		t.Errorf("Expected cached custom result 42, got %d (%v)", tokens, err)
+	}
+	if calculator.LastMethodUsed() != MethodCache {
+		t.Errorf("Expected cache, got %s", calculator.LastMethodUsed())
+	}
+}
+
+func TestTokenCalculatorImpl_GetMethodsDetailed(t *testing.T) {
+	calculator := NewTokenCalculator(100).(*TokenCalculatorImpl)
+	if err := calculator.RegisterMethod("custom-backend", func(string) (int, error) { return 1, nil }); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// 模擬 tiktoken 初始化失敗
+	calculator.tiktokenEnabled = false
+	calculator.tiktokenInitErr = fmt.Errorf("download failed")
+
+	methods := calculator.GetMethodsDetailed()
+	byName := make(map[string]MethodInfo)
+	for _, method := range methods {
+		byName[method.Name] = method
+	}
+
+	if len(methods) != 3 {
+		t.Fatalf("Expected 3 methods, got %v", methods)
+	}
+	if !byName[MethodEstimation].Available {
+		t.Errorf("Expected estimation to be available")
+	}
+	if tiktoken := byName[MethodTiktoken]; tiktoken.Available || tiktoken.Reason != "init failed: download failed" {
+		t.Errorf("Expected tiktoken unavailable with init failure reason, got %+v", tiktoken)
+	}
+	if !byName["custom-backend"].Available {
+		t.Errorf("Expected custom backend to be listed as available")
+	}
+
+	calculator.tiktokenEncoder = newTestTiktokenEncoder(t)
+	calculator.tiktokenEnabled = true
+	calculator.tiktokenInitErr = nil
+	for _, method := range calculator.GetMethodsDetailed() {
+		if method.Name == MethodTiktoken && (!method.Available || method.Reason != "") {
+			t.Errorf("Expected tiktoken available without reason, got %+v", method)
+		}
+	}
+}
+
+func TestTokenCalculatorImpl_CalculateTokensWithMethod(t *testing.T) {
+	calculator := NewTokenCalculator(100).(*TokenCalculatorImpl)
+	calculator.tiktokenEncoder = newTestTiktokenEncoder(t)
+	calculator.tiktokenEnabled = true
+
+	// 並行呼叫時回傳的方法不受其他呼叫影響
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		method := []string{MethodTiktoken, MethodEstimation}[i%2]
+		wg.Add(1)
+		go func(i int, method string) {
+			defer wg.Done()
+			text := fmt.Sprintf("concurrent text %d", i)
+			if _, used, err := calculator.CalculateTokensWithMethod(text, method); err != nil || used != method {
+				t.Errorf("Expected %s for %q, got %s (err=%v)", method, text, used, err)
+			}
+		}(i, method)
+	}
+	wg.Wait()
+
+	// 快取命中
+	if _, used, err := calculator.CalculateTokensWithMethod("concurrent text 0", MethodTiktoken); err != nil || used != MethodCache {
+		t.Errorf("Expected cache, got %s (err=%v)", used, err)
+	}
+
+	// 空文本不經任何方法
+	if tokens, used, err := calculator.CalculateTokensWithMethod("", MethodTiktoken); err != nil || tokens != 0 || used != "" {
+		t.Errorf("Expected empty text to return 0 without method, got %d via %q (err=%v)", tokens, used, err)
+	}
+}
@@ -0,0 +1,112 @@
+package calculator
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"token-monitor/internal/errors"
+	"unicode/utf8"
+)
+
+// fileChunkSize 大型檔案分段計算時每次讀取的位元組數
+const fileChunkSize = 64 * 1024
+
+// CalculateTokensFromFile 計算檔案內容的 Token 數量。不超過 MaxTextSize 的檔案一次計算；
+// 較大的檔案依換行（或 UTF-8 字元邊界）分段計算後加總，結果可能與整段計算略有差異。
+func (tc *TokenCalculatorImpl) CalculateTokensFromFile(path string, method string) (int, error) {
+	ctx := context.Background()
+
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, tc.handleFileError(ctx, err, path)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, tc.handleFileError(ctx, err, path)
+	}
+
+	if info.Size() <= MaxTextSize {
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return 0, tc.handleFileError(ctx, err, path)
+		}
+		return tc.CalculateTokens(string(data), method)
+	}
+
+	return tc.calculateTokensFromReader(ctx, file, path, method)
+}
+
+// calculateTokensFromReader 分段讀取並加總 Token 數量，分段點優先選在最後一個換行之後
+func (tc *TokenCalculatorImpl) calculateTokensFromReader(ctx context.Context, reader io.Reader, path string, method string) (int, error) {
+	total := 0
+	block := make([]byte, fileChunkSize)
+	var carry []byte
+
+	for {
+		n, readErr := io.ReadFull(reader, block)
+		eof := readErr == io.EOF || readErr == io.ErrUnexpectedEOF
+		if readErr != nil && !eof {
+			return 0, tc.handleFileError(ctx, readErr, path)
+		}
+
+		data := append(carry, block[:n]...)
+		cut := len(data)
+		if !eof {
+			cut = chunkSplitPoint(data)
+		}
+
+		tokens, err := tc.CalculateTokens(string(data[:cut]), method)
+		if err != nil {
+			return 0, err
+		}
+		total += tokens
+
+		if eof {
+			return total, nil
+		}
+		carry = append([]byte(nil), data[cut:]...)
+	}
+}
+
+// chunkSplitPoint 取得分段位置：最後一個換行之後，沒有換行時為最後一個完整 UTF-8 字元之後
+func chunkSplitPoint(data []byte) int {
+	if idx := bytes.LastIndexByte(data, '\n'); idx >= 0 {
+		return idx + 1
+	}
+
+	start := len(data) - 1
+	for start > 0 && len(data)-start < utf8.UTFMax && !utf8.RuneStart(data[start]) {
+		start--
+	}
+	if start < 0 || utf8.FullRune(data[start:]) {
+		return len(data)
+	}
+	return start
+}
+
+// handleFileError 將檔案讀取錯誤包裝為對應的錯誤碼
+func (tc *TokenCalculatorImpl) handleFileError(ctx context.Context, err error, path string) error {
+	code := errors.ErrCodeDataAccess
+	message := "讀取檔案失敗"
+	switch {
+	case os.IsNotExist(err):
+		code = errors.ErrCodeFileNotFound
+		message = "檔案不存在"
+	case os.IsPermission(err):
+		code = errors.ErrCodeFilePermission
+		message = "沒有讀取檔案的權限"
+	}
+
+	appErr := errors.Wrap(err, code, message)
+	appErr = appErr.WithContext(errors.ErrorContext{
+		Operation: "calculate_tokens_from_file",
+		Component: "token_calculator",
+		Parameters: map[string]interface{}{
+			"path": path,
+		},
+	})
+	return tc.errorHandler.Handle(ctx, appErr)
+}
@@ -0,0 +1,68 @@
+package calculator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"token-monitor/internal/errors"
+)
+
+// TestTokenCalculatorImpl_CalculateTokensFromFile 測試直接計算檔案的 Token 數量
+func TestTokenCalculatorImpl_CalculateTokensFromFile(t *testing.T) {
+	calculator := NewTokenCalculator(1000).(*TokenCalculatorImpl)
+	dir := t.TempDir()
+
+	content := "Hello world\n這是測試內容\n"
+	smallPath := filepath.Join(dir, "small.txt")
+	if err := os.WriteFile(smallPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	expected, _ := calculator.CalculateTokens(content, "estimation")
+	tokens, err := calculator.CalculateTokensFromFile(smallPath, "estimation")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tokens != expected {
+		t.Errorf("Expected %d tokens, got %d", expected, tokens)
+	}
+
+	// 超過 MaxTextSize 的檔案分段計算，不因長度限制失敗
+	line := strings.Repeat("word ", 19) + "中文\n"
+	large := strings.Repeat(line, MaxTextSize/len(line)+100)
+	largePath := filepath.Join(dir, "large.txt")
+	if err := os.WriteFile(largePath, []byte(large), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	tokens, err = calculator.CalculateTokensFromFile(largePath, "estimation")
+	if err != nil {
+		t.Fatalf("Unexpected error for large file: %v", err)
+	}
+	counter := NewIncrementalCounter(calculator)
+	approx := counter.Append(large)
+	if diff := tokens - approx; diff < -approx/100 || diff > approx/100 {
+		t.Errorf("Expected large file count near %d, got %d", approx, tokens)
+	}
+
+	_, err = calculator.CalculateTokensFromFile(filepath.Join(dir, "missing.txt"), "estimation")
+	if !errors.IsCode(err, errors.ErrCodeFileNotFound) {
+		t.Errorf("Expected ErrCodeFileNotFound, got %v", err)
+	}
+}
+
+// TestChunkSplitPoint 測試分段位置不會切斷換行後或 UTF-8 字元
+func TestChunkSplitPoint(t *testing.T) {
+	if cut := chunkSplitPoint([]byte("ab\ncd")); cut != 3 {
+		t.Errorf("Expected cut after newline, got %d", cut)
+	}
+
+	text := []byte("ab中")
+	if cut := chunkSplitPoint(text); cut != len(text) {
+		t.Errorf("Expected complete rune to be kept, got %d", cut)
+	}
+	if cut := chunkSplitPoint(text[:len(text)-1]); cut != 2 {
+		t.Errorf("Expected cut before partial rune, got %d", cut)
+	}
+}
@@ -0,0 +1,38 @@
+package calculator
+
+// IncrementalCounter 增量 Token 計數器，於文本追加時僅計算新增部分（僅支援估算方法）
+//
+// 計數器非並行安全；追加的字串應為完整的 UTF-8 字元。
+type IncrementalCounter struct {
+	calculator   *TokenCalculatorImpl
+	englishChars int
+	chineseChars int
+	length       int
+}
+
+// NewIncrementalCounter 建立增量 Token 計數器，使用計算器目前的估算參數與取整策略
+func NewIncrementalCounter(calculator *TokenCalculatorImpl) *IncrementalCounter {
+	return &IncrementalCounter{calculator: calculator}
+}
+
+// Append 追加文本並回傳目前緩衝區的估算 Token 總數
+func (ic *IncrementalCounter) Append(s string) int {
+	englishChars, chineseChars := countEstimationChars(s)
+	ic.englishChars += englishChars
+	ic.chineseChars += chineseChars
+	ic.length += len(s)
+
+	return ic.Count()
+}
+
+// Count 取得目前緩衝區的估算 Token 總數
+func (ic *IncrementalCounter) Count() int {
+	return ic.calculator.estimateFromCharCounts(ic.englishChars, ic.chineseChars, ic.length > 0)
+}
+
+// Reset 清空緩衝區計數
+func (ic *IncrementalCounter) Reset() {
+	ic.englishChars = 0
+	ic.chineseChars = 0
+	ic.length = 0
+}
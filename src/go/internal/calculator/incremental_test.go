@@ -0,0 +1,48 @@
+package calculator
+
+import (
+	"testing"
+)
+
+func TestIncrementalCounter_MatchesFullEstimation(t *testing.T) {
+	calculator := NewTokenCalculator(100).(*TokenCalculatorImpl)
+	counter := NewIncrementalCounter(calculator)
+
+	chunks := []string{"Hello", " world", "，你好", "世界", "! This is a longer sentence."}
+	buffer := ""
+	for _, chunk := range chunks {
+		buffer += chunk
+		got := counter.Append(chunk)
+
+		expected, err := calculator.calculateWithEstimation(buffer)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got != expected {
+			t.Errorf("After appending %q: expected %d tokens, got %d", chunk, expected, got)
+		}
+	}
+
+	if counter.Count() != counter.Append("") {
+		t.Errorf("Expected appending empty string to keep count")
+	}
+}
+
+func TestIncrementalCounter_Reset(t *testing.T) {
+	calculator := NewTokenCalculator(100).(*TokenCalculatorImpl)
+	counter := NewIncrementalCounter(calculator)
+
+	if counter.Count() != 0 {
+		t.Errorf("Expected 0 tokens for empty counter, got %d", counter.Count())
+	}
+
+	// 有內容時至少 1 個 token
+	if got := counter.Append("a"); got != 1 {
+		t.Errorf("Expected 1 token for single character, got %d", got)
+	}
+
+	counter.Reset()
+	if counter.Count() != 0 {
+		t.Errorf("Expected 0 tokens after reset, got %d", counter.Count())
+	}
+}
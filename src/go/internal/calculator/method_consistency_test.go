@@ -312,14 +312,15 @@ func TestMethodComparisonDetailedAnalysis(t *testing.T) {
 			var accuracySum float64
 
 			for i, text := range category.texts {
-				comparison := calculator.CompareCalculationMethods(text)
-				
-				estimation := comparison["estimation"].(map[string]interface{})["tokens"].(int)
-				tiktoken := comparison["tiktoken"].(map[string]interface{})["tokens"].(int)
-				comparisonData := comparison["comparison"].(map[string]interface{})
-				
-				accuracy := comparisonData["accuracy_percent"].(float64)
-				difference := comparisonData["difference"].(int)
+				comparison, err := calculator.CompareMethods(text)
+				if err != nil {
+					t.Fatalf("比較計算方法失敗: %v", err)
+				}
+
+				estimation := comparison.EstimationTokens
+				tiktoken := comparison.TiktokenTokens
+				accuracy := comparison.AccuracyPercent
+				difference := comparison.Difference
 
 				totalEstimation += estimation
 				totalTiktoken += tiktoken
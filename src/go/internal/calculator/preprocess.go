@@ -0,0 +1,120 @@
+package calculator
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"token-monitor/internal/errors"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// 無效 UTF-8 處理策略
+const (
+	InvalidUTF8Keep    = "keep"    // 保留原始位元組（預設）
+	InvalidUTF8Replace = "replace" // 替換為 U+FFFD
+	InvalidUTF8Reject  = "reject"  // 回傳 ErrCodeInvalidText
+)
+
+// SetNormalization 設定是否在計算前套用 Unicode NFC 正規化
+//
+// 啟用後，組合字元與預組字元（例如 "é" 與 "é"）會得到相同的 Token 數量
+// 並共用快取。預設停用以保持既有行為。
+func (tc *TokenCalculatorImpl) SetNormalization(enabled bool) {
+	tc.preprocessMutex.Lock()
+	defer tc.preprocessMutex.Unlock()
+
+	tc.normalizeUnicode = enabled
+}
+
+// SetTrimPolicy 設定是否在計算前去除文本頭尾的空白字元
+//
+// 僅影響計數與快取鍵，不會修改呼叫者持有的文本。預設停用以保持既有行為。
+func (tc *TokenCalculatorImpl) SetTrimPolicy(trim bool) {
+	tc.preprocessMutex.Lock()
+	defer tc.preprocessMutex.Unlock()
+
+	tc.trimWhitespace = trim
+}
+
+// SetInvalidUTF8Policy 設定無效 UTF-8 的處理策略（keep、replace、reject）
+func (tc *TokenCalculatorImpl) SetInvalidUTF8Policy(policy string) error {
+	switch policy {
+	case InvalidUTF8Keep, InvalidUTF8Replace, InvalidUTF8Reject:
+		tc.preprocessMutex.Lock()
+		tc.invalidUTF8Policy = policy
+		tc.preprocessMutex.Unlock()
+		return nil
+	default:
+		return fmt.Errorf("unsupported invalid UTF-8 policy: %s", policy)
+	}
+}
+
+// GetInvalidUTF8Stats 取得無效 UTF-8 處理統計
+func (tc *TokenCalculatorImpl) GetInvalidUTF8Stats() map[string]interface{} {
+	tc.preprocessMutex.RLock()
+	policy := tc.invalidUTF8Policy
+	tc.preprocessMutex.RUnlock()
+
+	return map[string]interface{}{
+		"policy":         policy,
+		"invalid_texts":  atomic.LoadInt64(&tc.invalidUTF8Texts),
+		"replaced_bytes": atomic.LoadInt64(&tc.invalidUTF8Bytes),
+		"rejected_texts": atomic.LoadInt64(&tc.invalidUTF8Rejects),
+	}
+}
+
+// hasPreprocessing 檢查是否啟用任何會改變文本的前處理設定
+func (tc *TokenCalculatorImpl) hasPreprocessing() bool {
+	tc.preprocessMutex.RLock()
+	defer tc.preprocessMutex.RUnlock()
+
+	return tc.normalizeUnicode || tc.trimWhitespace || tc.invalidUTF8Policy != InvalidUTF8Keep
+}
+
+// preprocessText 依目前設定對文本進行前處理
+func (tc *TokenCalculatorImpl) preprocessText(text string) (string, error) {
+	tc.preprocessMutex.RLock()
+	normalize, trim, policy := tc.normalizeUnicode, tc.trimWhitespace, tc.invalidUTF8Policy
+	tc.preprocessMutex.RUnlock()
+
+	if !utf8.ValidString(text) {
+		invalidBytes := countInvalidUTF8Bytes(text)
+		atomic.AddInt64(&tc.invalidUTF8Texts, 1)
+
+		switch policy {
+		case InvalidUTF8Reject:
+			atomic.AddInt64(&tc.invalidUTF8Rejects, 1)
+			return text, errors.Newf(errors.ErrCodeInvalidText, "文本包含無效的 UTF-8 序列: %d 個位元組", invalidBytes)
+		case InvalidUTF8Replace:
+			// 連續的無效位元組會被替換為單一 U+FFFD
+			atomic.AddInt64(&tc.invalidUTF8Bytes, int64(invalidBytes))
+			text = strings.ToValidUTF8(text, string(utf8.RuneError))
+		}
+	}
+
+	if normalize {
+		text = norm.NFC.String(text)
+	}
+
+	if trim {
+		text = strings.TrimSpace(text)
+	}
+
+	return text, nil
+}
+
+// countInvalidUTF8Bytes 計算文本中無效 UTF-8 位元組數量
+func countInvalidUTF8Bytes(text string) int {
+	count := 0
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if r == utf8.RuneError && size == 1 {
+			count++
+		}
+		i += size
+	}
+	return count
+}
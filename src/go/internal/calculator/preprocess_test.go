@@ -0,0 +1,153 @@
+package calculator
+
+import (
+	"sync"
+	"testing"
+
+	"token-monitor/internal/errors"
+)
+
+// TestUnicodeNormalization 測試 NFC 正規化
+func TestUnicodeNormalization(t *testing.T) {
+	calculator := NewTokenCalculator(100).(*TokenCalculatorImpl)
+
+	precomposed := "caf\u00e9 caf\u00e9 caf\u00e9 caf\u00e9"
+	combining := "cafe\u0301 cafe\u0301 cafe\u0301 cafe\u0301"
+
+	// 預設不正規化：組合字元多出一個字元
+	before, _ := calculator.CalculateTokens(precomposed, "estimation")
+	after, _ := calculator.CalculateTokens(combining, "estimation")
+	if before == after {
+		t.Errorf("Expected different counts without normalization, got %d for both", before)
+	}
+
+	calculator.ClearCache()
+	calculator.SetNormalization(true)
+
+	tokens1, err := calculator.CalculateTokens(precomposed, "estimation")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	tokens2, err := calculator.CalculateTokens(combining, "estimation")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if tokens1 != tokens2 {
+		t.Errorf("Expected identical counts after normalization, got %d and %d", tokens1, tokens2)
+	}
+
+	// 兩種寫法應共用同一個快取項目
+	if size := calculator.GetCacheStats()["cache_size"].(int); size != 1 {
+		t.Errorf("Expected a single cache entry, got %d", size)
+	}
+}
+
+// TestTrimPolicy 測試計算前去除頭尾空白
+func TestTrimPolicy(t *testing.T) {
+	calculator := NewTokenCalculator(100).(*TokenCalculatorImpl)
+
+	plain := "hello world, this is a test"
+	padded := "\n\n   " + plain + "   \t\n"
+
+	// 預設不去除：空白會增加計數
+	before, _ := calculator.CalculateTokens(padded, "estimation")
+	reference, _ := calculator.CalculateTokens(plain, "estimation")
+	if before <= reference {
+		t.Errorf("Expected padded text to count more without trimming, got %d vs %d", before, reference)
+	}
+
+	calculator.ClearCache()
+	calculator.SetTrimPolicy(true)
+
+	tokens1, err := calculator.CalculateTokens(plain, "estimation")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	tokens2, err := calculator.CalculateTokens(padded, "estimation")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tokens1 != tokens2 {
+		t.Errorf("Expected identical counts after trimming, got %d and %d", tokens1, tokens2)
+	}
+
+	// 快取鍵使用去除空白後的文本
+	if size := calculator.GetCacheStats()["cache_size"].(int); size != 1 {
+		t.Errorf("Expected a single cache entry, got %d", size)
+	}
+
+	if tokens, _ := calculator.CalculateTokens("   \n\t  ", "estimation"); tokens != 0 {
+		t.Errorf("Expected whitespace-only text to count 0 tokens after trimming, got %d", tokens)
+	}
+
+	within, tokens, err := calculator.IsWithinTokenLimit(padded, tokens1, "estimation")
+	if err != nil || !within || tokens != tokens1 {
+		t.Errorf("Expected trimmed count %d within limit, got %v %d %v", tokens1, within, tokens, err)
+	}
+}
+
+// TestInvalidUTF8Policy 測試無效 UTF-8 處理策略
+func TestInvalidUTF8Policy(t *testing.T) {
+	calculator := NewTokenCalculator(100).(*TokenCalculatorImpl)
+	invalid := "valid text \xff\xfe more text"
+
+	// 預設保留
+	if _, err := calculator.CalculateTokens(invalid, "estimation"); err != nil {
+		t.Errorf("Expected keep policy to succeed, got %v", err)
+	}
+
+	if err := calculator.SetInvalidUTF8Policy("replace"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	calculator.ClearCache()
+	if _, err := calculator.CalculateTokens(invalid, "estimation"); err != nil {
+		t.Errorf("Expected replace policy to succeed, got %v", err)
+	}
+
+	stats := calculator.GetInvalidUTF8Stats()
+	if stats["replaced_bytes"].(int64) != 2 {
+		t.Errorf("Expected 2 replaced bytes, got %v", stats["replaced_bytes"])
+	}
+
+	if err := calculator.SetInvalidUTF8Policy("reject"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	_, err := calculator.CalculateTokens(invalid, "estimation")
+	if !errors.IsCode(err, errors.ErrCodeInvalidText) {
+		t.Errorf("Expected ErrCodeInvalidText for rejected text, got %v", err)
+	}
+
+	stats = calculator.GetInvalidUTF8Stats()
+	if stats["invalid_texts"].(int64) != 3 || stats["rejected_texts"].(int64) != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	if err := calculator.SetInvalidUTF8Policy("drop"); err == nil {
+		t.Errorf("Expected error for unknown policy")
+	}
+}
+
+// TestPreprocessSettingsConcurrent 測試計算期間變更前處理設定不發生資料競爭（以 -race 執行）
+func TestPreprocessSettingsConcurrent(t *testing.T) {
+	calculator := NewTokenCalculator(100).(*TokenCalculatorImpl)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			calculator.SetTrimPolicy(i%2 == 0)
+			calculator.SetNormalization(i%2 == 1)
+			_ = calculator.SetInvalidUTF8Policy(InvalidUTF8Replace)
+		}(i)
+		go func() {
+			defer wg.Done()
+			if _, err := calculator.CalculateTokens("  hello world  ", "estimation"); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			_, _, _ = calculator.IsWithinTokenLimit("hello", 10, "estimation")
+		}()
+	}
+	wg.Wait()
+}
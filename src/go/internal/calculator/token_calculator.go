@@ -3,12 +3,15 @@ package calculator
 import (
 	"context"
 	"fmt"
+	"math"
 	"sync"
 	"time"
 	"unicode"
+	"unicode/utf8"
 
 	"token-monitor/internal/errors"
 	"token-monitor/internal/interfaces"
+	"token-monitor/internal/mathutil"
 	"token-monitor/internal/types"
 
 	"github.com/pkoukk/tiktoken-go"
@@ -19,13 +22,66 @@ type TokenCalculatorImpl struct {
 	cache           map[string]int
 	cacheMutex      sync.RWMutex
 	maxCacheSize    int
-	tiktokenEnabled bool
+	memoryPressure  func() bool // 回傳 true 時於寫入快取前自動壓縮
+	tiktokenEnabled bool        // 未停用 tiktoken；預設編碼器延遲至第一次使用才載入，載入失敗時設為 false
 	tiktokenEncoder *tiktoken.Tiktoken
+	tiktokenLoaded  bool  // 預設編碼器是否已嘗試載入（由 encoderMutex 保護）
+	tiktokenInitErr error // tiktoken 初始化失敗的原因
 	errorHandler    errors.ErrorHandler
 
 	// 估算演算法參數
 	englishCharsPerToken float64
 	chineseCharsPerToken float64
+
+	// settingsMutex 保護標示「由 settingsMutex 保護」的計算設定
+	settingsMutex        sync.RWMutex
+	estimationRounding   string // 估算結果取整策略：floor、round、ceil（由 settingsMutex 保護）
+	whitespaceOnlyTokens int    // 純空白文本的固定 Token 數，負數表示沿用計算結果（由 settingsMutex 保護）
+	autoMethodThreshold  int    // auto 方法改用估算的字符數門檻，0 表示不限制（由 settingsMutex 保護）
+
+	slowCalculationThreshold time.Duration // 超過此耗時的計算會記錄低嚴重度事件，0 表示停用（由 settingsMutex 保護）
+	controlCharThreshold     float64       // 控制字符佔文本長度超過此比例時拒絕（由 settingsMutex 保護）
+
+	// 自訂計算方法與備援鏈
+	methodMutex    sync.RWMutex
+	customMethods  map[string]MethodFunc
+	methodFallback []string
+	lastMethod     string // 最近一次產生結果的方法
+
+	// tiktoken 特殊 token 設定（皆為空時特殊 token 視為一般文本，由 encoderMutex 保護）
+	allowedSpecial    []string
+	disallowedSpecial []string
+
+	// 依模型延遲載入的 tiktoken 編碼器（預設編碼器仍為 tiktokenEncoder）；
+	// encoderMutex 同時保護 tiktokenEnabled、tiktokenEncoder 與 tiktokenLoaded
+	encoderMutex    sync.Mutex
+	modelEncodings  map[string]string             // 模型 -> 編碼名稱
+	encoders        map[string]*tiktoken.Tiktoken // 已載入的編碼器
+	encoderFailures map[string]error              // 載入失敗的編碼，不再重試
+	loadEncoding    func(string) (*tiktoken.Tiktoken, error)
+
+	// 文本前處理設定（preprocessMutex 保護下列策略設定，統計以 atomic 更新）
+	preprocessMutex    sync.RWMutex
+	normalizeUnicode   bool   // 計算前套用 NFC 正規化
+	invalidUTF8Policy  string // 無效 UTF-8 處理策略：keep、replace、reject
+	invalidUTF8Texts   int64  // 含無效 UTF-8 的文本數
+	invalidUTF8Bytes   int64  // 已替換的無效位元組數
+	invalidUTF8Rejects int64  // 因無效 UTF-8 而拒絕的文本數
+
+	trimWhitespace bool // 計算前去除頭尾空白
+
+	// 依活動類型的英文字符每 Token 比例（CalculateTokensTyped）
+	activityRatioMutex    sync.RWMutex
+	activityCharsPerToken map[types.ActivityType]float64
+
+	// base64 / data URI 內容處理策略（由 binaryMutex 保護）
+	binaryMutex         sync.RWMutex
+	binaryContentPolicy string
+	binaryTokensPerKB   int
+
+	// 各模型的上下文視窗大小（ContextUtilization）
+	contextMutex  sync.RWMutex
+	contextLimits map[string]int
 }
 
 // NewTokenCalculator 建立新的 Token 計算器
@@ -33,51 +89,116 @@ func NewTokenCalculator(maxCacheSize int) interfaces.TokenCalculator {
 	calc := &TokenCalculatorImpl{
 		cache:                make(map[string]int),
 		maxCacheSize:         maxCacheSize,
-		tiktokenEnabled:      false,
+		tiktokenEnabled:      true,
 		errorHandler:         errors.NewErrorHandler(),
 		englishCharsPerToken: 4.0, // 英文約 4 字符 = 1 token
 		chineseCharsPerToken: 1.5, // 中文約 1.5 字符 = 1 token
+		estimationRounding:   RoundingFloor,
+		whitespaceOnlyTokens: -1,
+		invalidUTF8Policy:    InvalidUTF8Keep,
+		modelEncodings:       make(map[string]string),
+		encoders:             make(map[string]*tiktoken.Tiktoken),
+		encoderFailures:      make(map[string]error),
+		loadEncoding:         tiktoken.GetEncoding,
+		customMethods:        make(map[string]MethodFunc),
+		controlCharThreshold: DefaultControlCharThreshold,
+
+		activityCharsPerToken: defaultActivityCharsPerToken(),
+		binaryContentPolicy:   BinaryContentCount,
+		binaryTokensPerKB:     DefaultBinaryTokensPerKB,
+		contextLimits:         defaultContextLimits(),
 	}
 
-	// 嘗試初始化 tiktoken
-	calc.initTiktoken()
-
+	// 預設編碼器延遲至第一次使用 tiktoken 時才載入，僅使用估算時不需載入
 	return calc
 }
 
 // CalculateTokens 計算文本的 Token 數量
 func (tc *TokenCalculatorImpl) CalculateTokens(text string, method string) (int, error) {
+	tokens, _, err := tc.CalculateTokensWithMethod(text, method)
+	return tokens, err
+}
+
+// CalculateTokensWithMethod 計算文本的 Token 數量，並回傳實際產生結果的方法（快取命中時為 MethodCache）。
+// 並行呼叫時應使用此方法而非 LastMethodUsed；空文本或套用純空白固定值時不經任何方法，回傳空字串。
+func (tc *TokenCalculatorImpl) CalculateTokensWithMethod(text string, method string) (int, string, error) {
 	ctx := context.Background()
-	
+
 	if text == "" {
-		return 0, nil
+		return 0, "", nil
 	}
 
-	// 驗證文本
-	if err := tc.ValidateText(text); err != nil {
-		appErr := errors.New(errors.ErrCodeInvalidText, "文本驗證失敗").WithCause(err)
+	// 文本前處理（UTF-8 修復、正規化等），快取鍵使用處理後的文本
+	text, err := tc.prepareText(ctx, text, method)
+	if err != nil {
+		return 0, "", err
+	}
+
+	// 依二進位內容策略處理 base64 / data URI 片段
+	text, binaryTokens := tc.applyBinaryContentPolicy(text)
+
+	tokens, usedMethod, err := tc.countPreparedText(ctx, text, method)
+	if err != nil {
+		return 0, "", err
+	}
+	return tokens + binaryTokens, usedMethod, nil
+}
+
+// countPreparedText 計算已前處理文本的 Token 數量與實際使用的方法，快取鍵為方法與處理後的文本
+func (tc *TokenCalculatorImpl) countPreparedText(ctx context.Context, text string, method string) (int, string, error) {
+	if text == "" {
+		return 0, "", nil
+	}
+
+	if tokens, ok := tc.whitespaceOnlyCount(text); ok {
+		return tokens, "", nil
+	}
+
+	// 檢查快取（不同方法的結果不同，快取鍵需包含方法）
+	cacheKey := methodCacheKey(method, text)
+	if tokens, found := tc.getCachedTokens(cacheKey); found {
+		tc.setLastMethod(MethodCache)
+		return tokens, MethodCache, nil
+	}
+
+	var tokens int
+	var err error
+	start := time.Now()
+	usedMethod := tc.ResolveMethod(text, method)
+
+	if chain := tc.fallbackChain(method); chain != nil {
+		tokens, usedMethod, err = tc.calculateWithFallback(text, chain)
+	} else {
+		tokens, err = tc.calculateWithDefaultMethod(ctx, text, method)
+	}
+
+	if err != nil {
+		appErr := errors.Wrap(err, errors.ErrCodeTokenCalculation, "Token 計算失敗")
 		appErr = appErr.WithContext(errors.ErrorContext{
-			Operation:  "validate_text",
+			Operation:  "calculate_tokens",
 			Component:  "token_calculator",
 			Parameters: map[string]interface{}{
 				"text_length": len(text),
 				"method":      method,
 			},
 		})
-		return 0, tc.errorHandler.Handle(ctx, appErr)
+		return 0, "", tc.errorHandler.Handle(ctx, appErr)
 	}
 
-	// 檢查快取
-	if tokens, found := tc.getCachedTokens(text); found {
-		return tokens, nil
-	}
+	tc.setLastMethod(usedMethod)
+	tc.reportSlowCalculation(ctx, text, usedMethod, time.Since(start))
 
-	var tokens int
-	var err error
+	// 儲存到快取
+	tc.setCachedTokens(cacheKey, tokens)
+
+	return tokens, usedMethod, nil
+}
 
+// calculateWithDefaultMethod 未設定備援鏈時的方法選擇：tiktoken 不可用時回退到估算
+func (tc *TokenCalculatorImpl) calculateWithDefaultMethod(ctx context.Context, text string, method string) (tokens int, err error) {
 	switch method {
 	case "tiktoken":
-		if tc.tiktokenEnabled {
+		if tc.tiktokenAvailable() {
 			tokens, err = tc.calculateWithTiktoken(text)
 		} else {
 			// tiktoken 不可用，記錄警告並回退到估算方法
@@ -92,31 +213,48 @@ func (tc *TokenCalculatorImpl) CalculateTokens(text string, method string) (int,
 	case "estimation":
 		tokens, err = tc.calculateWithEstimation(text)
 	default:
-		// 預設使用最佳可用方法
-		if tc.tiktokenEnabled {
+		// 預設依文本選擇方法：短文本使用 tiktoken，超過門檻的長文本使用估算
+		if tc.ResolveMethod(text, method) == "tiktoken" {
 			tokens, err = tc.calculateWithTiktoken(text)
 		} else {
 			tokens, err = tc.calculateWithEstimation(text)
 		}
 	}
 
+	return tokens, err
+}
+
+// prepareText 對文本進行前處理並驗證，回傳處理後的文本
+func (tc *TokenCalculatorImpl) prepareText(ctx context.Context, text string, method string) (string, error) {
+	text, err := tc.preprocessText(text)
 	if err != nil {
-		appErr := errors.Wrap(err, errors.ErrCodeTokenCalculation, "Token 計算失敗")
+		appErr := errors.Wrap(err, errors.ErrCodeInvalidText, "文本前處理失敗")
 		appErr = appErr.WithContext(errors.ErrorContext{
-			Operation:  "calculate_tokens",
+			Operation:  "preprocess_text",
 			Component:  "token_calculator",
 			Parameters: map[string]interface{}{
 				"text_length": len(text),
 				"method":      method,
 			},
 		})
-		return 0, tc.errorHandler.Handle(ctx, appErr)
+		return "", tc.errorHandler.Handle(ctx, appErr)
 	}
 
-	// 儲存到快取
-	tc.setCachedTokens(text, tokens)
+	// 驗證文本
+	if err := tc.ValidateText(text); err != nil {
+		appErr := errors.New(errors.ErrCodeInvalidText, "文本驗證失敗").WithCause(err)
+		appErr = appErr.WithContext(errors.ErrorContext{
+			Operation:  "validate_text",
+			Component:  "token_calculator",
+			Parameters: map[string]interface{}{
+				"text_length": len(text),
+				"method":      method,
+			},
+		})
+		return "", tc.errorHandler.Handle(ctx, appErr)
+	}
 
-	return tokens, nil
+	return text, nil
 }
 
 // calculateWithEstimation 使用估算演算法計算 Token
@@ -131,10 +269,13 @@ func (tc *TokenCalculatorImpl) calculateWithEstimation(text string) (int, error)
 	default:
 	}
 	
-	// 分離英文和中文字符
-	englishChars := 0
-	chineseChars := 0
+	englishChars, chineseChars := countEstimationChars(text)
 
+	return tc.estimateFromCharCounts(englishChars, chineseChars, len(text) > 0), nil
+}
+
+// countEstimationChars 分離英文和中文字符數量
+func countEstimationChars(text string) (englishChars, chineseChars int) {
 	for _, r := range text {
 		if r <= unicode.MaxASCII {
 			// ASCII 字符（包括英文、數字、符號）
@@ -147,27 +288,35 @@ func (tc *TokenCalculatorImpl) calculateWithEstimation(text string) (int, error)
 			englishChars++
 		}
 	}
+	return englishChars, chineseChars
+}
 
-	// 計算 Token 數量
-	englishTokens := float64(englishChars) / tc.englishCharsPerToken
+// estimateFromCharCounts 由字符數量估算 Token 數量
+func (tc *TokenCalculatorImpl) estimateFromCharCounts(englishChars, chineseChars int, hasContent bool) int {
+	return tc.estimateWithRatio(englishChars, chineseChars, tc.englishCharsPerToken, hasContent)
+}
+
+// estimateWithRatio 以指定的英文字符每 Token 比例估算 Token 數量
+func (tc *TokenCalculatorImpl) estimateWithRatio(englishChars, chineseChars int, englishCharsPerToken float64, hasContent bool) int {
+	englishTokens := float64(englishChars) / englishCharsPerToken
 	chineseTokens := float64(chineseChars) / tc.chineseCharsPerToken
 
-	totalTokens := int(englishTokens + chineseTokens)
+	totalTokens := tc.roundEstimate(englishTokens + chineseTokens)
 
 	// 至少 1 個 token（如果有內容的話）
-	if totalTokens == 0 && len(text) > 0 {
+	if totalTokens == 0 && hasContent {
 		totalTokens = 1
 	}
 
-	return totalTokens, nil
+	return totalTokens
 }
 
-// initTiktoken 初始化 tiktoken 編碼器
+// initTiktoken 初始化 tiktoken 編碼器（呼叫者需持有 encoderMutex）
 func (tc *TokenCalculatorImpl) initTiktoken() {
 	ctx := context.Background()
 	
 	// 嘗試初始化 tiktoken 編碼器 (使用 cl100k_base，適用於 GPT-3.5/GPT-4)
-	encoder, err := tiktoken.GetEncoding("cl100k_base")
+	encoder, err := tc.loadEncoding(defaultEncoding)
 	if err != nil {
 		warnErr := errors.Wrap(err, errors.ErrCodeTiktokenUnavailable, "Tiktoken 初始化失敗")
 		warnErr = warnErr.WithContext(errors.ErrorContext{
@@ -179,6 +328,7 @@ func (tc *TokenCalculatorImpl) initTiktoken() {
 		})
 		tc.errorHandler.Handle(ctx, warnErr)
 		tc.tiktokenEnabled = false
+		tc.tiktokenInitErr = err
 		return
 	}
 
@@ -187,31 +337,99 @@ func (tc *TokenCalculatorImpl) initTiktoken() {
 	fmt.Println("✅ Tiktoken initialized successfully")
 }
 
+// defaultEncoder 取得預設編碼器，第一次使用時才載入；tiktoken 停用或載入失敗時回傳 nil
+func (tc *TokenCalculatorImpl) defaultEncoder() *tiktoken.Tiktoken {
+	tc.encoderMutex.Lock()
+	defer tc.encoderMutex.Unlock()
+
+	return tc.defaultEncoderLocked()
+}
+
+// defaultEncoderLocked 同 defaultEncoder（呼叫者需持有 encoderMutex）
+func (tc *TokenCalculatorImpl) defaultEncoderLocked() *tiktoken.Tiktoken {
+	if tc.tiktokenEnabled && tc.tiktokenEncoder == nil && !tc.tiktokenLoaded {
+		tc.tiktokenLoaded = true
+		tc.initTiktoken()
+	}
+	if !tc.tiktokenEnabled {
+		return nil
+	}
+	return tc.tiktokenEncoder
+}
+
+// tiktokenAvailable 檢查 tiktoken 是否可用，必要時載入預設編碼器
+func (tc *TokenCalculatorImpl) tiktokenAvailable() bool {
+	return tc.defaultEncoder() != nil
+}
+
 // calculateWithTiktoken 使用 tiktoken 計算 Token
 func (tc *TokenCalculatorImpl) calculateWithTiktoken(text string) (int, error) {
-	if !tc.tiktokenEnabled || tc.tiktokenEncoder == nil {
+	encoder := tc.defaultEncoder()
+	if encoder == nil {
 		return tc.calculateWithEstimation(text)
 	}
 
+	return tc.encodeWithTiktoken(encoder, text)
+}
+
+// encodeWithTiktoken 使用指定的編碼器計算 Token
+func (tc *TokenCalculatorImpl) encodeWithTiktoken(encoder *tiktoken.Tiktoken, text string) (int, error) {
+	tokens, err := tc.encodeIDsWithTiktoken(encoder, text)
+	if err != nil {
+		return 0, err
+	}
+	return len(tokens), nil
+}
+
+// encodeIDsWithTiktoken 使用指定的編碼器取得 Token ID
+func (tc *TokenCalculatorImpl) encodeIDsWithTiktoken(encoder *tiktoken.Tiktoken, text string) (tokens []int, err error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 	
 	// 檢查上下文是否已取消
 	select {
 	case <-ctx.Done():
-		return 0, errors.New(errors.ErrCodeCalculationTimeout, "Tiktoken 計算超時")
+		return nil, errors.New(errors.ErrCodeCalculationTimeout, "Tiktoken 計算超時")
 	default:
 	}
 
-	// 使用 tiktoken 進行精確計算
+	// 使用 tiktoken 進行精確計算（文本含禁止的特殊 token 時 Encode 會恐慌，轉為錯誤回傳）
 	defer func() {
 		if r := recover(); r != nil {
-			panic(errors.New(errors.ErrCodeTokenCalculation, fmt.Sprintf("Tiktoken 計算發生恐慌: %v", r)))
+			tokens = nil
+			err = errors.New(errors.ErrCodeTokenCalculation, fmt.Sprintf("Tiktoken 計算發生恐慌: %v", r))
 		}
 	}()
 	
-	tokens := tc.tiktokenEncoder.Encode(text, nil, nil)
-	return len(tokens), nil
+	allowed, disallowed := tc.specialTokenPolicy()
+	return encoder.Encode(text, allowed, disallowed), nil
+}
+
+// EncodeTokens 取得文本的 tiktoken Token ID，tiktoken 未啟用時回傳錯誤
+func (tc *TokenCalculatorImpl) EncodeTokens(text string) ([]int, error) {
+	encoder := tc.defaultEncoder()
+	if encoder == nil {
+		return nil, errors.New(errors.ErrCodeTiktokenUnavailable, "Tiktoken 不可用，無法取得 Token ID")
+	}
+
+	return tc.encodeIDsWithTiktoken(encoder, text)
+}
+
+// DecodeTokens 將 tiktoken Token ID 還原為文本，tiktoken 未啟用或含未知 ID 時回傳錯誤
+func (tc *TokenCalculatorImpl) DecodeTokens(ids []int) (string, error) {
+	encoder := tc.defaultEncoder()
+	if encoder == nil {
+		return "", errors.New(errors.ErrCodeTiktokenUnavailable, "Tiktoken 不可用，無法解碼 Token ID")
+	}
+
+	// tiktoken 解碼會略過未知 ID，逐一檢查以免靜默遺失內容
+	for i, id := range ids {
+		if encoder.Decode([]int{id}) == "" {
+			return "", errors.Newf(errors.ErrCodeTokenCalculation, "未知的 Token ID: %d（位置 %d）", id, i)
+		}
+	}
+
+	return encoder.Decode(ids), nil
 }
 
 // AnalyzeTokenDistribution 分析 Token 分佈
@@ -240,8 +458,8 @@ func (tc *TokenCalculatorImpl) AnalyzeTokenDistribution(text string) (*types.Tok
 	}
 
 	// 計算各類型的 Token 數量
-	englishTokens := int(float64(englishChars) / tc.englishCharsPerToken)
-	chineseTokens := int(float64(chineseChars) / tc.chineseCharsPerToken)
+	englishTokens := tc.roundEstimate(float64(englishChars) / tc.englishCharsPerToken)
+	chineseTokens := tc.roundEstimate(float64(chineseChars) / tc.chineseCharsPerToken)
 	totalTokens := englishTokens + chineseTokens
 
 	if totalTokens == 0 && len(text) > 0 {
@@ -254,7 +472,7 @@ func (tc *TokenCalculatorImpl) AnalyzeTokenDistribution(text string) (*types.Tok
 	}
 
 	method := "estimation"
-	if tc.tiktokenEnabled {
+	if tc.tiktokenAvailable() {
 		method = "tiktoken"
 		// 如果使用 tiktoken，重新計算總 Token 數
 		if actualTotalTokens, err := tc.calculateWithTiktoken(text); err == nil {
@@ -278,7 +496,7 @@ func (tc *TokenCalculatorImpl) AnalyzeTokenDistribution(text string) (*types.Tok
 
 // IsTiktokenAvailable 檢查 tiktoken 是否可用
 func (tc *TokenCalculatorImpl) IsTiktokenAvailable() bool {
-	return tc.tiktokenEnabled
+	return tc.tiktokenAvailable()
 }
 
 // ClearCache 清除快取
@@ -289,29 +507,150 @@ func (tc *TokenCalculatorImpl) ClearCache() {
 	tc.cache = make(map[string]int)
 }
 
+// CompactCache 將快取縮減至 targetSize 筆，回傳移除的項目數
+//
+// 目前快取未記錄存取順序，保留的項目不保證為最近使用者。
+func (tc *TokenCalculatorImpl) CompactCache(targetSize int) int {
+	tc.cacheMutex.Lock()
+	defer tc.cacheMutex.Unlock()
+
+	return tc.compactCacheLocked(targetSize)
+}
+
+// CacheMemoryPressureHook 設定記憶體壓力檢查函數，回傳 true 時寫入快取前先壓縮至上限的一半
+//
+// 檢查函數在持有快取鎖時呼叫，不可再呼叫計算器的方法；傳入 nil 可移除。
+func (tc *TokenCalculatorImpl) CacheMemoryPressureHook(hook func() bool) {
+	tc.cacheMutex.Lock()
+	defer tc.cacheMutex.Unlock()
+
+	tc.memoryPressure = hook
+}
+
+// compactCacheLocked 縮減快取項目（呼叫者需持有 cacheMutex）
+func (tc *TokenCalculatorImpl) compactCacheLocked(targetSize int) int {
+	if targetSize < 0 {
+		targetSize = 0
+	}
+
+	removed := 0
+	for k := range tc.cache {
+		if len(tc.cache) <= targetSize {
+			break
+		}
+		delete(tc.cache, k)
+		removed++
+	}
+	return removed
+}
+
+// SetAutoMethodThreshold 設定 auto 方法的字符數門檻，超過門檻的文本改用估算（0 或負數表示不限制）
+func (tc *TokenCalculatorImpl) SetAutoMethodThreshold(chars int) {
+	if chars < 0 {
+		chars = 0
+	}
+	tc.settingsMutex.Lock()
+	tc.autoMethodThreshold = chars
+	tc.settingsMutex.Unlock()
+
+	// 門檻變更可能改變已快取文本的計算方法
+	tc.ClearCache()
+}
+
+// ResolveMethod 取得 CalculateTokens 對指定文本實際使用的計算方法（tiktoken 或 estimation）
+func (tc *TokenCalculatorImpl) ResolveMethod(text string, method string) string {
+	switch method {
+	case "estimation":
+		return "estimation"
+	case "tiktoken":
+		if tc.tiktokenAvailable() {
+			return "tiktoken"
+		}
+		return "estimation"
+	}
+
+	if !tc.tiktokenAvailable() {
+		return "estimation"
+	}
+	if threshold := tc.autoThreshold(); threshold > 0 && utf8.RuneCountInString(text) > threshold {
+		return "estimation"
+	}
+	return "tiktoken"
+}
+
+// autoThreshold 取得 auto 方法目前的字符數門檻
+func (tc *TokenCalculatorImpl) autoThreshold() int {
+	tc.settingsMutex.RLock()
+	defer tc.settingsMutex.RUnlock()
+	return tc.autoMethodThreshold
+}
+
+// SetSlowCalculationThreshold 設定慢速計算門檻，超過門檻的計算會透過錯誤處理器記錄低嚴重度事件（0 或負數表示停用）
+func (tc *TokenCalculatorImpl) SetSlowCalculationThreshold(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	tc.settingsMutex.Lock()
+	tc.slowCalculationThreshold = d
+	tc.settingsMutex.Unlock()
+}
+
+// reportSlowCalculation 計算耗時超過門檻時記錄事件，僅包含文本長度與實際使用的方法，不記錄文本內容
+func (tc *TokenCalculatorImpl) reportSlowCalculation(ctx context.Context, text string, method string, elapsed time.Duration) {
+	tc.settingsMutex.RLock()
+	threshold := tc.slowCalculationThreshold
+	tc.settingsMutex.RUnlock()
+
+	if threshold <= 0 || elapsed <= threshold {
+		return
+	}
+
+	slowErr := errors.Newf(errors.ErrCodeSlowCalculation, "Token 計算耗時 %s，超過門檻 %s", elapsed, threshold)
+	slowErr = slowErr.WithContext(errors.ErrorContext{
+		Operation: "calculate_tokens",
+		Component: "token_calculator",
+		Parameters: map[string]interface{}{
+			"text_length": len(text),
+			"method":      method,
+			"elapsed_ms":  float64(elapsed.Microseconds()) / 1000,
+		},
+	})
+	tc.errorHandler.Handle(ctx, slowErr)
+}
+
 // GetSupportedMethods 取得支援的計算方法
 func (tc *TokenCalculatorImpl) GetSupportedMethods() []string {
 	methods := []string{"estimation"}
-	if tc.tiktokenEnabled {
+	if tc.tiktokenAvailable() {
 		methods = append(methods, "tiktoken")
 	}
-	return methods
+	return append(methods, tc.customMethodNames()...)
+}
+
+// methodCacheKey 產生依計算方法區分的快取鍵
+func methodCacheKey(method, text string) string {
+	return method + "\x00" + text
 }
 
 // getCachedTokens 從快取取得 Token 數量
-func (tc *TokenCalculatorImpl) getCachedTokens(text string) (int, bool) {
+func (tc *TokenCalculatorImpl) getCachedTokens(key string) (int, bool) {
 	tc.cacheMutex.RLock()
 	defer tc.cacheMutex.RUnlock()
 
-	tokens, found := tc.cache[text]
+	tokens, found := tc.cache[key]
 	return tokens, found
 }
 
 // setCachedTokens 設定快取的 Token 數量
-func (tc *TokenCalculatorImpl) setCachedTokens(text string, tokens int) {
+func (tc *TokenCalculatorImpl) setCachedTokens(key string, tokens int) {
 	tc.cacheMutex.Lock()
 	defer tc.cacheMutex.Unlock()
 
+	// 記憶體壓力下主動壓縮
+	if tc.memoryPressure != nil && tc.memoryPressure() {
+		tc.compactCacheLocked(tc.maxCacheSize / 2)
+	}
+
 	// 如果快取已滿，清除一些舊的項目
 	if len(tc.cache) >= tc.maxCacheSize {
 		// 簡單的清除策略：清除一半的快取
@@ -326,7 +665,7 @@ func (tc *TokenCalculatorImpl) setCachedTokens(text string, tokens int) {
 		tc.cache = newCache
 	}
 
-	tc.cache[text] = tokens
+	tc.cache[key] = tokens
 }
 
 // SetEstimationParameters 設定估算演算法參數
@@ -335,6 +674,82 @@ func (tc *TokenCalculatorImpl) SetEstimationParameters(englishCharsPerToken, chi
 	tc.chineseCharsPerToken = chineseCharsPerToken
 }
 
+// SetWhitespaceOnlyTokens 設定純空白文本（如 "   \n\t"）的固定 Token 數，負數表示沿用各方法的計算結果（預設）。
+// 估算方法對任何非空文本至少回傳 1，tiktoken 則可能不同；設定後兩種方法結果一致。變更後會清除快取。
+func (tc *TokenCalculatorImpl) SetWhitespaceOnlyTokens(n int) {
+	if n < 0 {
+		n = -1
+	}
+	tc.settingsMutex.Lock()
+	tc.whitespaceOnlyTokens = n
+	tc.settingsMutex.Unlock()
+	tc.ClearCache()
+}
+
+// whitespaceOnlyCount 文本為純空白且已設定固定值時回傳該值
+func (tc *TokenCalculatorImpl) whitespaceOnlyCount(text string) (int, bool) {
+	tc.settingsMutex.RLock()
+	fixed := tc.whitespaceOnlyTokens
+	tc.settingsMutex.RUnlock()
+
+	if fixed < 0 || text == "" {
+		return 0, false
+	}
+
+	for _, r := range text {
+		if !unicode.IsSpace(r) {
+			return 0, false
+		}
+	}
+
+	return fixed, true
+}
+
+// 估算結果取整策略
+const (
+	RoundingFloor = "floor" // 無條件捨去（既有行為，系統性低估）
+	RoundingRound = "round" // 四捨五入（混合文本通常較接近 tiktoken）
+	RoundingCeil  = "ceil"  // 無條件進位（保守高估，適合上限檢查）
+)
+
+// SetEstimationRounding 設定估算結果的取整策略，空字串恢復預設的 "floor"。
+//
+// 預設維持 floor 以保持既有結果；floor 每段文本平均低估約 0.5 token、
+// ceil 平均高估約 0.5 token，round 的誤差則接近零。變更後會清除快取。
+func (tc *TokenCalculatorImpl) SetEstimationRounding(policy string) error {
+	if policy == "" {
+		policy = RoundingFloor
+	}
+
+	switch policy {
+	case RoundingFloor, RoundingRound, RoundingCeil:
+		tc.settingsMutex.Lock()
+		tc.estimationRounding = policy
+		tc.settingsMutex.Unlock()
+
+		tc.ClearCache()
+		return nil
+	default:
+		return fmt.Errorf("unsupported estimation rounding policy: %s", policy)
+	}
+}
+
+// roundEstimate 依取整策略將估算值轉為整數
+func (tc *TokenCalculatorImpl) roundEstimate(estimate float64) int {
+	tc.settingsMutex.RLock()
+	policy := tc.estimationRounding
+	tc.settingsMutex.RUnlock()
+
+	switch policy {
+	case RoundingRound:
+		return int(mathutil.RoundTo(estimate, 0))
+	case RoundingCeil:
+		return int(math.Ceil(estimate))
+	default:
+		return int(estimate)
+	}
+}
+
 // GetCacheStats 取得快取統計資訊
 func (tc *TokenCalculatorImpl) GetCacheStats() map[string]interface{} {
 	tc.cacheMutex.RLock()
@@ -344,7 +759,7 @@ func (tc *TokenCalculatorImpl) GetCacheStats() map[string]interface{} {
 		"cache_size":       len(tc.cache),
 		"max_cache_size":   tc.maxCacheSize,
 		"cache_usage":      float64(len(tc.cache)) / float64(tc.maxCacheSize),
-		"tiktoken_enabled": tc.tiktokenEnabled,
+		"tiktoken_enabled": tc.tiktokenAvailable(),
 	}
 }
 
@@ -363,9 +778,104 @@ func (tc *TokenCalculatorImpl) CalculateTokensForMultipleTexts(texts []string, m
 	return results, nil
 }
 
+// IsWithinTokenLimit 檢查文本的 Token 數是否不超過上限。
+// 估算方法在確定超過上限時即提前結束並回傳目前的部分計數；tiktoken 仍需完整編碼。
+func (tc *TokenCalculatorImpl) IsWithinTokenLimit(text string, limit int, method string) (bool, int, error) {
+	if limit < 0 {
+		return false, 0, errors.Newf(errors.ErrCodeInvalidTokenCount, "Token 上限不能為負數: %d", limit)
+	}
+
+	if !tc.canEstimateWithLimit(text, method) {
+		tokens, err := tc.CalculateTokens(text, method)
+		if err != nil {
+			return false, 0, err
+		}
+		return tokens <= limit, tokens, nil
+	}
+
+	if tokens, ok := tc.whitespaceOnlyCount(text); ok {
+		return tokens <= limit, tokens, nil
+	}
+
+	// 超過上限即提前結束，不需驗證整段文本
+	tokens, within := tc.estimateTokensWithLimit(text, limit)
+	if !within {
+		return false, tokens, nil
+	}
+
+	// 超過 MaxTextSize 的文本無法完整計算，視為超過上限而非錯誤
+	if len(text) > MaxTextSize {
+		return false, tokens, nil
+	}
+
+	// 估算已讀過整段文本，與 CalculateTokens 相同驗證控制字符等內容
+	if _, err := tc.prepareText(context.Background(), text, method); err != nil {
+		return false, 0, err
+	}
+
+	return true, tokens, nil
+}
+
+// canEstimateWithLimit 檢查能否使用估算的提前結束：文本不需前處理（正規化、去除空白、二進位內容處理或 UTF-8 修復），
+// 且一般方法解析會使用估算（未設定備援鏈、非自訂方法、未解析為 tiktoken）
+func (tc *TokenCalculatorImpl) canEstimateWithLimit(text string, method string) bool {
+	if tc.hasPreprocessing() || tc.hasBinaryContentPolicy() {
+		return false
+	}
+	if tc.fallbackChain(method) != nil {
+		return false
+	}
+	return tc.ResolveMethod(text, method) == MethodEstimation
+}
+
+// TokenDelta 計算文本修改前後的 Token 差異（after - before），負值表示修改節省了 Token；兩次計算皆使用快取
+func (tc *TokenCalculatorImpl) TokenDelta(before, after string, method string) (int, error) {
+	beforeTokens, err := tc.CalculateTokens(before, method)
+	if err != nil {
+		return 0, err
+	}
+
+	afterTokens, err := tc.CalculateTokens(after, method)
+	if err != nil {
+		return 0, err
+	}
+
+	return afterTokens - beforeTokens, nil
+}
+
+// estimateTokensWithLimit 以估算演算法計數，超過上限時提前結束
+func (tc *TokenCalculatorImpl) estimateTokensWithLimit(text string, limit int) (int, bool) {
+	englishChars := 0
+	chineseChars := 0
+
+	for _, r := range text {
+		if r <= unicode.MaxASCII || !unicode.Is(unicode.Han, r) {
+			englishChars++
+		} else {
+			chineseChars++
+		}
+
+		// 估算值只會隨字符增加而遞增，一旦超過上限即可確定結果
+		tokens := tc.roundEstimate(float64(englishChars)/tc.englishCharsPerToken + float64(chineseChars)/tc.chineseCharsPerToken)
+		if tokens > limit {
+			return tokens, false
+		}
+	}
+
+	tokens := tc.roundEstimate(float64(englishChars)/tc.englishCharsPerToken + float64(chineseChars)/tc.chineseCharsPerToken)
+	if tokens == 0 && len(text) > 0 {
+		tokens = 1
+	}
+
+	return tokens, tokens <= limit
+}
+
+// MaxTextSize 單次計算允許的最大文本長度（位元組）
+const MaxTextSize = 1000000
+
 // ValidateText 驗證文本是否適合 Token 計算
 func (tc *TokenCalculatorImpl) ValidateText(text string) error {
-	if len(text) > 1000000 { // 1MB 限制
+	if len(text) > MaxTextSize { // 1MB 限制
 		return errors.Newf(errors.ErrCodeInvalidText, "文本過長: %d 字符（最大限制: 1,000,000）", len(text))
 	}
 
@@ -377,30 +887,88 @@ func (tc *TokenCalculatorImpl) ValidateText(text string) error {
 		}
 	}
 
-	if controlChars > len(text)/10 { // 如果控制字符超過 10%
-		return errors.Newf(errors.ErrCodeInvalidText, "文本包含過多控制字符: %d（超過總長度的10%%）", controlChars)
+	tc.settingsMutex.RLock()
+	threshold := tc.controlCharThreshold
+	tc.settingsMutex.RUnlock()
+
+	if fraction := float64(controlChars) / float64(len(text)); controlChars > 0 && fraction > threshold {
+		appErr := errors.Newf(errors.ErrCodeInvalidText, "文本包含過多控制字符: %d（佔總長度的 %.2f%%，超過門檻 %.2f%%）",
+			controlChars, fraction*100, threshold*100)
+		return appErr.WithContext(errors.ErrorContext{
+			Operation: "validate_text",
+			Component: "token_calculator",
+			Parameters: map[string]interface{}{
+				"control_chars":          controlChars,
+				"control_char_fraction":  fraction,
+				"control_char_threshold": threshold,
+			},
+		})
 	}
 
 	return nil
 }
 
+// DefaultControlCharThreshold 預設的控制字符比例門檻（10%）
+const DefaultControlCharThreshold = 0.1
+
+// SetControlCharThreshold 設定控制字符（不含 Tab、換行、歸位）佔文本長度的比例門檻，超過時 ValidateText 拒絕文本（限制於 0 到 1 之間，NaN 保留目前門檻）
+func (tc *TokenCalculatorImpl) SetControlCharThreshold(fraction float64) {
+	// NaN 與任何值比較皆為 false，會使驗證失效
+	if math.IsNaN(fraction) {
+		return
+	}
+	tc.settingsMutex.Lock()
+	tc.controlCharThreshold = math.Max(0, math.Min(1, fraction))
+	tc.settingsMutex.Unlock()
+}
+
+// SetSpecialTokenPolicy 設定 tiktoken 的特殊 token 處理方式。
+// allowed 中的特殊 token（如 "<|endoftext|>"）會編碼為單一 token；文本含 disallowed 中的特殊 token 時
+// 回傳錯誤。兩者皆可使用 "all" 代表所有特殊 token；皆為空時特殊 token 視為一般文本（預設）。
+func (tc *TokenCalculatorImpl) SetSpecialTokenPolicy(allowed, disallowed []string) {
+	tc.encoderMutex.Lock()
+	tc.allowedSpecial = append([]string(nil), allowed...)
+	tc.disallowedSpecial = append([]string(nil), disallowed...)
+	tc.encoderMutex.Unlock()
+
+	tc.ClearCache()
+}
+
+// specialTokenPolicy 取得特殊 token 設定的快照（設定時整個替換切片，因此可直接共用）
+func (tc *TokenCalculatorImpl) specialTokenPolicy() (allowed, disallowed []string) {
+	tc.encoderMutex.Lock()
+	defer tc.encoderMutex.Unlock()
+
+	return tc.allowedSpecial, tc.disallowedSpecial
+}
+
 // GetTiktokenInfo 取得 tiktoken 資訊
 func (tc *TokenCalculatorImpl) GetTiktokenInfo() map[string]interface{} {
+	available := tc.tiktokenAvailable()
 	info := map[string]interface{}{
-		"enabled": tc.tiktokenEnabled,
+		"enabled": available,
 	}
 
-	if tc.tiktokenEnabled && tc.tiktokenEncoder != nil {
+	if available {
 		info["encoding"] = "cl100k_base"
 		info["model_compatibility"] = []string{"gpt-3.5-turbo", "gpt-4", "text-embedding-ada-002"}
 	}
 
+	loaded, failed := tc.encodingStatus()
+	info["loaded_encodings"] = loaded
+	info["failed_encodings"] = failed
+
+	info["auto_method_threshold"] = tc.autoThreshold()
+	allowed, disallowed := tc.specialTokenPolicy()
+	info["allowed_special"] = append([]string(nil), allowed...)
+	info["disallowed_special"] = append([]string(nil), disallowed...)
+
 	return info
 }
 
 // EnableTiktoken 手動啟用 tiktoken（如果初始化失敗）
 func (tc *TokenCalculatorImpl) EnableTiktoken() error {
-	if tc.tiktokenEnabled {
+	if tc.tiktokenAvailable() {
 		return nil // 已經啟用
 	}
 
@@ -409,13 +977,21 @@ func (tc *TokenCalculatorImpl) EnableTiktoken() error {
 		return fmt.Errorf("failed to initialize tiktoken: %w", err)
 	}
 
+	tc.encoderMutex.Lock()
+	defer tc.encoderMutex.Unlock()
+
 	tc.tiktokenEncoder = encoder
 	tc.tiktokenEnabled = true
+	tc.tiktokenLoaded = true
+	tc.tiktokenInitErr = nil
 	return nil
 }
 
 // DisableTiktoken 停用 tiktoken
 func (tc *TokenCalculatorImpl) DisableTiktoken() {
+	tc.encoderMutex.Lock()
+	defer tc.encoderMutex.Unlock()
+
 	tc.tiktokenEnabled = false
 	tc.tiktokenEncoder = nil
 }
@@ -435,7 +1011,7 @@ func (tc *TokenCalculatorImpl) CompareCalculationMethods(text string) map[string
 	}
 
 	// tiktoken 方法
-	if tc.tiktokenEnabled {
+	if tc.tiktokenAvailable() {
 		if tiktokenTokens, err := tc.calculateWithTiktoken(text); err == nil {
 			result["tiktoken"] = map[string]interface{}{
 				"tokens": tiktokenTokens,
@@ -446,7 +1022,7 @@ func (tc *TokenCalculatorImpl) CompareCalculationMethods(text string) map[string
 			if estimationData, ok := result["estimation"].(map[string]interface{}); ok {
 				if estimationTokens, ok := estimationData["tokens"].(int); ok {
 					difference := tiktokenTokens - estimationTokens
-					accuracy := 100.0 - (float64(abs(difference))/float64(tiktokenTokens))*100.0
+					accuracy := 100.0 - (float64(mathutil.AbsInt(difference))/float64(tiktokenTokens))*100.0
 					result["comparison"] = map[string]interface{}{
 						"difference":       difference,
 						"accuracy_percent": accuracy,
@@ -460,10 +1036,44 @@ func (tc *TokenCalculatorImpl) CompareCalculationMethods(text string) map[string
 	return result
 }
 
-// abs 計算絕對值
-func abs(x int) int {
-	if x < 0 {
-		return -x
+// MethodComparison 估算與 tiktoken 計算結果的比較
+type MethodComparison struct {
+	EstimationTokens int     `json:"estimation_tokens"`
+	TiktokenTokens   int     `json:"tiktoken_tokens"`
+	Difference       int     `json:"difference"` // tiktoken - 估算
+	AccuracyPercent  float64 `json:"accuracy_percent"`
+	PreferredMethod  string  `json:"preferred_method"`
+}
+
+// CompareMethods 比較估算與 tiktoken 的計算結果，tiktoken 不可用時回傳錯誤
+func (tc *TokenCalculatorImpl) CompareMethods(text string) (*MethodComparison, error) {
+	if !tc.tiktokenAvailable() {
+		return nil, errors.New(errors.ErrCodeTiktokenUnavailable, "Tiktoken 不可用，無法比較計算方法")
+	}
+
+	estimationTokens, err := tc.calculateWithEstimation(text)
+	if err != nil {
+		return nil, err
 	}
-	return x
+
+	tiktokenTokens, err := tc.calculateWithTiktoken(text)
+	if err != nil {
+		return nil, err
+	}
+
+	difference := tiktokenTokens - estimationTokens
+	accuracy := 100.0
+	if tiktokenTokens > 0 {
+		accuracy = 100.0 - (float64(mathutil.AbsInt(difference))/float64(tiktokenTokens))*100.0
+	} else if estimationTokens > 0 {
+		accuracy = 0
+	}
+
+	return &MethodComparison{
+		EstimationTokens: estimationTokens,
+		TiktokenTokens:   tiktokenTokens,
+		Difference:       difference,
+		AccuracyPercent:  accuracy,
+		PreferredMethod:  "tiktoken",
+	}, nil
 }
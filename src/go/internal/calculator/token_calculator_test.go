@@ -1,8 +1,16 @@
 package calculator
 
 import (
+	"context"
 	"fmt"
+	"math"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+	"token-monitor/internal/errors"
+
+	"github.com/pkoukk/tiktoken-go"
 )
 
 func TestTokenCalculatorImpl_CalculateTokens(t *testing.T) {
@@ -230,6 +238,42 @@ func TestTokenCalculatorImpl_ValidateText(t *testing.T) {
 	}
 }
 
+func TestTokenCalculatorImpl_ControlCharThreshold(t *testing.T) {
+	calculator := NewTokenCalculator(100).(*TokenCalculatorImpl)
+
+	// 20% 控制字符：預設 10% 門檻拒絕
+	text := "abcd\x00abcd\x01"
+	err := calculator.ValidateText(text)
+	if !errors.IsCode(err, errors.ErrCodeInvalidText) {
+		t.Fatalf("Expected ErrCodeInvalidText, got %v", err)
+	}
+	appErr := err.(*errors.AppError)
+	if fraction, ok := appErr.Context.Parameters["control_char_fraction"].(float64); !ok || fraction != 0.2 {
+		t.Errorf("Expected control char fraction 0.2 in error, got %v", appErr.Context.Parameters["control_char_fraction"])
+	}
+
+	// 放寬門檻後接受
+	calculator.SetControlCharThreshold(0.25)
+	if err := calculator.ValidateText(text); err != nil {
+		t.Errorf("Unexpected error with relaxed threshold: %v", err)
+	}
+
+	// Tab、換行與歸位不計入控制字符
+	calculator.SetControlCharThreshold(0)
+	if err := calculator.ValidateText("a\tb\nc\r"); err != nil {
+		t.Errorf("Unexpected error for tab/newline/CR: %v", err)
+	}
+	if err := calculator.ValidateText("abcdefghij\x00"); err == nil {
+		t.Errorf("Expected error with zero threshold")
+	}
+
+	// NaN 不可停用驗證
+	calculator.SetControlCharThreshold(math.NaN())
+	if err := calculator.ValidateText("abcdefghij\x00"); err == nil {
+		t.Errorf("Expected NaN threshold to keep the previous threshold")
+	}
+}
+
 func TestTokenCalculatorImpl_BatchCalculation(t *testing.T) {
 	calculator := NewTokenCalculator(100)
 
@@ -395,6 +439,43 @@ func TestTokenCalculatorImpl_CompareCalculationMethods(t *testing.T) {
 	}
 }
 
+func TestTokenCalculatorImpl_CompareMethods(t *testing.T) {
+	calculator := NewTokenCalculator(100).(*TokenCalculatorImpl)
+	calculator.DisableTiktoken()
+
+	if _, err := calculator.CompareMethods("Hello world"); !errors.IsCode(err, errors.ErrCodeTiktokenUnavailable) {
+		t.Errorf("Expected ErrCodeTiktokenUnavailable, got %v", err)
+	}
+
+	calculator.tiktokenEncoder = newTestTiktokenEncoder(t)
+	calculator.tiktokenEnabled = true
+
+	text := "Hello world"
+	comparison, err := calculator.CompareMethods(text)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	estimation, _ := calculator.calculateWithEstimation(text)
+	tiktokenTokens, _ := calculator.calculateWithTiktoken(text)
+
+	if comparison.EstimationTokens != estimation || comparison.TiktokenTokens != tiktokenTokens {
+		t.Errorf("Expected %d/%d tokens, got %+v", estimation, tiktokenTokens, comparison)
+	}
+	if comparison.Difference != tiktokenTokens-estimation {
+		t.Errorf("Expected difference %d, got %d", tiktokenTokens-estimation, comparison.Difference)
+	}
+	if comparison.PreferredMethod != "tiktoken" {
+		t.Errorf("Expected preferred method tiktoken, got %s", comparison.PreferredMethod)
+	}
+
+	// 與 map 版本結果一致
+	legacy := calculator.CompareCalculationMethods(text)["comparison"].(map[string]interface{})
+	if legacy["accuracy_percent"].(float64) != comparison.AccuracyPercent {
+		t.Errorf("Expected accuracy %f to match map version %f", comparison.AccuracyPercent, legacy["accuracy_percent"])
+	}
+}
+
 func TestTokenCalculatorImpl_TiktokenInfo(t *testing.T) {
 	calculator := NewTokenCalculator(100).(*TokenCalculatorImpl)
 
@@ -423,3 +504,584 @@ func min(a, b int) int {
 	}
 	return b
 }
+
+func TestTokenCalculatorImpl_IsWithinTokenLimit(t *testing.T) {
+	calculator := NewTokenCalculator(100).(*TokenCalculatorImpl)
+
+	text := "Hello world, 你好世界"
+	expected, err := calculator.CalculateTokens(text, "estimation")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	within, tokens, err := calculator.IsWithinTokenLimit(text, expected, "estimation")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !within || tokens != expected {
+		t.Errorf("Expected within limit with %d tokens, got within=%v tokens=%d", expected, within, tokens)
+	}
+
+	within, _, err = calculator.IsWithinTokenLimit(text, expected-1, "estimation")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if within {
+		t.Errorf("Expected text to exceed limit %d", expected-1)
+	}
+
+	// 超大文本應在超過上限時提前結束，只回傳部分計數
+	huge := strings.Repeat("a", 5000000)
+	within, tokens, err = calculator.IsWithinTokenLimit(huge, 100, "estimation")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if within {
+		t.Errorf("Expected huge text to exceed limit")
+	}
+	if tokens <= 100 || tokens > 1000 {
+		t.Errorf("Expected partial count just above limit, got %d", tokens)
+	}
+
+	if _, _, err := calculator.IsWithinTokenLimit(text, -1, "estimation"); err == nil {
+		t.Errorf("Expected error for negative limit")
+	}
+
+	// 超過 MaxTextSize 但估算在上限內時視為超過上限，不回傳錯誤
+	within, tokens, err = calculator.IsWithinTokenLimit(huge, 10000000, "estimation")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if within || tokens == 0 {
+		t.Errorf("Expected oversized text to be over the limit with a count, got within=%v tokens=%d", within, tokens)
+	}
+
+	// 上限內的文本與 CalculateTokens 相同先驗證
+	if _, _, err := calculator.IsWithinTokenLimit(strings.Repeat("\x01", 10)+"ab", 100, "estimation"); !errors.IsCode(err, errors.ErrCodeInvalidText) {
+		t.Errorf("Expected ErrCodeInvalidText for control characters, got %v", err)
+	}
+
+	// 自訂方法經一般方法解析，不走估算的提前結束
+	if err := calculator.RegisterMethod("fixed", func(string) (int, error) { return 42, nil }); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	within, tokens, err = calculator.IsWithinTokenLimit("custom method text", 50, "fixed")
+	if err != nil || !within || tokens != 42 {
+		t.Errorf("Expected custom method count 42 within limit, got within=%v tokens=%d err=%v", within, tokens, err)
+	}
+	if calculator.LastMethodUsed() != "fixed" {
+		t.Errorf("Expected custom method to be used, got %s", calculator.LastMethodUsed())
+	}
+}
+
+func TestTokenCalculatorImpl_EstimationRounding(t *testing.T) {
+	calculator := NewTokenCalculator(100).(*TokenCalculatorImpl)
+	text := "abcdefghij" // 10 個英文字符 = 2.5 tokens
+
+	testCases := []struct {
+		policy   string
+		expected int
+	}{
+		{RoundingFloor, 2},
+		{RoundingRound, 3},
+		{RoundingCeil, 3},
+		{"", 2}, // 空字串恢復預設的 floor
+	}
+
+	// 預設維持 floor
+	tokens, err := calculator.CalculateTokens(text, "estimation")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tokens != 2 {
+		t.Errorf("Expected default floor rounding to give 2 tokens, got %d", tokens)
+	}
+
+	for _, tc := range testCases {
+		if err := calculator.SetEstimationRounding(tc.policy); err != nil {
+			t.Fatalf("Unexpected error for policy %q: %v", tc.policy, err)
+		}
+		tokens, err := calculator.CalculateTokens(text, "estimation")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if tokens != tc.expected {
+			t.Errorf("Policy %q: expected %d tokens, got %d", tc.policy, tc.expected, tokens)
+		}
+	}
+
+	if err := calculator.SetEstimationRounding("truncate"); err == nil {
+		t.Errorf("Expected error for unsupported policy")
+	}
+}
+
+// TestTokenCalculatorImpl_SettingsConcurrent 測試計算期間變更計算設定不發生資料競爭（以 -race 執行）
+func TestTokenCalculatorImpl_SettingsConcurrent(t *testing.T) {
+	calculator := NewTokenCalculator(100).(*TokenCalculatorImpl)
+	calculator.tiktokenEncoder = newTestTiktokenEncoder(t)
+	policies := []string{RoundingFloor, RoundingRound, RoundingCeil}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			_ = calculator.SetEstimationRounding(policies[i%len(policies)])
+			calculator.SetSpecialTokenPolicy([]string{"all"}, nil)
+			calculator.SetWhitespaceOnlyTokens(i % 2)
+			calculator.SetAutoMethodThreshold(i * 5)
+			calculator.SetSlowCalculationThreshold(time.Duration(i) * time.Nanosecond)
+			calculator.SetControlCharThreshold(float64(i) / 10)
+		}(i)
+		go func() {
+			defer wg.Done()
+			if _, err := calculator.CalculateTokens("abcdefghij", "estimation"); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if _, err := calculator.CalculateTokens("abcdefghij", "tiktoken"); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if _, err := calculator.CalculateTokens("abcdefghij", "auto"); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if _, err := calculator.CalculateTokens(" \n\t ", "estimation"); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			_ = calculator.ValidateText("abc\x00defghij")
+			_ = calculator.GetTiktokenInfo()
+		}()
+	}
+	wg.Wait()
+}
+
+// newTestTiktokenEncoder 建立不需下載詞表的位元組級測試編碼器
+func newTestTiktokenEncoder(t *testing.T) *tiktoken.Tiktoken {
+	ranks := make(map[string]int, 256)
+	for i := 0; i < 256; i++ {
+		ranks[string([]byte{byte(i)})] = i
+	}
+	special := map[string]int{"<|endoftext|>": 256}
+
+	bpe, err := tiktoken.NewCoreBPE(ranks, special, `\S+|\s+`)
+	if err != nil {
+		t.Fatalf("Failed to build test encoder: %v", err)
+	}
+	return tiktoken.NewTiktoken(bpe, &tiktoken.Encoding{Name: "test"}, map[string]any{"<|endoftext|>": nil})
+}
+
+func TestTokenCalculatorImpl_SpecialTokenPolicy(t *testing.T) {
+	calculator := NewTokenCalculator(100).(*TokenCalculatorImpl)
+	calculator.tiktokenEncoder = newTestTiktokenEncoder(t)
+	calculator.tiktokenEnabled = true
+
+	text := "a<|endoftext|>b"
+
+	// 預設將特殊 token 視為一般文本，逐位元組編碼
+	tokens, err := calculator.CalculateTokens(text, "tiktoken")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tokens != len(text) {
+		t.Errorf("Expected special token to be encoded as text (%d tokens), got %d", len(text), tokens)
+	}
+
+	calculator.SetSpecialTokenPolicy([]string{"<|endoftext|>"}, nil)
+	tokens, err = calculator.CalculateTokens(text, "tiktoken")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tokens != 3 {
+		t.Errorf("Expected allowed special token to count as 1 token (3 total), got %d", tokens)
+	}
+
+	calculator.SetSpecialTokenPolicy(nil, []string{"<|endoftext|>"})
+	if _, err := calculator.CalculateTokens(text, "tiktoken"); err == nil {
+		t.Errorf("Expected error for disallowed special token")
+	}
+
+	info := calculator.GetTiktokenInfo()
+	if disallowed, ok := info["disallowed_special"].([]string); !ok || len(disallowed) != 1 {
+		t.Errorf("Expected disallowed special tokens in info, got %v", info["disallowed_special"])
+	}
+}
+
+func TestTokenCalculatorImpl_LazyEncoders(t *testing.T) {
+	calculator := NewTokenCalculator(100).(*TokenCalculatorImpl)
+
+	loads := make(map[string]int)
+	calculator.loadEncoding = func(name string) (*tiktoken.Tiktoken, error) {
+		loads[name]++
+		if name == "broken_base" {
+			return nil, fmt.Errorf("encoding %s unavailable", name)
+		}
+		return newTestTiktokenEncoder(t), nil
+	}
+	calculator.RegisterModelEncoding("custom-model", "test_base")
+	calculator.RegisterModelEncoding("broken-model", "broken_base")
+
+	// 建立與註冊時不應載入任何編碼，僅使用估算也不需載入預設編碼
+	if _, err := calculator.CalculateTokens("abc def", "estimation"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(loads) != 0 {
+		t.Errorf("Expected no encodings loaded at registration, got %v", loads)
+	}
+
+	for i := 0; i < 3; i++ {
+		tokens, err := calculator.CalculateTokensForModel("abc def", "custom-model")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if tokens != len("abc def") {
+			t.Errorf("Expected byte-level encoding to give %d tokens, got %d", len("abc def"), tokens)
+		}
+	}
+	if loads["test_base"] != 1 {
+		t.Errorf("Expected test_base to be loaded once, got %d", loads["test_base"])
+	}
+	// 重複計算使用快取，且快取依模型區分，不與估算結果混用
+	if calculator.LastMethodUsed() != MethodCache {
+		t.Errorf("Expected repeated model counts to use the cache, got %s", calculator.LastMethodUsed())
+	}
+	estimated, err := calculator.CalculateTokens("abc def", "estimation")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if estimated == len("abc def") {
+		t.Errorf("Expected estimation count to differ from the cached model count, got %d", estimated)
+	}
+
+	// 預設編碼在第一次使用 tiktoken 時才載入
+	if _, err := calculator.CalculateTokens("abc", "tiktoken"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if loads[defaultEncoding] != 1 || !calculator.IsTiktokenAvailable() {
+		t.Errorf("Expected default encoding to be loaded once on first use, got %d", loads[defaultEncoding])
+	}
+
+	// 載入失敗時回退到估算方法，且不再重試
+	for i := 0; i < 3; i++ {
+		tokens, err := calculator.CalculateTokensForModel("Hello world", "broken-model")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if tokens <= 0 {
+			t.Errorf("Expected estimation fallback to give positive tokens, got %d", tokens)
+		}
+	}
+	if loads["broken_base"] != 1 {
+		t.Errorf("Expected broken_base load to be attempted once, got %d", loads["broken_base"])
+	}
+
+	info := calculator.GetTiktokenInfo()
+	loaded, _ := info["loaded_encodings"].([]string)
+	failed, _ := info["failed_encodings"].([]string)
+	if len(loaded) == 0 || loaded[len(loaded)-1] != "test_base" {
+		t.Errorf("Expected test_base among loaded encodings, got %v", loaded)
+	}
+	if len(failed) != 1 || failed[0] != "broken_base" {
+		t.Errorf("Expected failed encodings [broken_base], got %v", failed)
+	}
+
+	// 停用 tiktoken 後依模型計算改用估算，不再載入任何編碼
+	calculator.DisableTiktoken()
+	loadsBefore := len(loads)
+	for _, model := range []string{"gpt-4", "custom-model"} {
+		text := "Hello tiktoken world for " + model
+		tokens, err := calculator.CalculateTokensForModel(text, model)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if calculator.LastMethodUsed() != MethodEstimation {
+			t.Errorf("Expected estimation for %s after DisableTiktoken, got %s", model, calculator.LastMethodUsed())
+		}
+		if tokens == len(text) {
+			t.Errorf("Expected estimated count for %s, got byte-level count %d", model, tokens)
+		}
+	}
+	if len(loads) != loadsBefore || loads[defaultEncoding] != 1 {
+		t.Errorf("Expected no encodings loaded after DisableTiktoken, got %v", loads)
+	}
+}
+
+func TestTokenCalculatorImpl_WhitespaceOnlyTokens(t *testing.T) {
+	calculator := NewTokenCalculator(100).(*TokenCalculatorImpl)
+	whitespaceTexts := []string{" ", "   \n\t  ", "\r\n\r\n", "　 "}
+
+	// 預設沿用估算結果：非空文本至少 1 個 token
+	for _, text := range whitespaceTexts {
+		tokens, err := calculator.CalculateTokens(text, "estimation")
+		if err != nil {
+			t.Fatalf("Unexpected error for %q: %v", text, err)
+		}
+		if tokens < 1 {
+			t.Errorf("Expected default to keep at least 1 token for %q, got %d", text, tokens)
+		}
+	}
+
+	calculator.SetWhitespaceOnlyTokens(0)
+	for _, method := range []string{"estimation", "tiktoken", ""} {
+		for _, text := range whitespaceTexts {
+			tokens, err := calculator.CalculateTokens(text, method)
+			if err != nil {
+				t.Fatalf("Unexpected error for %q: %v", text, err)
+			}
+			if tokens != 0 {
+				t.Errorf("Method %q: expected whitespace-only %q to count as 0, got %d", method, text, tokens)
+			}
+		}
+	}
+
+	within, tokens, err := calculator.IsWithinTokenLimit("   \n\t  ", 0, "estimation")
+	if err != nil || !within || tokens != 0 {
+		t.Errorf("Expected whitespace-only text within limit 0, got within=%v tokens=%d err=%v", within, tokens, err)
+	}
+
+	// 含非空白字符的文本不受影響
+	if tokens, _ := calculator.CalculateTokens("  a  ", "estimation"); tokens < 1 {
+		t.Errorf("Expected non-whitespace text to be counted, got %d", tokens)
+	}
+
+	calculator.SetWhitespaceOnlyTokens(-1)
+	if tokens, _ := calculator.CalculateTokens("   ", "estimation"); tokens != 1 {
+		t.Errorf("Expected default behavior to be restored, got %d", tokens)
+	}
+}
+
+func TestTokenCalculatorImpl_CompactCache(t *testing.T) {
+	calculator := NewTokenCalculator(100).(*TokenCalculatorImpl)
+
+	for i := 0; i < 10; i++ {
+		if _, err := calculator.CalculateTokens(fmt.Sprintf("text %d", i), "estimation"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	if removed := calculator.CompactCache(4); removed != 6 {
+		t.Errorf("Expected 6 entries removed, got %d", removed)
+	}
+	if size := calculator.GetCacheStats()["cache_size"].(int); size != 4 {
+		t.Errorf("Expected cache size 4, got %d", size)
+	}
+
+	// 已小於目標大小時不移除
+	if removed := calculator.CompactCache(10); removed != 0 {
+		t.Errorf("Expected no entries removed, got %d", removed)
+	}
+}
+
+func TestTokenCalculatorImpl_CacheMemoryPressureHook(t *testing.T) {
+	calculator := NewTokenCalculator(10).(*TokenCalculatorImpl)
+
+	for i := 0; i < 8; i++ {
+		calculator.CalculateTokens(fmt.Sprintf("text %d", i), "estimation")
+	}
+
+	underPressure := true
+	calculator.CacheMemoryPressureHook(func() bool { return underPressure })
+
+	// 寫入前壓縮至上限的一半（5 筆），再加入新項目
+	calculator.CalculateTokens("pressure text", "estimation")
+	if size := calculator.GetCacheStats()["cache_size"].(int); size != 6 {
+		t.Errorf("Expected cache size 6 after pressure compaction, got %d", size)
+	}
+
+	underPressure = false
+	calculator.CalculateTokens("relaxed text", "estimation")
+	if size := calculator.GetCacheStats()["cache_size"].(int); size != 7 {
+		t.Errorf("Expected cache size 7 without pressure, got %d", size)
+	}
+}
+
+func TestTokenCalculatorImpl_AutoMethodThreshold(t *testing.T) {
+	calculator := NewTokenCalculator(100).(*TokenCalculatorImpl)
+	calculator.tiktokenEncoder = newTestTiktokenEncoder(t)
+	calculator.tiktokenEnabled = true
+
+	shortText := "short text"
+	longText := strings.Repeat("long text ", 10)
+
+	// 未設定門檻時 auto 皆使用 tiktoken
+	if method := calculator.ResolveMethod(longText, "auto"); method != "tiktoken" {
+		t.Errorf("Expected tiktoken without threshold, got %s", method)
+	}
+
+	calculator.SetAutoMethodThreshold(50)
+
+	if method := calculator.ResolveMethod(shortText, "auto"); method != "tiktoken" {
+		t.Errorf("Expected tiktoken for short text, got %s", method)
+	}
+	if method := calculator.ResolveMethod(longText, "auto"); method != "estimation" {
+		t.Errorf("Expected estimation for long text, got %s", method)
+	}
+	// 明確指定方法時不受門檻影響
+	if method := calculator.ResolveMethod(longText, "tiktoken"); method != "tiktoken" {
+		t.Errorf("Expected explicit tiktoken to be kept, got %s", method)
+	}
+
+	expectedShort, _ := calculator.calculateWithTiktoken(shortText)
+	expectedLong, _ := calculator.calculateWithEstimation(longText)
+
+	if tokens, err := calculator.CalculateTokens(shortText, "auto"); err != nil || tokens != expectedShort {
+		t.Errorf("Expected %d tiktoken tokens for short text, got %d (err: %v)", expectedShort, tokens, err)
+	}
+	if tokens, err := calculator.CalculateTokens(longText, "auto"); err != nil || tokens != expectedLong {
+		t.Errorf("Expected %d estimated tokens for long text, got %d (err: %v)", expectedLong, tokens, err)
+	}
+
+	calculator.DisableTiktoken()
+	if method := calculator.ResolveMethod(shortText, "auto"); method != "estimation" {
+		t.Errorf("Expected estimation when tiktoken is disabled, got %s", method)
+	}
+}
+
+func TestTokenCalculatorImpl_EncodeTokens(t *testing.T) {
+	calculator := NewTokenCalculator(100).(*TokenCalculatorImpl)
+	calculator.DisableTiktoken()
+
+	if _, err := calculator.EncodeTokens("abc"); !errors.IsCode(err, errors.ErrCodeTiktokenUnavailable) {
+		t.Errorf("Expected ErrCodeTiktokenUnavailable, got %v", err)
+	}
+
+	calculator.tiktokenEncoder = newTestTiktokenEncoder(t)
+	calculator.tiktokenEnabled = true
+
+	ids, err := calculator.EncodeTokens("abc")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []int{'a', 'b', 'c'}
+	if fmt.Sprint(ids) != fmt.Sprint(expected) {
+		t.Errorf("Expected token IDs %v, got %v", expected, ids)
+	}
+
+	// Token ID 數量與計數一致
+	count, _ := calculator.calculateWithTiktoken("abc")
+	if len(ids) != count {
+		t.Errorf("Expected %d token IDs to match count, got %d", count, len(ids))
+	}
+}
+
+func TestTokenCalculatorImpl_DecodeTokens(t *testing.T) {
+	calculator := NewTokenCalculator(100).(*TokenCalculatorImpl)
+	calculator.DisableTiktoken()
+
+	if _, err := calculator.DecodeTokens([]int{97}); !errors.IsCode(err, errors.ErrCodeTiktokenUnavailable) {
+		t.Errorf("Expected ErrCodeTiktokenUnavailable, got %v", err)
+	}
+
+	calculator.tiktokenEncoder = newTestTiktokenEncoder(t)
+	calculator.tiktokenEnabled = true
+
+	// 編碼後解碼應還原原文
+	text := "Hello 世界"
+	ids, err := calculator.EncodeTokens(text)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	decoded, err := calculator.DecodeTokens(ids)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if decoded != text {
+		t.Errorf("Expected round trip to return %q, got %q", text, decoded)
+	}
+
+	if _, err := calculator.DecodeTokens([]int{97, 99999}); !errors.IsCode(err, errors.ErrCodeTokenCalculation) {
+		t.Errorf("Expected ErrCodeTokenCalculation for unknown ID, got %v", err)
+	}
+}
+
+// slowCalculationListener 記錄錯誤處理器收到的事件（監聽器以 goroutine 非同步通知）
+type slowCalculationListener struct {
+	events chan *errors.AppError
+}
+
+func (l *slowCalculationListener) OnError(ctx context.Context, err *errors.AppError) {
+	l.events <- err
+}
+
+func (l *slowCalculationListener) OnRecovery(ctx context.Context, err *errors.AppError, recovered bool) {
+}
+
+func TestTokenCalculatorImpl_SlowCalculationThreshold(t *testing.T) {
+	calculator := NewTokenCalculator(100).(*TokenCalculatorImpl)
+	listener := &slowCalculationListener{events: make(chan *errors.AppError, 4)}
+	calculator.errorHandler.RegisterListener(listener)
+
+	// 未設定門檻時不記錄
+	if _, err := calculator.CalculateTokens(strings.Repeat("untracked ", 100), "estimation"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	text := strings.Repeat("secret payload ", 200)
+	calculator.SetSlowCalculationThreshold(time.Nanosecond)
+	if _, err := calculator.CalculateTokens(text, "estimation"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var event *errors.AppError
+	select {
+	case event = <-listener.events:
+	case <-time.After(time.Second):
+		t.Fatal("Expected a slow calculation event")
+	}
+
+	if event.Code != errors.ErrCodeSlowCalculation || event.Severity != errors.SeverityLow {
+		t.Errorf("Expected low severity slow calculation event, got %s/%s", event.Code, event.Severity)
+	}
+	if event.Context.Parameters["text_length"] != len(text) || event.Context.Parameters["method"] != "estimation" {
+		t.Errorf("Unexpected event parameters: %v", event.Context.Parameters)
+	}
+
+	// 事件不得包含文本內容
+	if strings.Contains(fmt.Sprintf("%+v %v", event, event.Context.Parameters), "secret payload") {
+		t.Errorf("Slow calculation event should not contain text content")
+	}
+
+	// 依模型計算同樣記錄慢速計算
+	calculator.loadEncoding = func(string) (*tiktoken.Tiktoken, error) { return newTestTiktokenEncoder(t), nil }
+	if _, err := calculator.CalculateTokensForModel(text, "gpt-4"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	select {
+	case event = <-listener.events:
+	case <-time.After(time.Second):
+		t.Fatal("Expected a slow calculation event for the model count")
+	}
+	if event.Context.Parameters["method"] != MethodTiktoken {
+		t.Errorf("Expected tiktoken slow calculation event, got %v", event.Context.Parameters)
+	}
+}
+
+func TestTokenCalculatorImpl_TokenDelta(t *testing.T) {
+	calculator := NewTokenCalculator(100).(*TokenCalculatorImpl)
+
+	before := "Please carefully and thoroughly summarize the following document for me"
+	after := "Summarize this document"
+
+	delta, err := calculator.TokenDelta(before, after, "estimation")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	beforeTokens, _ := calculator.CalculateTokens(before, "estimation")
+	afterTokens, _ := calculator.CalculateTokens(after, "estimation")
+	if delta != afterTokens-beforeTokens || delta >= 0 {
+		t.Errorf("Expected negative delta %d, got %d", afterTokens-beforeTokens, delta)
+	}
+
+	// 兩段文本都應已快取
+	if _, found := calculator.getCachedTokens(methodCacheKey("estimation", before)); !found {
+		t.Errorf("Expected before text to be cached")
+	}
+	if _, found := calculator.getCachedTokens(methodCacheKey("estimation", after)); !found {
+		t.Errorf("Expected after text to be cached")
+	}
+
+	// 相同文本差異為 0
+	if delta, err := calculator.TokenDelta(after, after, "estimation"); err != nil || delta != 0 {
+		t.Errorf("Expected zero delta for identical text, got %d (%v)", delta, err)
+	}
+}
@@ -1,14 +1,19 @@
 package config
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
 	"reflect"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
+	"token-monitor/internal/errors"
 	"token-monitor/internal/types"
 )
 
@@ -19,8 +24,33 @@ type ConfigManager struct {
 	watchers     []ConfigWatcher
 	autoSave     bool
 	lastModified time.Time
+
+	// mutex 保護 config 指標、watchers 與載入狀態。config 採寫入時複製：已發布的配置不再修改，
+	// 變更時複製、修改後再替換指標，對外僅提供深拷貝快照
+	mutex sync.RWMutex
+
+	// loadBreaker 保護配置檔案載入，避免錯誤配置造成重複載入風暴
+	loadBreaker   errors.CircuitBreaker
+	lastLoadError error
+	lastLoadTime  time.Time
 }
 
+// ConfigStatus 配置管理器狀態
+type ConfigStatus struct {
+	ConfigPath    string              `json:"config_path"`
+	BreakerState  errors.CircuitState `json:"breaker_state"`
+	LastModified  time.Time           `json:"last_modified"`
+	LastLoadTime  time.Time           `json:"last_load_time"`
+	LastLoadError string              `json:"last_load_error,omitempty"`
+	UsingFallback bool                `json:"using_fallback"` // 最近一次載入失敗，仍使用上次成功的配置
+}
+
+// 配置載入斷路器預設參數
+const (
+	defaultLoadFailureThreshold = 3
+	defaultLoadRecoveryTimeout  = 30 * time.Second
+)
+
 // Config 主配置結構
 type Config struct {
 	Version     string           `json:"version"`
@@ -121,6 +151,10 @@ func NewConfigManager(configPath string) *ConfigManager {
 		config:     getDefaultConfig(),
 		watchers:   make([]ConfigWatcher, 0),
 		autoSave:   true,
+		loadBreaker: errors.NewSimpleCircuitBreaker(errors.CircuitBreakerConfig{
+			FailureThreshold: defaultLoadFailureThreshold,
+			RecoveryTimeout:  defaultLoadRecoveryTimeout,
+		}),
 	}
 }
 
@@ -212,8 +246,73 @@ func getDefaultConfig() *Config {
 	}
 }
 
-// LoadConfig 載入配置
+// LoadConfig 載入配置，連續失敗時斷路器開啟並暫停載入，期間保留上次成功的配置
 func (cm *ConfigManager) LoadConfig() error {
+	return cm.recordLoad(cm.loadConfigFile)
+}
+
+// LoadConfigStrict 載入配置，合併預設值前先以內嵌的 JSON Schema 驗證，拒絕未知欄位與型別不符。
+// 驗證失敗時回傳 SchemaErrors（列出各欄位問題）並保留目前配置；LoadConfig 維持寬鬆解析。
+func (cm *ConfigManager) LoadConfigStrict() error {
+	return cm.recordLoad(func() error {
+		return cm.readConfigFile(validateConfigSchema)
+	})
+}
+
+// recordLoad 透過斷路器執行載入，並記錄載入時間與結果
+func (cm *ConfigManager) recordLoad(load func() error) error {
+	loadTime := time.Now()
+	err := cm.loadBreaker.Call(context.Background(), load)
+
+	cm.mutex.Lock()
+	cm.lastLoadTime = loadTime
+	cm.lastLoadError = err
+	cm.mutex.Unlock()
+
+	return err
+}
+
+// ReloadConfig 重新載入配置檔案，成功時通知監聽器
+func (cm *ConfigManager) ReloadConfig() error {
+	oldConfig := cm.configSnapshot()
+
+	if err := cm.LoadConfig(); err != nil {
+		return fmt.Errorf("重新載入配置失敗: %w", err)
+	}
+
+	// 通知監聽器
+	if err := cm.notifyWatchers(&oldConfig, cm.GetConfig()); err != nil {
+		return fmt.Errorf("配置重新載入通知失敗: %w", err)
+	}
+
+	return nil
+}
+
+// Status 取得配置管理器狀態（含載入斷路器狀態）
+func (cm *ConfigManager) Status() ConfigStatus {
+	cm.mutex.RLock()
+	defer cm.mutex.RUnlock()
+
+	status := ConfigStatus{
+		ConfigPath:    cm.configPath,
+		BreakerState:  cm.loadBreaker.GetState(),
+		LastModified:  cm.lastModified,
+		LastLoadTime:  cm.lastLoadTime,
+		UsingFallback: cm.lastLoadError != nil,
+	}
+	if cm.lastLoadError != nil {
+		status.LastLoadError = cm.lastLoadError.Error()
+	}
+	return status
+}
+
+// loadConfigFile 讀取並解析配置檔案，僅在成功時替換目前配置
+func (cm *ConfigManager) loadConfigFile() error {
+	return cm.readConfigFile(nil)
+}
+
+// readConfigFile 讀取配置檔案，validate 不為 nil 時於解析前驗證原始內容
+func (cm *ConfigManager) readConfigFile(validate func([]byte) error) error {
 	// 檢查配置檔案是否存在
 	if _, err := os.Stat(cm.configPath); os.IsNotExist(err) {
 		// 建立預設配置檔案
@@ -226,6 +325,12 @@ func (cm *ConfigManager) LoadConfig() error {
 		return fmt.Errorf("讀取配置檔案失敗: %w", err)
 	}
 
+	if validate != nil {
+		if err := validate(data); err != nil {
+			return err
+		}
+	}
+
 	// 解析 JSON
 	var config Config
 	if err := json.Unmarshal(data, &config); err != nil {
@@ -233,12 +338,16 @@ func (cm *ConfigManager) LoadConfig() error {
 	}
 
 	// 合併預設配置（處理新增的配置項）
-	cm.config = cm.mergeWithDefaults(&config)
+	merged := cm.mergeWithDefaults(&config)
+	info, statErr := os.Stat(cm.configPath)
 
+	cm.mutex.Lock()
+	cm.config = merged
 	// 更新最後修改時間
-	if info, err := os.Stat(cm.configPath); err == nil {
+	if statErr == nil {
 		cm.lastModified = info.ModTime()
 	}
+	cm.mutex.Unlock()
 
 	return nil
 }
@@ -251,11 +360,13 @@ func (cm *ConfigManager) SaveConfig() error {
 		return fmt.Errorf("建立配置目錄失敗: %w", err)
 	}
 
-	// 更新最後修改時間
-	cm.config.LastUpdated = time.Now()
-
-	// 序列化為 JSON
-	data, err := json.MarshalIndent(cm.config, "", "  ")
+	// 更新最後修改時間並序列化為 JSON
+	cm.mutex.Lock()
+	updated := cloneConfig(cm.config)
+	updated.LastUpdated = time.Now()
+	cm.config = updated
+	data, err := json.MarshalIndent(updated, "", "  ")
+	cm.mutex.Unlock()
 	if err != nil {
 		return fmt.Errorf("序列化配置失敗: %w", err)
 	}
@@ -268,25 +379,40 @@ func (cm *ConfigManager) SaveConfig() error {
 	return nil
 }
 
-// GetConfig 獲取配置
+// GetConfig 獲取配置快照，修改回傳值不會影響目前配置
 func (cm *ConfigManager) GetConfig() *Config {
-	return cm.config
+	cm.mutex.RLock()
+	defer cm.mutex.RUnlock()
+
+	return cloneConfig(cm.config)
 }
 
-// UpdateConfig 更新配置
+// configSnapshot 在讀取鎖保護下複製目前配置
+func (cm *ConfigManager) configSnapshot() Config {
+	cm.mutex.RLock()
+	defer cm.mutex.RUnlock()
+
+	return *cloneConfig(cm.config)
+}
+
+// UpdateConfig 更新配置。updateFunc 於持有鎖時修改配置副本，成功後才替換目前配置；
+// updateFunc 不可再呼叫 ConfigManager 的方法。
 func (cm *ConfigManager) UpdateConfig(updateFunc func(*Config) error) error {
-	oldConfig := *cm.config // 複製舊配置
+	cm.mutex.Lock()
+	oldConfig := cm.config
+	newConfig := cloneConfig(oldConfig)
 
 	// 執行更新函數
-	if err := updateFunc(cm.config); err != nil {
+	if err := updateFunc(newConfig); err != nil {
+		cm.mutex.Unlock()
 		return fmt.Errorf("更新配置失敗: %w", err)
 	}
+	cm.config = newConfig
+	cm.mutex.Unlock()
 
 	// 通知監聽器
-	for _, watcher := range cm.watchers {
-		if err := watcher.OnConfigChanged(&oldConfig, cm.config); err != nil {
-			return fmt.Errorf("配置變更通知失敗: %w", err)
-		}
+	if err := cm.notifyWatchers(oldConfig, newConfig); err != nil {
+		return fmt.Errorf("配置變更通知失敗: %w", err)
 	}
 
 	// 自動儲存
@@ -299,11 +425,17 @@ func (cm *ConfigManager) UpdateConfig(updateFunc func(*Config) error) error {
 
 // AddWatcher 添加配置監聽器
 func (cm *ConfigManager) AddWatcher(watcher ConfigWatcher) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
 	cm.watchers = append(cm.watchers, watcher)
 }
 
 // RemoveWatcher 移除配置監聽器
 func (cm *ConfigManager) RemoveWatcher(watcher ConfigWatcher) {
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+
 	for i, w := range cm.watchers {
 		if w == watcher {
 			cm.watchers = append(cm.watchers[:i], cm.watchers[i+1:]...)
@@ -312,6 +444,22 @@ func (cm *ConfigManager) RemoveWatcher(watcher ConfigWatcher) {
 	}
 }
 
+// notifyWatchers 以監聽器快照通知配置變更，通知期間不持有鎖，監聽器可安全地新增或移除監聽器。
+// 每個監聽器收到各自的配置副本，修改不會影響目前配置或其他監聽器。
+func (cm *ConfigManager) notifyWatchers(oldConfig, newConfig *Config) error {
+	cm.mutex.RLock()
+	watchers := make([]ConfigWatcher, len(cm.watchers))
+	copy(watchers, cm.watchers)
+	cm.mutex.RUnlock()
+
+	for _, watcher := range watchers {
+		if err := watcher.OnConfigChanged(cloneConfig(oldConfig), cloneConfig(newConfig)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // SetAutoSave 設定自動儲存
 func (cm *ConfigManager) SetAutoSave(enabled bool) {
 	cm.autoSave = enabled
@@ -319,7 +467,12 @@ func (cm *ConfigManager) SetAutoSave(enabled bool) {
 
 // ValidateConfig 驗證配置
 func (cm *ConfigManager) ValidateConfig() error {
-	config := cm.config
+	config := cm.configSnapshot()
+	return validateConfig(&config)
+}
+
+// validateConfig 驗證指定配置
+func validateConfig(config *Config) error {
 
 	// 驗證一般配置
 	if config.General.Language == "" {
@@ -354,14 +507,15 @@ func (cm *ConfigManager) ValidateConfig() error {
 
 // ResetToDefaults 重置為預設配置
 func (cm *ConfigManager) ResetToDefaults() error {
-	oldConfig := *cm.config
-	cm.config = getDefaultConfig()
+	newConfig := getDefaultConfig()
+	cm.mutex.Lock()
+	oldConfig := cm.config
+	cm.config = newConfig
+	cm.mutex.Unlock()
 
 	// 通知監聽器
-	for _, watcher := range cm.watchers {
-		if err := watcher.OnConfigChanged(&oldConfig, cm.config); err != nil {
-			return fmt.Errorf("配置重置通知失敗: %w", err)
-		}
+	if err := cm.notifyWatchers(oldConfig, newConfig); err != nil {
+		return fmt.Errorf("配置重置通知失敗: %w", err)
 	}
 
 	// 自動儲存
@@ -372,6 +526,25 @@ func (cm *ConfigManager) ResetToDefaults() error {
 	return nil
 }
 
+// cloneConfig 深拷貝配置，包含 map 與切片
+func cloneConfig(config *Config) *Config {
+	clone := *config
+	clone.Calculator.EstimationRules = maps.Clone(config.Calculator.EstimationRules)
+	if config.Analyzer.CustomPatterns != nil {
+		clone.Analyzer.CustomPatterns = make(map[string][]string, len(config.Analyzer.CustomPatterns))
+		for name, patterns := range config.Analyzer.CustomPatterns {
+			clone.Analyzer.CustomPatterns[name] = slices.Clone(patterns)
+		}
+	}
+	clone.Analyzer.ActivityWeights = maps.Clone(config.Analyzer.ActivityWeights)
+	clone.Cost.PricingModels = maps.Clone(config.Cost.PricingModels)
+	clone.Reporter.EnabledFormats = slices.Clone(config.Reporter.EnabledFormats)
+	clone.Reporter.TemplateSettings = maps.Clone(config.Reporter.TemplateSettings)
+	clone.CLI.DefaultCommands = slices.Clone(config.CLI.DefaultCommands)
+	clone.CLI.Aliases = maps.Clone(config.CLI.Aliases)
+	return &clone
+}
+
 // mergeWithDefaults 與預設配置合併
 func (cm *ConfigManager) mergeWithDefaults(config *Config) *Config {
 	defaultConfig := getDefaultConfig()
@@ -388,6 +561,11 @@ func (cm *ConfigManager) mergeStructs(defaultVal, configVal reflect.Value) {
 		defaultField := defaultVal.Field(i)
 		configField := configVal.Field(i)
 
+		// 略過未匯出欄位（例如 time.Time 內部欄位）
+		if !configField.CanSet() {
+			continue
+		}
+
 		if configField.IsZero() {
 			configField.Set(defaultField)
 		} else if defaultField.Kind() == reflect.Struct && configField.Kind() == reflect.Struct {
@@ -398,8 +576,11 @@ func (cm *ConfigManager) mergeStructs(defaultVal, configVal reflect.Value) {
 
 // GetConfigValue 獲取配置值
 func (cm *ConfigManager) GetConfigValue(path string) (interface{}, error) {
+	cm.mutex.RLock()
+	defer cm.mutex.RUnlock()
+
 	parts := strings.Split(path, ".")
-	val := reflect.ValueOf(cm.config).Elem()
+	val := reflect.ValueOf(cloneConfig(cm.config)).Elem()
 
 	for _, part := range parts {
 		val = val.FieldByName(strings.Title(part))
@@ -411,6 +592,23 @@ func (cm *ConfigManager) GetConfigValue(path string) (interface{}, error) {
 	return val.Interface(), nil
 }
 
+// GetConfigValueAs 獲取配置值並轉換為指定型別，型別不符時回傳錯誤
+func GetConfigValueAs[T any](cm *ConfigManager, path string) (T, error) {
+	var zero T
+
+	value, err := cm.GetConfigValue(path)
+	if err != nil {
+		return zero, err
+	}
+
+	typed, ok := value.(T)
+	if !ok {
+		return zero, fmt.Errorf("配置值型別不符: %s 為 %T，預期 %s", path, value, reflect.TypeOf((*T)(nil)).Elem())
+	}
+
+	return typed, nil
+}
+
 // SetConfigValue 設定配置值
 func (cm *ConfigManager) SetConfigValue(path string, value interface{}) error {
 	return cm.UpdateConfig(func(config *Config) error {
@@ -443,7 +641,9 @@ func (cm *ConfigManager) SetConfigValue(path string, value interface{}) error {
 
 // ExportConfig 匯出配置
 func (cm *ConfigManager) ExportConfig(outputPath string) error {
+	cm.mutex.RLock()
 	data, err := json.MarshalIndent(cm.config, "", "  ")
+	cm.mutex.RUnlock()
 	if err != nil {
 		return fmt.Errorf("序列化配置失敗: %w", err)
 	}
@@ -463,14 +663,15 @@ func (cm *ConfigManager) ImportConfig(inputPath string) error {
 		return fmt.Errorf("解析配置檔案失敗: %w", err)
 	}
 
-	oldConfig := *cm.config
-	cm.config = cm.mergeWithDefaults(&config)
+	newConfig := cm.mergeWithDefaults(&config)
+	cm.mutex.Lock()
+	oldConfig := cm.config
+	cm.config = newConfig
+	cm.mutex.Unlock()
 
 	// 通知監聽器
-	for _, watcher := range cm.watchers {
-		if err := watcher.OnConfigChanged(&oldConfig, cm.config); err != nil {
-			return fmt.Errorf("配置匯入通知失敗: %w", err)
-		}
+	if err := cm.notifyWatchers(oldConfig, newConfig); err != nil {
+		return fmt.Errorf("配置匯入通知失敗: %w", err)
 	}
 
 	// 自動儲存
@@ -480,3 +681,17 @@ func (cm *ConfigManager) ImportConfig(inputPath string) error {
 
 	return nil
 }
+
+// PatchConfig 將部分 JSON 配置套用於目前配置（而非預設配置），僅覆寫提供的欄位。
+// 修補於 UpdateConfig 的同一臨界區內完成，驗證失敗時不套用任何變更。
+func (cm *ConfigManager) PatchConfig(partial json.RawMessage) error {
+	return cm.UpdateConfig(func(config *Config) error {
+		if err := json.Unmarshal(partial, config); err != nil {
+			return fmt.Errorf("解析配置修補失敗: %w", err)
+		}
+		if err := validateConfig(config); err != nil {
+			return fmt.Errorf("配置修補驗證失敗: %w", err)
+		}
+		return nil
+	})
+}
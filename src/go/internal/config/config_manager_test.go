@@ -0,0 +1,397 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"token-monitor/internal/errors"
+)
+
+// TestLoadConfigCircuitBreaker 測試連續載入失敗時斷路器開啟並保留上次成功的配置
+func TestLoadConfigCircuitBreaker(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	cm := NewConfigManager(configPath)
+	cm.config.Cost.DefaultModel = "claude-haiku-3.5"
+	if err := cm.SaveConfig(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := cm.LoadConfig(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := os.WriteFile(configPath, []byte("{invalid"), 0644); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for i := 0; i < defaultLoadFailureThreshold; i++ {
+		if err := cm.ReloadConfig(); err == nil {
+			t.Fatalf("Expected error on reload %d", i)
+		}
+	}
+
+	status := cm.Status()
+	if status.BreakerState != errors.CircuitOpen {
+		t.Errorf("Expected breaker to be open, got %s", status.BreakerState)
+	}
+	if !status.UsingFallback || status.LastLoadError == "" {
+		t.Errorf("Expected status to report fallback with error, got %+v", status)
+	}
+
+	// 斷路器開啟後應直接拒絕載入
+	err := cm.LoadConfig()
+	if !errors.IsCode(err, errors.ErrCodeSystemResource) {
+		t.Errorf("Expected circuit breaker error, got %v", err)
+	}
+
+	// 保留上次成功的配置
+	if cm.GetConfig().Cost.DefaultModel != "claude-haiku-3.5" {
+		t.Errorf("Expected last-good config to be kept, got %s", cm.GetConfig().Cost.DefaultModel)
+	}
+}
+
+// TestConfigStatus 測試正常載入後的狀態
+func TestConfigStatus(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	cm := NewConfigManager(configPath)
+
+	if err := cm.LoadConfig(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	status := cm.Status()
+	if status.BreakerState != errors.CircuitClosed {
+		t.Errorf("Expected breaker to be closed, got %s", status.BreakerState)
+	}
+	if status.UsingFallback || status.LastLoadError != "" {
+		t.Errorf("Expected no load error, got %+v", status)
+	}
+	if status.ConfigPath != configPath {
+		t.Errorf("Expected config path %s, got %s", configPath, status.ConfigPath)
+	}
+}
+
+// TestConfigStatusConcurrent 測試載入與讀取狀態並行時不發生資料競爭（以 -race 執行）
+func TestConfigStatusConcurrent(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	cm := NewConfigManager(configPath)
+	if err := cm.LoadConfig(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_ = cm.LoadConfig()
+			_ = cm.LoadConfigStrict()
+		}()
+		go func() {
+			defer wg.Done()
+			_ = cm.Status()
+		}()
+	}
+	wg.Wait()
+
+	if status := cm.Status(); status.LastLoadTime.IsZero() || status.LastModified.IsZero() {
+		t.Errorf("Expected load and modification times to be recorded, got %+v", status)
+	}
+}
+
+// countingWatcher 計算收到通知次數的監聽器
+type countingWatcher struct {
+	mutex sync.Mutex
+	calls int
+}
+
+func (w *countingWatcher) OnConfigChanged(oldConfig, newConfig *Config) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.calls++
+	return nil
+}
+
+// watcherAddingWatcher 在通知期間新增監聽器，驗證通知時未持有鎖
+type watcherAddingWatcher struct {
+	cm *ConfigManager
+}
+
+func (w *watcherAddingWatcher) OnConfigChanged(oldConfig, newConfig *Config) error {
+	added := &countingWatcher{}
+	w.cm.AddWatcher(added)
+	w.cm.RemoveWatcher(added)
+	return nil
+}
+
+// fieldReadingWatcher 在通知期間讀取並修改收到的配置，驗證監聽器取得的是快照
+type fieldReadingWatcher struct{}
+
+func (w *fieldReadingWatcher) OnConfigChanged(oldConfig, newConfig *Config) error {
+	_ = oldConfig.General.MaxConcurrency + newConfig.General.MaxConcurrency
+	newConfig.Cost.PricingModels["watcher-model"] = PricingModel{}
+	newConfig.General.Language = ""
+	return nil
+}
+
+// TestConfigWatchersConcurrent 測試並行註冊監聽器與更新配置（請搭配 -race 執行）
+func TestConfigWatchersConcurrent(t *testing.T) {
+	cm := NewConfigManager(filepath.Join(t.TempDir(), "config.json"))
+	cm.SetAutoSave(false)
+	cm.AddWatcher(&watcherAddingWatcher{cm: cm})
+	cm.AddWatcher(&fieldReadingWatcher{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				watcher := &countingWatcher{}
+				cm.AddWatcher(watcher)
+				cm.RemoveWatcher(watcher)
+			}
+		}()
+		go func(n int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				err := cm.UpdateConfig(func(config *Config) error {
+					config.General.MaxConcurrency = n + 1
+					return nil
+				})
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				config := cm.GetConfig()
+				if config.General.MaxConcurrency <= 0 || config.General.Language == "" {
+					t.Errorf("Unexpected config snapshot: %+v", config.General)
+				}
+				_ = len(config.Cost.PricingModels)
+				if _, err := cm.GetConfigValue("general.language"); err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	persistent := &countingWatcher{}
+	cm.AddWatcher(persistent)
+	if err := cm.ResetToDefaults(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if persistent.calls != 1 {
+		t.Errorf("Expected watcher to be notified once, got %d", persistent.calls)
+	}
+	if len(cm.watchers) != 3 {
+		t.Errorf("Expected 3 registered watchers, got %d", len(cm.watchers))
+	}
+
+	// 監聽器與 GetConfig 呼叫者修改快照不影響目前配置
+	snapshot := cm.GetConfig()
+	snapshot.General.Language = "modified"
+	snapshot.Cost.PricingModels["snapshot-model"] = PricingModel{}
+	current := cm.GetConfig()
+	if current.General.Language != "zh-TW" {
+		t.Errorf("Expected language to be unaffected by snapshot changes, got %s", current.General.Language)
+	}
+	if _, ok := current.Cost.PricingModels["snapshot-model"]; ok {
+		t.Error("Expected pricing models to be unaffected by snapshot changes")
+	}
+	if _, ok := current.Cost.PricingModels["watcher-model"]; ok {
+		t.Error("Expected pricing models to be unaffected by watcher changes")
+	}
+}
+
+// TestGetConfigValueAs 測試以泛型取得型別化的配置值
+func TestGetConfigValueAs(t *testing.T) {
+	cm := NewConfigManager(filepath.Join(t.TempDir(), "config.json"))
+
+	language, err := GetConfigValueAs[string](cm, "general.language")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if language != "zh-TW" {
+		t.Errorf("Expected zh-TW, got %s", language)
+	}
+
+	concurrency, err := GetConfigValueAs[int](cm, "general.maxConcurrency")
+	if err != nil || concurrency != 4 {
+		t.Errorf("Expected 4, got %d (%v)", concurrency, err)
+	}
+
+	if _, err := GetConfigValueAs[int](cm, "general.language"); err == nil {
+		t.Error("Expected type mismatch error")
+	} else if !strings.Contains(err.Error(), "int") {
+		t.Errorf("Expected error to mention expected type, got %v", err)
+	}
+
+	if _, err := GetConfigValueAs[string](cm, "general.missing"); err == nil {
+		t.Error("Expected error for missing path")
+	}
+}
+
+// TestPatchConfig 測試部分配置修補
+func TestPatchConfig(t *testing.T) {
+	cm := NewConfigManager(filepath.Join(t.TempDir(), "config.json"))
+	cm.SetAutoSave(false)
+	if err := cm.SetConfigValue("general.language", "en-US"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	watcher := &countingWatcher{}
+	cm.AddWatcher(watcher)
+
+	err := cm.PatchConfig(json.RawMessage(`{"general": {"max_concurrency": 8}, "cost": {"default_model": "claude-opus-4.0"}}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	config := cm.GetConfig()
+	if config.General.MaxConcurrency != 8 || config.Cost.DefaultModel != "claude-opus-4.0" {
+		t.Errorf("Expected patched fields to be applied, got %+v / %s", config.General, config.Cost.DefaultModel)
+	}
+	// 未提供的欄位維持目前配置，而非預設配置
+	if config.General.Language != "en-US" {
+		t.Errorf("Expected unpatched field to keep current value, got %s", config.General.Language)
+	}
+	if watcher.calls != 1 {
+		t.Errorf("Expected watcher to be notified once, got %d", watcher.calls)
+	}
+
+	// 驗證失敗時不套用
+	if err := cm.PatchConfig(json.RawMessage(`{"general": {"max_concurrency": 0}}`)); err == nil {
+		t.Error("Expected validation error")
+	}
+	if cm.GetConfig().General.MaxConcurrency != 8 {
+		t.Errorf("Expected config to be unchanged after failed patch, got %d", cm.GetConfig().General.MaxConcurrency)
+	}
+
+	if err := cm.PatchConfig(json.RawMessage(`{invalid`)); err == nil {
+		t.Error("Expected parse error")
+	}
+	if watcher.calls != 1 {
+		t.Errorf("Expected no notification for failed patches, got %d", watcher.calls)
+	}
+
+	// 驗證只套用於修補，UpdateConfig 維持原本不驗證的行為
+	if err := cm.UpdateConfig(func(config *Config) error {
+		config.General.MaxConcurrency = 0
+		return nil
+	}); err != nil {
+		t.Errorf("Expected UpdateConfig to skip validation, got %v", err)
+	}
+	if cm.GetConfig().General.MaxConcurrency != 0 {
+		t.Errorf("Expected UpdateConfig change to be applied, got %d", cm.GetConfig().General.MaxConcurrency)
+	}
+}
+
+// TestPatchConfigConcurrent 測試並行修補不同欄位時不會遺失更新（請搭配 -race 執行）
+func TestPatchConfigConcurrent(t *testing.T) {
+	cm := NewConfigManager(filepath.Join(t.TempDir(), "config.json"))
+	cm.SetAutoSave(false)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			patch := fmt.Sprintf(`{"cli": {"aliases": {"alias-%d": "command-%d"}}}`, n, n)
+			if err := cm.PatchConfig(json.RawMessage(patch)); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	aliases := cm.GetConfig().CLI.Aliases
+	for i := 0; i < 20; i++ {
+		if aliases[fmt.Sprintf("alias-%d", i)] != fmt.Sprintf("command-%d", i) {
+			t.Errorf("Expected alias-%d to be kept after concurrent patches", i)
+		}
+	}
+}
+
+// TestConfigSchemaMatchesDefaults 測試預設配置符合內嵌的 schema
+func TestConfigSchemaMatchesDefaults(t *testing.T) {
+	data, err := json.Marshal(getDefaultConfig())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := validateConfigSchema(data); err != nil {
+		t.Errorf("Expected default config to satisfy schema, got %v", err)
+	}
+}
+
+// TestLoadConfigStrict 測試嚴格載入拒絕未知欄位與型別不符
+func TestLoadConfigStrict(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	cm := NewConfigManager(configPath)
+
+	valid := `{"general": {"language": "en-US", "max_concurrency": 2}, "cost": {"pricing_models": {"m": {"input_price": 1}}}}`
+	if err := os.WriteFile(configPath, []byte(valid), 0644); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := cm.LoadConfigStrict(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cm.GetConfig().General.Language != "en-US" || cm.GetConfig().General.MaxConcurrency != 2 {
+		t.Errorf("Expected strict load to apply config, got %+v", cm.GetConfig().General)
+	}
+
+	invalid := `{
+		"general": {"language": "zh-TW", "max_concurency": 4, "max_concurrency": "8"},
+		"cost": {"pricing_models": {"m": {"input_price": "cheap"}}},
+		"reporter": {"enabled_formats": ["json", 1]},
+		"unknown_section": {}
+	}`
+	if err := os.WriteFile(configPath, []byte(invalid), 0644); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// 一次列出所有欄位錯誤
+	err := cm.LoadConfigStrict()
+	schemaErrs, ok := err.(SchemaErrors)
+	if !ok {
+		t.Fatalf("Expected SchemaErrors, got %T: %v", err, err)
+	}
+
+	expected := []string{
+		"cost.pricing_models.m.input_price",
+		"general.max_concurency",
+		"general.max_concurrency",
+		"reporter.enabled_formats[1]",
+		"unknown_section",
+	}
+	if len(schemaErrs) != len(expected) {
+		t.Fatalf("Expected %d errors, got %v", len(expected), schemaErrs)
+	}
+	for i, path := range expected {
+		if schemaErrs[i].Path != path {
+			t.Errorf("Expected error %d at %s, got %s", i, path, schemaErrs[i].Path)
+		}
+	}
+	if !strings.Contains(err.Error(), "general.max_concurrency: 型別不符") {
+		t.Errorf("Expected field-level message, got %v", err)
+	}
+
+	// 驗證失敗時保留目前配置
+	if cm.GetConfig().General.Language != "en-US" {
+		t.Errorf("Expected current config to be kept, got %s", cm.GetConfig().General.Language)
+	}
+
+	// 未知欄位在寬鬆載入時會被忽略
+	if err := os.WriteFile(configPath, []byte(`{"general": {"language": "ja-JP"}, "unknown_section": {}}`), 0644); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := cm.LoadConfig(); err != nil {
+		t.Errorf("Expected lenient load to ignore unknown fields, got %v", err)
+	}
+	if err := cm.LoadConfigStrict(); err == nil {
+		t.Error("Expected strict load to reject unknown fields")
+	}
+}
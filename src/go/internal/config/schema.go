@@ -0,0 +1,216 @@
+package config
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// configSchemaJSON 配置檔案的 JSON Schema（支援 type、properties、additionalProperties、items）
+//
+//go:embed config_schema.json
+var configSchemaJSON []byte
+
+// jsonSchema JSON Schema 的子集
+type jsonSchema struct {
+	Type                 schemaTypes            `json:"type"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	AdditionalProperties *additionalProperties  `json:"additionalProperties"`
+	Items                *jsonSchema            `json:"items"`
+}
+
+// schemaTypes 允許的型別，可為單一字串或字串陣列
+type schemaTypes []string
+
+// UnmarshalJSON 解析單一型別或型別陣列
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = schemaTypes{single}
+		return nil
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return fmt.Errorf("invalid schema type: %s", data)
+	}
+	*t = multiple
+	return nil
+}
+
+// additionalProperties 額外屬性設定：false 表示拒絕，物件表示額外屬性的 schema
+type additionalProperties struct {
+	Allowed bool
+	Schema  *jsonSchema
+}
+
+// UnmarshalJSON 解析布林值或 schema 物件
+func (a *additionalProperties) UnmarshalJSON(data []byte) error {
+	var allowed bool
+	if err := json.Unmarshal(data, &allowed); err == nil {
+		a.Allowed = allowed
+		return nil
+	}
+
+	a.Allowed = true
+	return json.Unmarshal(data, &a.Schema)
+}
+
+// SchemaError 單一欄位的驗證錯誤
+type SchemaError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// Error 實作 error 介面
+func (e SchemaError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// SchemaErrors 配置驗證的所有欄位錯誤（依路徑排序）
+type SchemaErrors []SchemaError
+
+// Error 實作 error 介面
+func (e SchemaErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, schemaErr := range e {
+		messages[i] = schemaErr.Error()
+	}
+	return fmt.Sprintf("配置驗證失敗（%d 個錯誤）: %s", len(e), strings.Join(messages, "; "))
+}
+
+// loadConfigSchema 解析內嵌的配置 schema
+func loadConfigSchema() (*jsonSchema, error) {
+	var schema jsonSchema
+	if err := json.Unmarshal(configSchemaJSON, &schema); err != nil {
+		return nil, fmt.Errorf("解析配置 schema 失敗: %w", err)
+	}
+	return &schema, nil
+}
+
+// validateConfigSchema 以內嵌的 schema 驗證配置 JSON，回傳 SchemaErrors 或解析錯誤
+func validateConfigSchema(data []byte) error {
+	schema, err := loadConfigSchema()
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return fmt.Errorf("解析配置檔案失敗: %w", err)
+	}
+
+	var problems SchemaErrors
+	schema.validate("", document, &problems)
+	if len(problems) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].Path < problems[j].Path })
+	return problems
+}
+
+// validate 遞迴驗證值並累計錯誤
+func (s *jsonSchema) validate(path string, value interface{}, problems *SchemaErrors) {
+	actual := jsonTypeOf(value)
+	if len(s.Type) > 0 && !s.allowsType(actual, value) {
+		*problems = append(*problems, SchemaError{
+			Path:    path,
+			Message: fmt.Sprintf("型別不符，預期 %s，實際為 %s", strings.Join(s.Type, " 或 "), actual),
+		})
+		return
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			childPath := joinSchemaPath(path, key)
+			if property, exists := s.Properties[key]; exists {
+				property.validate(childPath, v[key], problems)
+				continue
+			}
+			if s.AdditionalProperties == nil {
+				continue
+			}
+			if !s.AdditionalProperties.Allowed {
+				*problems = append(*problems, SchemaError{Path: childPath, Message: "未知的配置欄位"})
+				continue
+			}
+			if s.AdditionalProperties.Schema != nil {
+				s.AdditionalProperties.Schema.validate(childPath, v[key], problems)
+			}
+		}
+	case []interface{}:
+		if s.Items == nil {
+			return
+		}
+		for i, item := range v {
+			s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, problems)
+		}
+	}
+}
+
+// allowsType 檢查型別是否允許（integer 接受整數值的 number）
+func (s *jsonSchema) allowsType(actual string, value interface{}) bool {
+	for _, allowed := range s.Type {
+		if allowed == actual {
+			return true
+		}
+		if allowed == "integer" && actual == "number" && isIntegerNumber(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonTypeOf 取得解碼值的 JSON 型別名稱
+func jsonTypeOf(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// isIntegerNumber 檢查 JSON 數值是否為整數
+func isIntegerNumber(value interface{}) bool {
+	number, ok := value.(json.Number)
+	if !ok {
+		return false
+	}
+	_, err := number.Int64()
+	return err == nil
+}
+
+// joinSchemaPath 組合欄位路徑
+func joinSchemaPath(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
@@ -0,0 +1,82 @@
+package cost
+
+import (
+	"fmt"
+	"sort"
+	"time"
+	"token-monitor/internal/types"
+)
+
+// FastestGrowingActivity 依時間區間（hourly、daily、weekly、monthly）彙總各活動類型的成本，
+// 回傳首末區間成長率（百分比，與 CostTrendAnalysis.GrowthRate 相同）最高的活動類型。
+// 活動類型須至少有兩個時間區間且首區間成本大於 0 才列入比較；成長率相同時取名稱排序較前者。
+func (cc *CostCalculatorImpl) FastestGrowingActivity(records []types.UsageRecord, timeRange string) (types.ActivityType, float64, error) {
+	cc.mutex.RLock()
+	defer cc.mutex.RUnlock()
+
+	if len(records) == 0 {
+		return "", 0, fmt.Errorf("no usage records provided")
+	}
+
+	// 活動類型 -> 時間區間 -> 成本
+	buckets := make(map[types.ActivityType]map[time.Time]float64)
+	for _, record := range records {
+		breakdown, err := cc.calculateRecordCostLocked(record)
+		if err != nil {
+			continue
+		}
+
+		activityType := record.Activity.Type
+		if buckets[activityType] == nil {
+			buckets[activityType] = make(map[time.Time]float64)
+		}
+		buckets[activityType][timeBucketKey(record.Timestamp, timeRange)] += breakdown.TotalCost
+	}
+
+	activityTypes := make([]types.ActivityType, 0, len(buckets))
+	for activityType := range buckets {
+		activityTypes = append(activityTypes, activityType)
+	}
+	sort.Slice(activityTypes, func(i, j int) bool { return activityTypes[i] < activityTypes[j] })
+
+	var fastest types.ActivityType
+	bestGrowth := 0.0
+	found := false
+	for _, activityType := range activityTypes {
+		growth, ok := bucketGrowthRate(buckets[activityType])
+		if !ok {
+			continue
+		}
+		if !found || growth > bestGrowth {
+			fastest, bestGrowth, found = activityType, growth, true
+		}
+	}
+
+	if !found {
+		return "", 0, fmt.Errorf("no activity type has at least two %s buckets with a non-zero starting cost", timeRange)
+	}
+	return fastest, bestGrowth, nil
+}
+
+// bucketGrowthRate 計算首末時間區間的成長率（百分比）；少於兩個區間或首區間成本為 0 時回傳 false
+func bucketGrowthRate(costs map[time.Time]float64) (float64, bool) {
+	if len(costs) < 2 {
+		return 0, false
+	}
+
+	var first, last time.Time
+	for bucket := range costs {
+		if first.IsZero() || bucket.Before(first) {
+			first = bucket
+		}
+		if last.IsZero() || bucket.After(last) {
+			last = bucket
+		}
+	}
+
+	firstCost := costs[first]
+	if firstCost <= 0 {
+		return 0, false
+	}
+	return (costs[last] - firstCost) / firstCost * 100, true
+}
@@ -0,0 +1,44 @@
+package cost
+
+import (
+	"testing"
+	"time"
+	"token-monitor/internal/types"
+)
+
+// TestFastestGrowingActivity 測試找出成本成長最快的活動類型
+func TestFastestGrowingActivity(t *testing.T) {
+	calculator := NewCostCalculator()
+	day := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	records := []types.UsageRecord{
+		// coding: 1000 -> 1500 tokens（成長 50%）
+		newTestRecord(day, types.ActivityCoding, 500, 500, "claude-sonnet-4.0"),
+		newTestRecord(day.AddDate(0, 0, 2), types.ActivityCoding, 750, 750, "claude-sonnet-4.0"),
+		// debugging: 1000 -> 3000 tokens（成長 200%）
+		newTestRecord(day, types.ActivityDebugging, 500, 500, "claude-sonnet-4.0"),
+		newTestRecord(day.AddDate(0, 0, 1), types.ActivityDebugging, 500, 500, "claude-sonnet-4.0"),
+		newTestRecord(day.AddDate(0, 0, 2), types.ActivityDebugging, 1500, 1500, "claude-sonnet-4.0"),
+		// chat 只有一個區間，不列入比較
+		newTestRecord(day.AddDate(0, 0, 2), types.ActivityChat, 100000, 100000, "claude-sonnet-4.0"),
+	}
+
+	activityType, growth, err := calculator.FastestGrowingActivity(records, "daily")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if activityType != types.ActivityDebugging {
+		t.Errorf("Expected debugging to grow fastest, got %s", activityType)
+	}
+	if growth < 199.999 || growth > 200.001 {
+		t.Errorf("Expected 200%% growth, got %.4f", growth)
+	}
+
+	// 所有記錄都在同一區間
+	if _, _, err := calculator.FastestGrowingActivity(records, "monthly"); err == nil {
+		t.Error("Expected error when fewer than two buckets exist")
+	}
+	if _, _, err := calculator.FastestGrowingActivity(nil, "daily"); err == nil {
+		t.Error("Expected error for empty records")
+	}
+}
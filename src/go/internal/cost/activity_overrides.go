@@ -0,0 +1,64 @@
+package cost
+
+import (
+	"fmt"
+	"token-monitor/internal/types"
+)
+
+// SetActivityModelOverride 設定活動類型使用的定價模型。CalculateDetailedCost 在 options.ActivityType
+// 符合時、依記錄計價的報告與分析在記錄活動類型符合時改用此模型計價；model 為空字串表示移除覆寫。
+func (cc *CostCalculatorImpl) SetActivityModelOverride(activityType types.ActivityType, model string) error {
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	if model == "" {
+		delete(cc.activityModelOverrides, activityType)
+		return nil
+	}
+
+	if _, err := cc.pricingEngine.GetPricingModel(model); err != nil {
+		return fmt.Errorf("invalid override model %s for activity %s: %w", model, activityType, err)
+	}
+
+	if cc.activityModelOverrides == nil {
+		cc.activityModelOverrides = make(map[types.ActivityType]string)
+	}
+	cc.activityModelOverrides[activityType] = model
+	return nil
+}
+
+// GetActivityModelOverrides 取得所有活動類型的定價模型覆寫
+func (cc *CostCalculatorImpl) GetActivityModelOverrides() map[types.ActivityType]string {
+	cc.mutex.RLock()
+	defer cc.mutex.RUnlock()
+
+	overrides := make(map[types.ActivityType]string, len(cc.activityModelOverrides))
+	for activityType, model := range cc.activityModelOverrides {
+		overrides[activityType] = model
+	}
+	return overrides
+}
+
+// resolveActivityModel 依 options.ActivityType 取得覆寫的定價模型，未設定時回傳原模型（呼叫者須持有讀取鎖）
+func (cc *CostCalculatorImpl) resolveActivityModel(model string, options *CostOptions) string {
+	if options == nil || options.ActivityType == "" {
+		return model
+	}
+	if override, exists := cc.activityModelOverrides[options.ActivityType]; exists {
+		return override
+	}
+	return model
+}
+
+// recordModel 取得記錄計價使用的定價模型，套用活動類型覆寫
+func (cc *CostCalculatorImpl) recordModel(record types.UsageRecord) string {
+	cc.mutex.RLock()
+	defer cc.mutex.RUnlock()
+
+	return cc.recordModelLocked(record)
+}
+
+// recordModelLocked 取得記錄計價使用的定價模型，套用活動類型覆寫（呼叫者須持有讀取鎖）
+func (cc *CostCalculatorImpl) recordModelLocked(record types.UsageRecord) string {
+	return cc.resolveActivityModel(record.Cost.PricingModel, &CostOptions{ActivityType: record.Activity.Type})
+}
@@ -0,0 +1,147 @@
+package cost
+
+import (
+	"math"
+	"testing"
+	"time"
+	"token-monitor/internal/types"
+)
+
+// TestActivityModelOverride 測試依活動類型覆寫定價模型
+func TestActivityModelOverride(t *testing.T) {
+	calculator := NewCostCalculator()
+
+	if err := calculator.SetActivityModelOverride(types.ActivityDocumentation, "claude-haiku-3.5"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	docs, err := calculator.CalculateDetailedCost(1000, 1000, "claude-sonnet-4.0", &CostOptions{ActivityType: types.ActivityDocumentation})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if docs.PricingModel != "claude-haiku-3.5" {
+		t.Errorf("Expected documentation to use claude-haiku-3.5, got %s", docs.PricingModel)
+	}
+
+	coding, err := calculator.CalculateDetailedCost(1000, 1000, "claude-sonnet-4.0", &CostOptions{ActivityType: types.ActivityCoding})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if coding.PricingModel != "claude-sonnet-4.0" || coding.TotalCost <= docs.TotalCost {
+		t.Errorf("Expected coding to keep claude-sonnet-4.0, got %s (%.6f)", coding.PricingModel, coding.TotalCost)
+	}
+
+	// 每日追蹤記錄在覆寫後的模型
+	byModel := calculator.GetDailyCostByModel(time.Now().Format("2006-01-02"))
+	if byModel["claude-haiku-3.5"] != docs.TotalCost {
+		t.Errorf("Expected daily tracking under claude-haiku-3.5, got %v", byModel)
+	}
+
+	if err := calculator.SetActivityModelOverride(types.ActivityChat, "unknown-model"); err == nil {
+		t.Error("Expected error for unknown override model")
+	}
+
+	if err := calculator.SetActivityModelOverride(types.ActivityDocumentation, ""); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(calculator.GetActivityModelOverrides()) != 0 {
+		t.Errorf("Expected override to be removed, got %v", calculator.GetActivityModelOverrides())
+	}
+	docs, _ = calculator.CalculateDetailedCost(1000, 1000, "claude-sonnet-4.0", &CostOptions{ActivityType: types.ActivityDocumentation})
+	if docs.PricingModel != "claude-sonnet-4.0" {
+		t.Errorf("Expected passed model after removing override, got %s", docs.PricingModel)
+	}
+}
+
+// TestActivityModelOverrideInReport 測試報告中一般記錄與快取記錄皆套用活動類型覆寫，並依覆寫後的模型分組
+func TestActivityModelOverrideInReport(t *testing.T) {
+	calculator := NewCostCalculator()
+	if err := calculator.SetActivityModelOverride(types.ActivityDocumentation, "claude-haiku-3.5"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	now := time.Now()
+	plain := newTestRecord(now, types.ActivityDocumentation, 1_000_000, 0, "claude-sonnet-4.0")
+	cached := newTestRecord(now, types.ActivityDocumentation, 1_000_000, 0, "claude-sonnet-4.0")
+	cached.Billing.CacheReadTokens = 1_000_000
+	coding := newTestRecord(now, types.ActivityCoding, 1_000_000, 0, "claude-sonnet-4.0")
+
+	report, err := calculator.GenerateCostReport([]types.UsageRecord{plain, cached, coding}, &types.ReportOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	haiku, err := calculator.CalculateCost(1_000_000, 0, "claude-haiku-3.5")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cachedCost, err := calculator.CalculateDetailedCost(1_000_000, 0, "claude-haiku-3.5", &CostOptions{Mode: CacheBilling, CacheReadTokens: 1_000_000})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	docsSummary := report.ByModel["claude-haiku-3.5"]
+	if docsSummary.RecordCount != 2 {
+		t.Errorf("Expected 2 records under claude-haiku-3.5, got %d (%v)", docsSummary.RecordCount, report.ByModel)
+	}
+	expected := haiku.TotalCost + cachedCost.TotalCost
+	if math.Abs(docsSummary.TotalCost-expected) > 1e-9 {
+		t.Errorf("Expected claude-haiku-3.5 cost %f, got %f", expected, docsSummary.TotalCost)
+	}
+	if report.ByModel["claude-sonnet-4.0"].RecordCount != 1 {
+		t.Errorf("Expected only the coding record under claude-sonnet-4.0, got %d", report.ByModel["claude-sonnet-4.0"].RecordCount)
+	}
+}
+
+// TestActivityModelOverrideInAnalyses 測試依記錄計價的趨勢、時間範圍、效率、熱圖與會話分析皆套用活動類型覆寫
+func TestActivityModelOverrideInAnalyses(t *testing.T) {
+	calculator := NewCostCalculator()
+	if err := calculator.SetActivityModelOverride(types.ActivityDocumentation, "claude-haiku-3.5"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	now := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	record := newTestRecord(now, types.ActivityDocumentation, 1_000_000, 0, "claude-sonnet-4.0")
+	record.SessionID = "docs"
+	records := []types.UsageRecord{record}
+
+	haiku, err := calculator.CalculateCost(1_000_000, 0, "claude-haiku-3.5")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := haiku.TotalCost
+
+	assertCost := func(name string, got float64) {
+		t.Helper()
+		if math.Abs(got-expected) > 1e-9 {
+			t.Errorf("%s: expected overridden cost %f, got %f", name, expected, got)
+		}
+	}
+
+	trends, err := calculator.AnalyzeCostTrends(records, "daily")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assertCost("trends", trends.TotalCost)
+
+	rangeCost, _, err := calculator.GetCostForTimeRange(records, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assertCost("time range", rangeCost)
+
+	efficiency, err := calculator.CalculateCostEfficiency(records)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	assertCost("efficiency", float64(record.Tokens.Total)/efficiency.ByActivity[types.ActivityDocumentation])
+
+	assertCost("heatmap", calculator.CostHeatmap(records)["2026-03-02"][types.ActivityDocumentation])
+
+	sessions := calculator.BuildSessions(records)
+	if len(sessions) != 1 {
+		t.Fatalf("Expected 1 session, got %d", len(sessions))
+	}
+	assertCost("sessions", sessions[0].TotalCost)
+	assertCost("session daily costs", calculator.GetSessionDailyCosts(records)["docs"]["2026-03-02"])
+}
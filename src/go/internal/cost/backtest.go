@@ -0,0 +1,57 @@
+package cost
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"token-monitor/internal/types"
+)
+
+// minBacktestTrainingBuckets 產生預測所需的最少訓練時間點數
+const minBacktestTrainingBuckets = 3
+
+// BacktestPredictions 以最後 holdout 個時間區間作為驗證資料，使用其餘區間產生預測，
+// 回傳預測與實際成本的平均絕對百分比誤差（MAPE，單位為 %）。實際成本為 0 的區間不納入計算。
+func (cc *CostCalculatorImpl) BacktestPredictions(records []types.UsageRecord, timeRange string, holdout int) (mape float64, err error) {
+	cc.mutex.RLock()
+	defer cc.mutex.RUnlock()
+
+	if len(records) == 0 {
+		return 0, fmt.Errorf("no usage records provided")
+	}
+	if holdout <= 0 {
+		return 0, fmt.Errorf("holdout must be positive: %d", holdout)
+	}
+
+	dataPoints := cc.costDataPointsLocked(records, timeRange)
+	sort.Slice(dataPoints, func(i, j int) bool {
+		return dataPoints[i].Timestamp.Before(dataPoints[j].Timestamp)
+	})
+
+	training := len(dataPoints) - holdout
+	if training < minBacktestTrainingBuckets {
+		return 0, fmt.Errorf("not enough time buckets for backtest: have %d, need at least %d training buckets plus %d holdout",
+			len(dataPoints), minBacktestTrainingBuckets, holdout)
+	}
+
+	predictions := generateCostPredictionsN(dataPoints[:training], holdout)
+	if len(predictions) != holdout {
+		return 0, fmt.Errorf("unable to generate predictions from %d training buckets", training)
+	}
+
+	totalError := 0.0
+	compared := 0
+	for i, actual := range dataPoints[training:] {
+		if actual.Cost == 0 {
+			continue
+		}
+		totalError += math.Abs(actual.Cost-predictions[i].PredictedCost) / actual.Cost
+		compared++
+	}
+
+	if compared == 0 {
+		return 0, fmt.Errorf("all held-out buckets have zero cost")
+	}
+
+	return totalError / float64(compared) * 100, nil
+}
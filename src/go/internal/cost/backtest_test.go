@@ -0,0 +1,42 @@
+package cost
+
+import (
+	"math"
+	"testing"
+	"time"
+	"token-monitor/internal/types"
+)
+
+// TestBacktestPredictions 測試預測回測的平均絕對百分比誤差
+func TestBacktestPredictions(t *testing.T) {
+	calculator := NewCostCalculator()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// 每日成本線性成長：1x、2x、3x、4x、5x
+	records := make([]types.UsageRecord, 0)
+	for day := 0; day < 5; day++ {
+		tokens := 1000 * (day + 1)
+		records = append(records, newTestRecord(start.AddDate(0, 0, day), types.ActivityCoding, tokens, tokens, "claude-sonnet-4.0"))
+	}
+
+	mape, err := calculator.BacktestPredictions(records, "daily", 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// 訓練資料平均成長率 (1 + 0.5) / 2 = 0.75，預測 3 * (1 + 0.75) = 5.25、3 * (1 + 1.5) = 7.5
+	expected := (math.Abs(4-5.25)/4 + math.Abs(5-7.5)/5) / 2 * 100
+	if math.Abs(mape-expected) > 1e-6 {
+		t.Errorf("Expected MAPE %.4f, got %.4f", expected, mape)
+	}
+
+	if _, err := calculator.BacktestPredictions(records, "daily", 0); err == nil {
+		t.Error("Expected error for non-positive holdout")
+	}
+	if _, err := calculator.BacktestPredictions(records, "daily", 3); err == nil {
+		t.Error("Expected error when fewer than 3 training buckets remain")
+	}
+	if _, err := calculator.BacktestPredictions(nil, "daily", 1); err == nil {
+		t.Error("Expected error for empty records")
+	}
+}
@@ -0,0 +1,60 @@
+package cost
+
+import (
+	"fmt"
+	"strings"
+	"token-monitor/internal/types"
+)
+
+// RecordCostError 單筆記錄的成本計算錯誤
+type RecordCostError struct {
+	Index int
+	Err   error
+}
+
+// BatchCostError 批次成本計算中失敗記錄的錯誤清單
+type BatchCostError struct {
+	Failures []RecordCostError
+}
+
+// Error 實作 error 介面
+func (e *BatchCostError) Error() string {
+	messages := make([]string, 0, len(e.Failures))
+	for _, failure := range e.Failures {
+		messages = append(messages, fmt.Sprintf("record %d: %v", failure.Index, failure.Err))
+	}
+	return fmt.Sprintf("%d records failed cost calculation: %s", len(e.Failures), strings.Join(messages, "; "))
+}
+
+// CalculateCostBatch 批次計算記錄成本，只取得一次讀取鎖。
+// 失敗的記錄在結果中為 nil，並記錄在回傳的 *BatchCostError 中，不會中斷其他記錄的計算。
+func (cc *CostCalculatorImpl) CalculateCostBatch(records []types.UsageRecord) ([]*types.CostBreakdown, error) {
+	cc.mutex.RLock()
+	defer cc.mutex.RUnlock()
+
+	breakdowns, batchErr := cc.calculateCostBatchLocked(records)
+	if batchErr != nil {
+		return breakdowns, batchErr
+	}
+	return breakdowns, nil
+}
+
+// calculateCostBatchLocked 批次計算記錄成本（呼叫者須持有讀取鎖）
+func (cc *CostCalculatorImpl) calculateCostBatchLocked(records []types.UsageRecord) ([]*types.CostBreakdown, *BatchCostError) {
+	breakdowns := make([]*types.CostBreakdown, len(records))
+	var failures []RecordCostError
+
+	for i, record := range records {
+		breakdown, err := cc.calculateRecordCostLocked(record)
+		if err != nil {
+			failures = append(failures, RecordCostError{Index: i, Err: err})
+			continue
+		}
+		breakdowns[i] = breakdown
+	}
+
+	if len(failures) > 0 {
+		return breakdowns, &BatchCostError{Failures: failures}
+	}
+	return breakdowns, nil
+}
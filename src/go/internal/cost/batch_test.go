@@ -0,0 +1,51 @@
+package cost
+
+import (
+	"testing"
+	"time"
+	"token-monitor/internal/types"
+)
+
+// TestCalculateCostBatch 測試批次成本計算
+func TestCalculateCostBatch(t *testing.T) {
+	calculator := NewCostCalculator()
+	now := time.Now()
+
+	records := []types.UsageRecord{
+		newTestRecord(now, types.ActivityCoding, 1000, 2000, "claude-sonnet-4.0"),
+		newTestRecord(now, types.ActivityCoding, 1000, 1000, "unknown-model"),
+		newTestRecord(now, types.ActivityChat, 500, 500, "claude-haiku-3.5"),
+		newTestRecord(now, types.ActivityChat, -1, 500, "claude-haiku-3.5"),
+	}
+
+	breakdowns, err := calculator.CalculateCostBatch(records)
+	if len(breakdowns) != len(records) {
+		t.Fatalf("Expected %d results, got %d", len(records), len(breakdowns))
+	}
+
+	batchErr, ok := err.(*BatchCostError)
+	if !ok {
+		t.Fatalf("Expected *BatchCostError, got %T", err)
+	}
+	if len(batchErr.Failures) != 2 || batchErr.Failures[0].Index != 1 || batchErr.Failures[1].Index != 3 {
+		t.Errorf("Expected failures at indexes 1 and 3, got %+v", batchErr.Failures)
+	}
+
+	for _, i := range []int{0, 2} {
+		expected, err := calculator.CalculateCost(records[i].Tokens.Input, records[i].Tokens.Output, records[i].Cost.PricingModel)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if breakdowns[i] == nil || breakdowns[i].TotalCost != expected.TotalCost {
+			t.Errorf("Record %d: expected cost %f, got %+v", i, expected.TotalCost, breakdowns[i])
+		}
+	}
+	if breakdowns[1] != nil || breakdowns[3] != nil {
+		t.Errorf("Expected nil breakdowns for failed records")
+	}
+
+	// 全部成功時不應回傳錯誤
+	if _, err := calculator.CalculateCostBatch(records[:1]); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
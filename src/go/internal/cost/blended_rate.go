@@ -0,0 +1,47 @@
+package cost
+
+import (
+	"fmt"
+	"sort"
+)
+
+// BlendedRate 依各模型的 Token 佔比計算加權平均的輸入與輸出費率（USD / 1M tokens）。
+// usage 為模型 -> Token 數（或相對佔比），模型必須已註冊。
+func (cc *CostCalculatorImpl) BlendedRate(usage map[string]int) (inputRate, outputRate float64, err error) {
+	cc.mutex.RLock()
+	defer cc.mutex.RUnlock()
+
+	if len(usage) == 0 {
+		return 0, 0, fmt.Errorf("usage must include at least one model")
+	}
+
+	// 依名稱排序，確保錯誤訊息與浮點加總順序穩定
+	models := make([]string, 0, len(usage))
+	for model := range usage {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+
+	totalTokens := 0
+	for _, model := range models {
+		tokens := usage[model]
+		if tokens < 0 {
+			return 0, 0, fmt.Errorf("token share for model %s cannot be negative: %d", model, tokens)
+		}
+
+		pricingModel, err := cc.pricingEngine.GetPricingModel(model)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to get pricing model %s: %w", model, err)
+		}
+
+		inputRate += pricingModel.InputPrice * float64(tokens)
+		outputRate += pricingModel.OutputPrice * float64(tokens)
+		totalTokens += tokens
+	}
+
+	if totalTokens == 0 {
+		return 0, 0, fmt.Errorf("total token share must be positive")
+	}
+
+	return inputRate / float64(totalTokens), outputRate / float64(totalTokens), nil
+}
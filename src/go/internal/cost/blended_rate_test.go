@@ -0,0 +1,45 @@
+package cost
+
+import (
+	"math"
+	"testing"
+)
+
+// TestBlendedRate 測試多模型混合使用的加權平均費率
+func TestBlendedRate(t *testing.T) {
+	calculator := NewCostCalculator()
+
+	sonnet, _ := calculator.pricingEngine.GetPricingModel("claude-sonnet-4.0")
+	haiku, _ := calculator.pricingEngine.GetPricingModel("claude-haiku-3.5")
+
+	inputRate, outputRate, err := calculator.BlendedRate(map[string]int{
+		"claude-sonnet-4.0": 3000,
+		"claude-haiku-3.5":  1000,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expectedInput := (sonnet.InputPrice*3 + haiku.InputPrice) / 4
+	expectedOutput := (sonnet.OutputPrice*3 + haiku.OutputPrice) / 4
+	if math.Abs(inputRate-expectedInput) > 1e-9 || math.Abs(outputRate-expectedOutput) > 1e-9 {
+		t.Errorf("Expected blended rates %.4f/%.4f, got %.4f/%.4f", expectedInput, expectedOutput, inputRate, outputRate)
+	}
+
+	// 單一模型即為該模型費率
+	inputRate, outputRate, _ = calculator.BlendedRate(map[string]int{"claude-sonnet-4.0": 1})
+	if inputRate != sonnet.InputPrice || outputRate != sonnet.OutputPrice {
+		t.Errorf("Expected sonnet rates, got %.4f/%.4f", inputRate, outputRate)
+	}
+
+	for name, usage := range map[string]map[string]int{
+		"empty":    {},
+		"unknown":  {"unknown-model": 10},
+		"negative": {"claude-sonnet-4.0": -1},
+		"zero":     {"claude-sonnet-4.0": 0},
+	} {
+		if _, _, err := calculator.BlendedRate(usage); err == nil {
+			t.Errorf("Expected error for %s usage", name)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package cost
+
+import (
+	"fmt"
+	"token-monitor/internal/types"
+)
+
+// CostDelta 成本差異
+type CostDelta struct {
+	Current       float64 `json:"current"`
+	Baseline      float64 `json:"baseline"`
+	Delta         float64 `json:"delta"`
+	PercentChange float64 `json:"percent_change"` // 基準為零時固定為 0，並以 IsNew 標示
+	IsNew         bool    `json:"is_new"`
+}
+
+// CostComparison 成本期間比較結果
+type CostComparison struct {
+	Current    types.CostSummary                `json:"current"`
+	Baseline   types.CostSummary                `json:"baseline"`
+	Total      CostDelta                        `json:"total"`
+	ByActivity map[types.ActivityType]CostDelta `json:"by_activity"`
+	ByModel    map[string]CostDelta             `json:"by_model"`
+}
+
+// CompareCostPeriods 比較目前期間與基準期間的成本（例如本週 vs 上週）
+func (cc *CostCalculatorImpl) CompareCostPeriods(current, baseline []types.UsageRecord) (*CostComparison, error) {
+	if len(current) == 0 && len(baseline) == 0 {
+		return nil, fmt.Errorf("no usage records provided")
+	}
+
+	currentReport, err := cc.generatePeriodReport(current)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate current period report: %w", err)
+	}
+
+	baselineReport, err := cc.generatePeriodReport(baseline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate baseline period report: %w", err)
+	}
+
+	comparison := &CostComparison{
+		Current:    currentReport.Summary,
+		Baseline:   baselineReport.Summary,
+		Total:      newCostDelta(currentReport.Summary.TotalCost, baselineReport.Summary.TotalCost),
+		ByActivity: make(map[types.ActivityType]CostDelta),
+		ByModel:    make(map[string]CostDelta),
+	}
+
+	// 合併兩期出現過的活動類型與模型
+	for activityType, summary := range currentReport.ByActivity {
+		comparison.ByActivity[activityType] = newCostDelta(summary.TotalCost, baselineReport.ByActivity[activityType].TotalCost)
+	}
+	for activityType, summary := range baselineReport.ByActivity {
+		if _, exists := comparison.ByActivity[activityType]; !exists {
+			comparison.ByActivity[activityType] = newCostDelta(0, summary.TotalCost)
+		}
+	}
+
+	for model, summary := range currentReport.ByModel {
+		comparison.ByModel[model] = newCostDelta(summary.TotalCost, baselineReport.ByModel[model].TotalCost)
+	}
+	for model, summary := range baselineReport.ByModel {
+		if _, exists := comparison.ByModel[model]; !exists {
+			comparison.ByModel[model] = newCostDelta(0, summary.TotalCost)
+		}
+	}
+
+	return comparison, nil
+}
+
+// generatePeriodReport 生成單一期間的報告（空期間回傳空報告）
+func (cc *CostCalculatorImpl) generatePeriodReport(records []types.UsageRecord) (*types.CostReport, error) {
+	if len(records) == 0 {
+		return &types.CostReport{
+			ByActivity: make(map[types.ActivityType]types.CostSummary),
+			ByModel:    make(map[string]types.CostSummary),
+		}, nil
+	}
+
+	return cc.GenerateCostReport(records, &types.ReportOptions{})
+}
+
+// newCostDelta 建立成本差異
+func newCostDelta(current, baseline float64) CostDelta {
+	delta := CostDelta{
+		Current:  current,
+		Baseline: baseline,
+		Delta:    current - baseline,
+	}
+
+	if baseline > 0 {
+		delta.PercentChange = delta.Delta / baseline * 100
+	} else if current > 0 {
+		delta.IsNew = true
+	}
+
+	return delta
+}
@@ -0,0 +1,75 @@
+package cost
+
+import (
+	"testing"
+	"time"
+	"token-monitor/internal/mathutil"
+	"token-monitor/internal/types"
+)
+
+// TestCompareCostPeriods 測試期間成本比較
+func TestCompareCostPeriods(t *testing.T) {
+	calculator := NewCostCalculator()
+	now := time.Now()
+
+	baseline := []types.UsageRecord{
+		newTestRecord(now.AddDate(0, 0, -8), types.ActivityCoding, 1000, 1000, "claude-sonnet-4.0"),
+		newTestRecord(now.AddDate(0, 0, -9), types.ActivityChat, 1000, 1000, "claude-sonnet-4.0"),
+	}
+	current := []types.UsageRecord{
+		newTestRecord(now.AddDate(0, 0, -1), types.ActivityCoding, 2000, 2000, "claude-sonnet-4.0"),
+		newTestRecord(now, types.ActivityDebugging, 1000, 1000, "claude-opus-4.0"),
+	}
+
+	comparison, err := calculator.CompareCostPeriods(current, baseline)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// 基準: 2 * 0.018 = 0.036；目前: 0.036 + 0.09 = 0.126
+	if mathutil.AbsFloat(comparison.Total.Delta-0.09) > 1e-9 {
+		t.Errorf("Expected total delta 0.09, got %.6f", comparison.Total.Delta)
+	}
+	if mathutil.AbsFloat(comparison.Total.PercentChange-250) > 1e-6 {
+		t.Errorf("Expected 250%% change, got %.4f", comparison.Total.PercentChange)
+	}
+
+	coding := comparison.ByActivity[types.ActivityCoding]
+	if mathutil.AbsFloat(coding.PercentChange-100) > 1e-6 {
+		t.Errorf("Expected coding to double, got %.4f%%", coding.PercentChange)
+	}
+
+	debugging := comparison.ByActivity[types.ActivityDebugging]
+	if !debugging.IsNew || debugging.PercentChange != 0 {
+		t.Errorf("Expected debugging to be reported as new, got %+v", debugging)
+	}
+
+	chat := comparison.ByActivity[types.ActivityChat]
+	if chat.Current != 0 || mathutil.AbsFloat(chat.PercentChange+100) > 1e-6 {
+		t.Errorf("Expected chat to drop by 100%%, got %+v", chat)
+	}
+
+	if !comparison.ByModel["claude-opus-4.0"].IsNew {
+		t.Errorf("Expected opus model to be reported as new")
+	}
+}
+
+// TestCompareCostPeriodsEmptyBaseline 測試空基準期間
+func TestCompareCostPeriodsEmptyBaseline(t *testing.T) {
+	calculator := NewCostCalculator()
+	current := []types.UsageRecord{
+		newTestRecord(time.Now(), types.ActivityCoding, 1000, 1000, "claude-sonnet-4.0"),
+	}
+
+	comparison, err := calculator.CompareCostPeriods(current, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !comparison.Total.IsNew || comparison.Baseline.TotalCost != 0 {
+		t.Errorf("Expected total to be reported as new, got %+v", comparison.Total)
+	}
+
+	if _, err := calculator.CompareCostPeriods(nil, nil); err == nil {
+		t.Errorf("Expected error when both periods are empty")
+	}
+}
@@ -0,0 +1,53 @@
+package cost
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 定價配置文件格式
+const (
+	ConfigFormatJSON = "json"
+	ConfigFormatYAML = "yaml"
+)
+
+// detectConfigFormat 依副檔名判斷配置格式；沒有副檔名時依內容判斷（以 { 開頭視為 JSON）
+func detectConfigFormat(configPath string, data []byte) (string, error) {
+	switch ext := strings.ToLower(filepath.Ext(configPath)); ext {
+	case ".json":
+		return ConfigFormatJSON, nil
+	case ".yaml", ".yml":
+		return ConfigFormatYAML, nil
+	case "":
+		if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+			return ConfigFormatJSON, nil
+		}
+		return ConfigFormatYAML, nil
+	default:
+		return "", fmt.Errorf("unsupported config format %q (expected .json, .yaml or .yml)", ext)
+	}
+}
+
+// unmarshalPricingConfig 依配置格式解析定價配置
+func unmarshalPricingConfig(configPath string, data []byte, out interface{}) error {
+	format, err := detectConfigFormat(configPath, data)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case ConfigFormatJSON:
+		err = json.Unmarshal(data, out)
+	default:
+		err = yaml.Unmarshal(data, out)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s config: %w", format, err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package cost
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testJSONPricingConfig = `{
+  "pricing": {
+    "json-model": {
+      "input": 2.0,
+      "output": 10.0,
+      "cache_read": 0.2,
+      "cache_write": 2.5,
+      "batch_discount": 0.4,
+      "batch_discount_scope": "output"
+    }
+  }
+}`
+
+// writeTestConfig 寫入測試用配置文件
+func writeTestConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	configPath := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return configPath
+}
+
+// TestLoadPricingModelsJSON 測試以 JSON 格式載入定價模型
+func TestLoadPricingModelsJSON(t *testing.T) {
+	calculator := NewCostCalculator()
+	reloads := 0
+	calculator.pricingEngine.OnReload(func() { reloads++ })
+	if err := calculator.LoadPricingModels(writeTestConfig(t, "pricing.json", testJSONPricingConfig)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if reloads != 1 {
+		t.Errorf("Expected reload callbacks to fire once, got %d", reloads)
+	}
+
+	model, err := calculator.pricingEngine.GetPricingModel("json-model")
+	if err != nil {
+		t.Fatalf("Expected json-model to be loaded: %v", err)
+	}
+	if model.InputPrice != 2.0 || model.OutputPrice != 10.0 || model.CacheRead != 0.2 || model.BatchDiscount != 0.4 {
+		t.Errorf("Unexpected pricing model: %+v", model)
+	}
+	if model.BatchDiscountScope != BatchDiscountScopeOutput {
+		t.Errorf("Expected batch discount scope %q, got %q", BatchDiscountScopeOutput, model.BatchDiscountScope)
+	}
+}
+
+// TestPricingEngineLoadFromConfigJSON 測試定價引擎載入 JSON 配置，無副檔名時依內容判斷
+func TestPricingEngineLoadFromConfigJSON(t *testing.T) {
+	for _, name := range []string{"pricing.json", "pricing"} {
+		engine := NewPricingEngine()
+		if err := engine.LoadFromConfig(writeTestConfig(t, name, testJSONPricingConfig)); err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+
+		model, err := engine.GetPricingModel("json-model")
+		if err != nil {
+			t.Fatalf("%s: expected json-model to be loaded: %v", name, err)
+		}
+		if model.BatchDiscountScope != BatchDiscountScopeOutput || model.CacheWrite != 2.5 {
+			t.Errorf("%s: unexpected pricing model: %+v", name, model)
+		}
+	}
+}
+
+// TestLoadPricingModelsUnsupportedFormat 測試不支援的格式回傳明確錯誤
+func TestLoadPricingModelsUnsupportedFormat(t *testing.T) {
+	calculator := NewCostCalculator()
+
+	err := calculator.LoadPricingModels(writeTestConfig(t, "pricing.toml", "[pricing]\n"))
+	if err == nil || !strings.Contains(err.Error(), "unsupported config format") {
+		t.Errorf("Expected unsupported format error, got %v", err)
+	}
+
+	// 副檔名為 JSON 但內容無效
+	err = calculator.LoadPricingModels(writeTestConfig(t, "pricing.json", "pricing:\n  model: {}\n"))
+	if err == nil || !strings.Contains(err.Error(), "invalid json config") {
+		t.Errorf("Expected invalid json error, got %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package cost
+
+import (
+	"fmt"
+	"time"
+	"token-monitor/internal/types"
+)
+
+// Turn 對話中的一輪，InputTokens 為本輪新增的輸入（不含先前的對話內容）
+type Turn struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// CalculateConversationCost 計算多輪對話的總成本。每一輪的提示包含先前所有輪次的輸入與輸出。
+//
+// 未啟用快取時，每輪的完整提示皆以輸入價格計費。啟用快取時，先前已寫入快取的前綴以快取讀取計費，
+// 其餘新內容（上一輪輸出與本輪輸入）寫入快取一次，因此每個 Token 只會被寫入一次。回傳各輪加總的成本分解，不記入成本追蹤。
+func (cc *CostCalculatorImpl) CalculateConversationCost(turns []Turn, model string, cacheEnabled bool) (*types.CostBreakdown, error) {
+	cc.mutex.RLock()
+	defer cc.mutex.RUnlock()
+
+	if len(turns) == 0 {
+		return nil, fmt.Errorf("conversation must have at least one turn")
+	}
+	if model == "" {
+		model = cc.pricingEngine.GetDefaultModel()
+	}
+
+	pricingModel, err := cc.pricingEngine.GetPricingModel(model)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pricing model %s: %w", model, err)
+	}
+	if cacheEnabled && pricingModel.CacheRead == 0 && pricingModel.CacheWrite == 0 {
+		return nil, fmt.Errorf("model %s does not support prompt caching", model)
+	}
+
+	total := &types.CostBreakdown{
+		Currency:     "USD",
+		PricingModel: model,
+		Timestamp:    time.Now(),
+		CostDetails: types.CostDetails{
+			InputRate:   pricingModel.InputPrice,
+			OutputRate:  pricingModel.OutputPrice,
+			BillingMode: StandardBilling.String(),
+		},
+	}
+	if cacheEnabled {
+		total.CostDetails.CacheReadRate = pricingModel.CacheRead
+		total.CostDetails.CacheWriteRate = pricingModel.CacheWrite
+		total.CostDetails.BillingMode = CacheBilling.String()
+	}
+
+	prefix := 0 // 先前輪次的輸入與輸出總和
+	cached := 0 // 已寫入快取的 Token 數
+	for i, turn := range turns {
+		if turn.InputTokens < 0 || turn.OutputTokens < 0 {
+			return nil, fmt.Errorf("turn %d: token counts cannot be negative: input=%d, output=%d", i, turn.InputTokens, turn.OutputTokens)
+		}
+
+		prompt := prefix + turn.InputTokens
+		inputTokens, options := prompt, &CostOptions{Mode: StandardBilling}
+		if cacheEnabled {
+			written := prompt - cached
+			inputTokens = 0
+			options = &CostOptions{Mode: CacheBilling, CacheReadTokens: cached, CacheWriteTokens: written}
+			cached += written
+		}
+
+		breakdown, err := cc.computeDetailedCost(inputTokens, turn.OutputTokens, model, options)
+		if err != nil {
+			return nil, fmt.Errorf("turn %d: %w", i, err)
+		}
+		addBreakdown(total, breakdown)
+
+		prefix = prompt + turn.OutputTokens
+	}
+
+	if listPrice := listPriceTotal(total.TokenCounts, pricingModel); listPrice > 0 {
+		total.EffectiveDiscountPercent = (1 - total.TotalCost/listPrice) * 100
+	}
+
+	return total, nil
+}
+
+// addBreakdown 將成本分解的金額與 Token 數累加到 total
+func addBreakdown(total, breakdown *types.CostBreakdown) {
+	total.InputCost += breakdown.InputCost
+	total.OutputCost += breakdown.OutputCost
+	total.CacheReadCost += breakdown.CacheReadCost
+	total.CacheWriteCost += breakdown.CacheWriteCost
+	total.BatchDiscount += breakdown.BatchDiscount
+	total.ReasoningCost += breakdown.ReasoningCost
+	total.TotalCost += breakdown.TotalCost
+
+	total.TokenCounts.Input += breakdown.TokenCounts.Input
+	total.TokenCounts.Output += breakdown.TokenCounts.Output
+	total.TokenCounts.CacheRead += breakdown.TokenCounts.CacheRead
+	total.TokenCounts.CacheWrite += breakdown.TokenCounts.CacheWrite
+	total.TokenCounts.Reasoning += breakdown.TokenCounts.Reasoning
+	total.TokenCounts.Total += breakdown.TokenCounts.Total
+}
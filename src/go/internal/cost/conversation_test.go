@@ -0,0 +1,70 @@
+package cost
+
+import (
+	"math"
+	"testing"
+	"token-monitor/internal/types"
+)
+
+// TestCalculateConversationCost 測試多輪對話在有無快取時的成本
+func TestCalculateConversationCost(t *testing.T) {
+	calculator := NewCostCalculator()
+	calculator.pricingEngine.AddPricingModel("conversation-model", &types.PricingModel{
+		Name:        "conversation-model",
+		InputPrice:  2.0,
+		OutputPrice: 4.0,
+		CacheRead:   0.2,
+		CacheWrite:  2.5,
+	})
+	turns := []Turn{
+		{InputTokens: 1_000_000, OutputTokens: 1_000_000},
+		{InputTokens: 1_000_000, OutputTokens: 1_000_000},
+	}
+
+	// 無快取：第一輪 1M 輸入；第二輪提示包含前一輪共 3M 輸入
+	standard, err := calculator.CalculateConversationCost(turns, "conversation-model", false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if math.Abs(standard.TotalCost-16.0) > 1e-9 || standard.TokenCounts.Input != 4_000_000 {
+		t.Errorf("Expected standard total 16.0 with 4M input, got %f with %d", standard.TotalCost, standard.TokenCounts.Input)
+	}
+
+	// 快取：第一輪寫入 1M；第二輪讀取 1M、寫入 2M（上一輪輸出與本輪輸入）
+	cached, err := calculator.CalculateConversationCost(turns, "conversation-model", true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cached.TokenCounts.CacheRead != 1_000_000 || cached.TokenCounts.CacheWrite != 3_000_000 || cached.TokenCounts.Input != 0 {
+		t.Errorf("Unexpected cached token counts: %+v", cached.TokenCounts)
+	}
+	if math.Abs(cached.TotalCost-15.7) > 1e-9 {
+		t.Errorf("Expected cached total 15.7, got %f", cached.TotalCost)
+	}
+	if math.Abs(cached.EffectiveDiscountPercent-(1-15.7/16.0)*100) > 1e-9 {
+		t.Errorf("Expected effective discount relative to uncached cost, got %f", cached.EffectiveDiscountPercent)
+	}
+	if cached.CostDetails.BillingMode != BillingModeCache {
+		t.Errorf("Expected cache billing mode, got %s", cached.CostDetails.BillingMode)
+	}
+}
+
+// TestCalculateConversationCostValidation 測試對話成本的輸入驗證
+func TestCalculateConversationCostValidation(t *testing.T) {
+	calculator := NewCostCalculator()
+	calculator.pricingEngine.AddPricingModel("no-cache-model", &types.PricingModel{
+		Name:        "no-cache-model",
+		InputPrice:  1.0,
+		OutputPrice: 1.0,
+	})
+
+	if _, err := calculator.CalculateConversationCost(nil, "claude-sonnet-4.0", true); err == nil {
+		t.Errorf("Expected error for empty conversation")
+	}
+	if _, err := calculator.CalculateConversationCost([]Turn{{InputTokens: -1}}, "claude-sonnet-4.0", false); err == nil {
+		t.Errorf("Expected error for negative tokens")
+	}
+	if _, err := calculator.CalculateConversationCost([]Turn{{InputTokens: 100}}, "no-cache-model", true); err == nil {
+		t.Errorf("Expected error for model without cache pricing")
+	}
+}
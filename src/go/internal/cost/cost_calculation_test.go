@@ -2,6 +2,7 @@ package cost
 
 import (
 	"testing"
+	"token-monitor/internal/mathutil"
 	"token-monitor/internal/types"
 )
 
@@ -67,20 +68,20 @@ func TestCostCalculationAccuracy(t *testing.T) {
 				return
 			}
 
-			if absFloat64(result.TotalCost-tc.expectedCost) > tc.tolerance {
+			if mathutil.AbsFloat(result.TotalCost-tc.expectedCost) > tc.tolerance {
 				t.Errorf("Expected cost %.6f, got %.6f (difference: %.6f)",
-					tc.expectedCost, result.TotalCost, absFloat64(result.TotalCost-tc.expectedCost))
+					tc.expectedCost, result.TotalCost, mathutil.AbsFloat(result.TotalCost-tc.expectedCost))
 			}
 
 			// 驗證成本分解
 			expectedInputCost := float64(tc.inputTokens) / 1_000_000 * result.CostDetails.InputRate
 			expectedOutputCost := float64(tc.outputTokens) / 1_000_000 * result.CostDetails.OutputRate
 
-			if absFloat64(result.InputCost-expectedInputCost) > tc.tolerance {
+			if mathutil.AbsFloat(result.InputCost-expectedInputCost) > tc.tolerance {
 				t.Errorf("Expected input cost %.6f, got %.6f", expectedInputCost, result.InputCost)
 			}
 
-			if absFloat64(result.OutputCost-expectedOutputCost) > tc.tolerance {
+			if mathutil.AbsFloat(result.OutputCost-expectedOutputCost) > tc.tolerance {
 				t.Errorf("Expected output cost %.6f, got %.6f", expectedOutputCost, result.OutputCost)
 			}
 		})
@@ -133,7 +134,7 @@ func TestDifferentPricingModels(t *testing.T) {
 
 			// 驗證成本分解的一致性
 			expectedTotal := result.InputCost + result.OutputCost
-			if absFloat64(result.TotalCost-expectedTotal) > 0.000001 {
+			if mathutil.AbsFloat(result.TotalCost-expectedTotal) > 0.000001 {
 				t.Errorf("Cost breakdown inconsistent: input(%.6f) + output(%.6f) = %.6f, but total is %.6f",
 					result.InputCost, result.OutputCost, expectedTotal, result.TotalCost)
 			}
@@ -250,7 +251,7 @@ func TestCostOptimizationSuggestions(t *testing.T) {
 
 	// 驗證邏輯一致性
 	expectedSavings := suggestions.CurrentCost - suggestions.OptimizedCost
-	if absFloat64(suggestions.TotalSavings-expectedSavings) > 0.000001 {
+	if mathutil.AbsFloat(suggestions.TotalSavings-expectedSavings) > 0.000001 {
 		t.Errorf("Savings calculation inconsistent: current(%.6f) - optimized(%.6f) = %.6f, but total savings is %.6f",
 			suggestions.CurrentCost, suggestions.OptimizedCost, expectedSavings, suggestions.TotalSavings)
 	}
@@ -283,17 +284,17 @@ func TestCacheBillingAccuracy(t *testing.T) {
 	expectedCacheReadCost := float64(cacheReadTokens) / 1_000_000 * 0.30
 	expectedCacheWriteCost := float64(cacheWriteTokens) / 1_000_000 * 3.75
 
-	if absFloat64(result.CacheReadCost-expectedCacheReadCost) > 0.000001 {
+	if mathutil.AbsFloat(result.CacheReadCost-expectedCacheReadCost) > 0.000001 {
 		t.Errorf("Expected cache read cost %.6f, got %.6f", expectedCacheReadCost, result.CacheReadCost)
 	}
 
-	if absFloat64(result.CacheWriteCost-expectedCacheWriteCost) > 0.000001 {
+	if mathutil.AbsFloat(result.CacheWriteCost-expectedCacheWriteCost) > 0.000001 {
 		t.Errorf("Expected cache write cost %.6f, got %.6f", expectedCacheWriteCost, result.CacheWriteCost)
 	}
 
 	// 驗證總成本包含快取成本
 	expectedTotalCost := result.InputCost + result.OutputCost + result.CacheReadCost + result.CacheWriteCost
-	if absFloat64(result.TotalCost-expectedTotalCost) > 0.000001 {
+	if mathutil.AbsFloat(result.TotalCost-expectedTotalCost) > 0.000001 {
 		t.Errorf("Expected total cost %.6f, got %.6f", expectedTotalCost, result.TotalCost)
 	}
 
@@ -325,7 +326,7 @@ func TestBatchBillingAccuracy(t *testing.T) {
 	// 驗證批次折扣
 	// Claude Sonnet 4.0 有 50% 的批次折扣
 	expectedDiscountRate := 0.5
-	if absFloat64(result.CostDetails.DiscountRate-expectedDiscountRate) > 0.000001 {
+	if mathutil.AbsFloat(result.CostDetails.DiscountRate-expectedDiscountRate) > 0.000001 {
 		t.Errorf("Expected discount rate %.2f, got %.2f", expectedDiscountRate, result.CostDetails.DiscountRate)
 	}
 
@@ -336,13 +337,13 @@ func TestBatchBillingAccuracy(t *testing.T) {
 
 	// 驗證折扣金額
 	expectedBatchDiscount := originalTotalCost * expectedDiscountRate
-	if absFloat64(result.BatchDiscount-expectedBatchDiscount) > 0.000001 {
+	if mathutil.AbsFloat(result.BatchDiscount-expectedBatchDiscount) > 0.000001 {
 		t.Errorf("Expected batch discount %.6f, got %.6f", expectedBatchDiscount, result.BatchDiscount)
 	}
 
 	// 驗證折扣後的總成本
 	expectedDiscountedCost := originalTotalCost * (1 - expectedDiscountRate)
-	if absFloat64(result.TotalCost-expectedDiscountedCost) > 0.000001 {
+	if mathutil.AbsFloat(result.TotalCost-expectedDiscountedCost) > 0.000001 {
 		t.Errorf("Expected discounted total cost %.6f, got %.6f", expectedDiscountedCost, result.TotalCost)
 	}
 
@@ -352,6 +353,29 @@ func TestBatchBillingAccuracy(t *testing.T) {
 	}
 }
 
+// TestParseBillingMode 測試計費模式字串與列舉互相轉換
+func TestParseBillingMode(t *testing.T) {
+	for _, mode := range []BillingMode{StandardBilling, CacheBilling, BatchBilling} {
+		parsed, err := ParseBillingMode(mode.String())
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %v", mode, err)
+		}
+		if parsed != mode {
+			t.Errorf("Expected %s to round-trip, got %s", mode, parsed)
+		}
+		if !IsValidBillingMode(mode.String()) {
+			t.Errorf("Expected %s to be valid", mode)
+		}
+	}
+
+	if _, err := ParseBillingMode("Cache"); err == nil {
+		t.Errorf("Expected error for unknown billing mode")
+	}
+	if IsValidBillingMode("") {
+		t.Errorf("Expected empty billing mode to be invalid")
+	}
+}
+
 // TestModelComparison 測試模型比較功能
 func TestModelComparison(t *testing.T) {
 	calculator := NewCostCalculator()
@@ -401,7 +425,7 @@ func TestModelComparison(t *testing.T) {
 	if len(costs) > 1 {
 		firstCost := costs[0]
 		for _, cost := range costs[1:] {
-			if absFloat64(cost-firstCost) > 0.000001 {
+			if mathutil.AbsFloat(cost-firstCost) > 0.000001 {
 				allSame = false
 				break
 			}
@@ -413,11 +437,3 @@ func TestModelComparison(t *testing.T) {
 		t.Log("Warning: All models have the same cost, this might indicate an issue")
 	}
 }
-
-// absFloat64 計算浮點數絕對值
-func absFloat64(x float64) float64 {
-	if x < 0 {
-		return -x
-	}
-	return x
-}
@@ -3,12 +3,14 @@ package cost
 import (
 	"fmt"
 	"log"
+	"math"
 	"os"
+	"sort"
+	"strings"
 	"sync"
 	"time"
+	"token-monitor/internal/errors"
 	"token-monitor/internal/types"
-
-	"gopkg.in/yaml.v3"
 )
 
 // CostCalculatorImpl CostCalculator 介面實作
@@ -19,10 +21,32 @@ type CostCalculatorImpl struct {
 
 	// 成本追蹤
 	sessionCosts map[string]float64
-	dailyCosts   map[string]float64
+	dailyCosts   map[string]map[string]float64 // 日期 -> 模型 -> 成本
 
 	// 最後更新時間
 	lastConfigUpdate time.Time
+
+	// 優化分析器（重複使用以保留閾值設定，受 mutex 保護）
+	optimizer *Optimizer
+
+	// 同一 SessionID 間隔超過此時間即視為不同會話
+	sessionGap time.Duration
+
+	// 每日成本追蹤設定（保留天數 0 表示不限制）
+	dailyTrackingDisabled bool
+	dailyRetentionDays    int
+
+	// OpenAI 模型名稱 -> 定價模型名稱
+	openAIModelAliases map[string]string
+
+	// 活動類型 -> 定價模型名稱（依工作類型議定的費率）
+	activityModelOverrides map[types.ActivityType]string
+
+	// 報告去重使用的鍵函式（nil 表示 DefaultRecordKey）
+	recordKeyFunc RecordKeyFunc
+
+	// 只有總 Token 數的記錄拆分輸入/輸出時的輸入比例
+	inputFraction float64
 }
 
 // BillingMode 計費模式
@@ -34,33 +58,66 @@ const (
 	BatchBilling
 )
 
+// 計費模式字串，對應 types.CostDetails.BillingMode
+const (
+	BillingModeStandard = "standard"
+	BillingModeCache    = "cache"
+	BillingModeBatch    = "batch"
+)
+
+// String 取得計費模式字串
+func (m BillingMode) String() string {
+	switch m {
+	case CacheBilling:
+		return BillingModeCache
+	case BatchBilling:
+		return BillingModeBatch
+	default:
+		return BillingModeStandard
+	}
+}
+
+// ParseBillingMode 將計費模式字串轉換為 BillingMode
+func ParseBillingMode(s string) (BillingMode, error) {
+	switch s {
+	case BillingModeStandard:
+		return StandardBilling, nil
+	case BillingModeCache:
+		return CacheBilling, nil
+	case BillingModeBatch:
+		return BatchBilling, nil
+	default:
+		return StandardBilling, fmt.Errorf("invalid billing mode: %q", s)
+	}
+}
+
+// IsValidBillingMode 檢查計費模式字串是否有效
+func IsValidBillingMode(s string) bool {
+	_, err := ParseBillingMode(s)
+	return err == nil
+}
+
 // CostOptions 成本計算選項
 type CostOptions struct {
 	Mode             BillingMode
 	CacheReadTokens  int
 	CacheWriteTokens int
+	ReasoningTokens  int
 	IsBatch          bool
 	SessionID        string
 	ActivityType     types.ActivityType
 }
 
-// ConfigData 配置文件結構
-type ConfigData struct {
-	Pricing map[string]struct {
-		Input         float64 `yaml:"input"`
-		Output        float64 `yaml:"output"`
-		CacheRead     float64 `yaml:"cache_read"`
-		CacheWrite    float64 `yaml:"cache_write"`
-		BatchDiscount float64 `yaml:"batch_discount"`
-	} `yaml:"pricing"`
-}
-
 // NewCostCalculator 創建新的成本計算器
 func NewCostCalculator() *CostCalculatorImpl {
+	pricingEngine := NewPricingEngine()
 	return &CostCalculatorImpl{
-		pricingEngine: NewPricingEngine(),
+		pricingEngine: pricingEngine,
 		sessionCosts:  make(map[string]float64),
-		dailyCosts:    make(map[string]float64),
+		dailyCosts:    make(map[string]map[string]float64),
+		optimizer:     NewOptimizer(pricingEngine),
+		sessionGap:    DefaultSessionGap,
+		inputFraction: DefaultInputFraction,
 	}
 }
 
@@ -69,13 +126,18 @@ func (cc *CostCalculatorImpl) CalculateCost(inputTokens, outputTokens int, model
 	cc.mutex.RLock()
 	defer cc.mutex.RUnlock()
 
+	return cc.calculateCostLocked(inputTokens, outputTokens, model)
+}
+
+// calculateCostLocked 計算成本（呼叫者須持有讀取鎖）
+func (cc *CostCalculatorImpl) calculateCostLocked(inputTokens, outputTokens int, model string) (*types.CostBreakdown, error) {
 	// 輸入驗證
 	if inputTokens < 0 || outputTokens < 0 {
 		return nil, fmt.Errorf("token counts cannot be negative: input=%d, output=%d", inputTokens, outputTokens)
 	}
 
 	if model == "" {
-		model = "claude-sonnet-4.0" // 預設模型
+		model = cc.pricingEngine.GetDefaultModel()
 	}
 
 	// 獲取定價模型
@@ -99,7 +161,7 @@ func (cc *CostCalculatorImpl) CalculateCost(inputTokens, outputTokens int, model
 	breakdown.CostDetails = types.CostDetails{
 		InputRate:   pricingModel.InputPrice,
 		OutputRate:  pricingModel.OutputPrice,
-		BillingMode: "standard",
+		BillingMode: StandardBilling.String(),
 	}
 	breakdown.Timestamp = time.Now()
 
@@ -108,8 +170,73 @@ func (cc *CostCalculatorImpl) CalculateCost(inputTokens, outputTokens int, model
 
 // CalculateDetailedCost 計算詳細成本（新增功能）
 func (cc *CostCalculatorImpl) CalculateDetailedCost(inputTokens, outputTokens int, model string, options *CostOptions) (*types.CostBreakdown, error) {
-	cc.mutex.RLock()
-	defer cc.mutex.RUnlock()
+	// 追蹤會寫入 map，需持寫入鎖
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	breakdown, err := cc.computeDetailedCost(inputTokens, outputTokens, model, options)
+	if err != nil {
+		return nil, err
+	}
+
+	cc.trackCost(breakdown, options)
+
+	return breakdown, nil
+}
+
+// CalculateDetailedCostWithinBudget 計算詳細成本，加入後的會話（無 SessionID 時為當日）累計成本超過預算時回傳 ErrCodeBudgetExceeded 且不記錄追蹤
+func (cc *CostCalculatorImpl) CalculateDetailedCostWithinBudget(inputTokens, outputTokens int, model string, options *CostOptions, remainingBudget float64) (*types.CostBreakdown, error) {
+	if options == nil {
+		return nil, fmt.Errorf("options cannot be nil")
+	}
+
+	// 檢查與記錄需在同一寫入鎖內完成，避免並行呼叫共同突破預算
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	breakdown, err := cc.computeDetailedCost(inputTokens, outputTokens, model, options)
+	if err != nil {
+		return nil, err
+	}
+
+	spent := cc.budgetSpentLocked(options)
+	if spent+breakdown.TotalCost > remainingBudget {
+		appErr := errors.Newf(errors.ErrCodeBudgetExceeded,
+			"cost %.6f exceeds remaining budget %.6f (already spent %.6f)", breakdown.TotalCost, remainingBudget, spent)
+		return nil, appErr.WithContext(errors.ErrorContext{
+			Operation: "calculate_within_budget",
+			Component: "cost_calculator",
+			Parameters: map[string]interface{}{
+				"model":            model,
+				"cost":             breakdown.TotalCost,
+				"spent":            spent,
+				"remaining_budget": remainingBudget,
+			},
+		})
+	}
+
+	cc.trackCost(breakdown, options)
+
+	return breakdown, nil
+}
+
+// budgetSpentLocked 回傳預算檢查所用的已追蹤成本：有 SessionID 時為會話累計，否則為當日累計，呼叫者需持有鎖
+func (cc *CostCalculatorImpl) budgetSpentLocked(options *CostOptions) float64 {
+	if options.SessionID != "" {
+		return cc.sessionCosts[options.SessionID]
+	}
+
+	var total float64
+	for _, cost := range cc.dailyCosts[time.Now().Format("2006-01-02")] {
+		total += cost
+	}
+	return total
+}
+
+// computeDetailedCost 計算詳細成本但不更新會話與日常追蹤
+func (cc *CostCalculatorImpl) computeDetailedCost(inputTokens, outputTokens int, model string, options *CostOptions) (*types.CostBreakdown, error) {
+	// 依活動類型覆寫定價模型
+	model = cc.resolveActivityModel(model, options)
 
 	// 輸入驗證
 	if err := cc.validateInput(inputTokens, outputTokens, model, options); err != nil {
@@ -135,7 +262,7 @@ func (cc *CostCalculatorImpl) CalculateDetailedCost(inputTokens, outputTokens in
 		CostDetails: types.CostDetails{
 			InputRate:   pricingModel.InputPrice,
 			OutputRate:  pricingModel.OutputPrice,
-			BillingMode: "standard",
+			BillingMode: StandardBilling.String(),
 		},
 	}
 
@@ -150,6 +277,12 @@ func (cc *CostCalculatorImpl) CalculateDetailedCost(inputTokens, outputTokens in
 			breakdown.TokenCounts.CacheWrite = options.CacheWriteTokens
 			breakdown.TokenCounts.Total += options.CacheReadTokens + options.CacheWriteTokens
 		}
+
+		// 設定推理 Token 數量
+		if options.ReasoningTokens > 0 {
+			breakdown.TokenCounts.Reasoning = options.ReasoningTokens
+			breakdown.TokenCounts.Total += options.ReasoningTokens
+		}
 	}
 
 	// 根據計費模式計算成本
@@ -166,15 +299,40 @@ func (cc *CostCalculatorImpl) CalculateDetailedCost(inputTokens, outputTokens in
 		return nil, fmt.Errorf("failed to calculate cost: %w", err)
 	}
 
-	// 記錄成本到會話和日常追蹤
+	if listPrice := listPriceTotal(breakdown.TokenCounts, pricingModel); listPrice > 0 {
+		breakdown.EffectiveDiscountPercent = (1 - breakdown.TotalCost/listPrice) * 100
+	}
+
+	return breakdown, nil
+}
+
+// listPriceTotal 以標準模式計算相同 Token 的定價表價格，快取讀寫 Token 以輸入價格計
+func listPriceTotal(counts types.TokenCounts, model *types.PricingModel) float64 {
+	inputMTokens := float64(counts.Input+counts.CacheRead+counts.CacheWrite) / 1_000_000
+	outputMTokens := float64(counts.Output) / 1_000_000
+
+	total := inputMTokens*model.InputPrice + outputMTokens*model.OutputPrice
+	if counts.Reasoning > 0 && model.ReasoningPrice > 0 {
+		total += float64(counts.Reasoning) / 1_000_000 * model.ReasoningPrice
+	}
+	return total
+}
+
+// trackCost 記錄成本到會話和日常追蹤
+func (cc *CostCalculatorImpl) trackCost(breakdown *types.CostBreakdown, options *CostOptions) {
 	if options != nil && options.SessionID != "" {
 		cc.sessionCosts[options.SessionID] += breakdown.TotalCost
 	}
 
-	today := time.Now().Format("2006-01-02")
-	cc.dailyCosts[today] += breakdown.TotalCost
-
-	return breakdown, nil
+	if !cc.dailyTrackingDisabled {
+		now := time.Now()
+		date := now.Format("2006-01-02")
+		if cc.dailyCosts[date] == nil {
+			cc.dailyCosts[date] = make(map[string]float64)
+		}
+		cc.dailyCosts[date][breakdown.PricingModel] += breakdown.TotalCost
+		cc.pruneDailyCosts(now)
+	}
 }
 
 // calculateStandardCost 計算標準成本
@@ -186,7 +344,15 @@ func (cc *CostCalculatorImpl) calculateStandardCost(breakdown *types.CostBreakdo
 	breakdown.InputCost = inputMTokens * model.InputPrice
 	breakdown.OutputCost = outputMTokens * model.OutputPrice
 	breakdown.TotalCost = breakdown.InputCost + breakdown.OutputCost
-	breakdown.CostDetails.BillingMode = "standard"
+	breakdown.CostDetails.BillingMode = StandardBilling.String()
+
+	// 推理 Token 另行計價（未設定價格時不計）
+	if breakdown.TokenCounts.Reasoning > 0 && model.ReasoningPrice > 0 {
+		reasoningMTokens := float64(breakdown.TokenCounts.Reasoning) / 1_000_000
+		breakdown.ReasoningCost = reasoningMTokens * model.ReasoningPrice
+		breakdown.TotalCost += breakdown.ReasoningCost
+		breakdown.CostDetails.ReasoningRate = model.ReasoningPrice
+	}
 
 	return nil
 }
@@ -209,7 +375,7 @@ func (cc *CostCalculatorImpl) calculateCacheCost(breakdown *types.CostBreakdown,
 	// 更新成本詳細資訊
 	breakdown.CostDetails.CacheReadRate = model.CacheRead
 	breakdown.CostDetails.CacheWriteRate = model.CacheWrite
-	breakdown.CostDetails.BillingMode = "cache"
+	breakdown.CostDetails.BillingMode = CacheBilling.String()
 
 	return nil
 }
@@ -221,16 +387,30 @@ func (cc *CostCalculatorImpl) calculateBatchCost(breakdown *types.CostBreakdown,
 		return err
 	}
 
-	// 應用批次折扣
+	// 依適用範圍應用批次折扣（推理成本隨輸出折扣）
 	if options.IsBatch && model.BatchDiscount > 0 {
+		discountInput, discountOutput := batchDiscountTargets(model.BatchDiscountScope)
+		discounted := 0.0
+		if discountInput {
+			discounted += breakdown.InputCost
+		}
+		if discountOutput {
+			discounted += breakdown.OutputCost + breakdown.ReasoningCost
+		}
+
 		discountMultiplier := 1.0 - model.BatchDiscount
-		breakdown.BatchDiscount = breakdown.TotalCost * model.BatchDiscount
-		breakdown.InputCost *= discountMultiplier
-		breakdown.OutputCost *= discountMultiplier
-		breakdown.TotalCost *= discountMultiplier
+		breakdown.BatchDiscount = discounted * model.BatchDiscount
+		if discountInput {
+			breakdown.InputCost *= discountMultiplier
+		}
+		if discountOutput {
+			breakdown.OutputCost *= discountMultiplier
+			breakdown.ReasoningCost *= discountMultiplier
+		}
+		breakdown.TotalCost -= breakdown.BatchDiscount
 
 		breakdown.CostDetails.DiscountRate = model.BatchDiscount
-		breakdown.CostDetails.BillingMode = "batch"
+		breakdown.CostDetails.BillingMode = BatchBilling.String()
 	}
 
 	return nil
@@ -242,6 +422,67 @@ func (cc *CostCalculatorImpl) CalculateCostWithOptions(inputTokens, outputTokens
 	return cc.CalculateDetailedCost(inputTokens, outputTokens, model, options)
 }
 
+// billingCandidate 比較計費模式時的候選計算參數
+type billingCandidate struct {
+	mode        BillingMode
+	inputTokens int
+	options     *CostOptions
+}
+
+// CheapestBillingMode 比較標準、快取與批次（batchEligible 時）計費模式，回傳成本最低的模式及其成本分解。
+// 標準與批次模式下快取讀寫 Token 以一般輸入計價；成本相同時依標準、快取、批次的順序優先。結果不記入成本追蹤。
+func (cc *CostCalculatorImpl) CheapestBillingMode(inputTokens, outputTokens, cacheRead, cacheWrite int, model string, batchEligible bool) (BillingMode, *types.CostBreakdown, error) {
+	cc.mutex.RLock()
+	defer cc.mutex.RUnlock()
+
+	if cacheRead < 0 || cacheWrite < 0 {
+		return StandardBilling, nil, fmt.Errorf("cache token counts cannot be negative: read=%d, write=%d", cacheRead, cacheWrite)
+	}
+
+	uncachedInput := inputTokens + cacheRead + cacheWrite
+	candidates := []billingCandidate{
+		{StandardBilling, uncachedInput, &CostOptions{Mode: StandardBilling}},
+	}
+	if cacheRead > 0 || cacheWrite > 0 {
+		candidates = append(candidates, billingCandidate{CacheBilling, inputTokens,
+			&CostOptions{Mode: CacheBilling, CacheReadTokens: cacheRead, CacheWriteTokens: cacheWrite}})
+	}
+	if batchEligible {
+		candidates = append(candidates, billingCandidate{BatchBilling, uncachedInput,
+			&CostOptions{Mode: BatchBilling, IsBatch: true}})
+	}
+
+	bestMode := StandardBilling
+	var best *types.CostBreakdown
+	for _, candidate := range candidates {
+		breakdown, err := cc.computeDetailedCost(candidate.inputTokens, outputTokens, model, candidate.options)
+		if err != nil {
+			return StandardBilling, nil, fmt.Errorf("failed to evaluate %s billing: %w", candidate.mode, err)
+		}
+		if best == nil || breakdown.TotalCost < best.TotalCost {
+			bestMode = candidate.mode
+			best = breakdown
+		}
+	}
+
+	return bestMode, best, nil
+}
+
+// CostFromDistribution 依輸入比例將 Token 分佈的總數拆分為輸入/輸出並計算成本
+func (cc *CostCalculatorImpl) CostFromDistribution(dist *types.TokenDistribution, inputFraction float64, model string) (*types.CostBreakdown, error) {
+	if dist == nil {
+		return nil, fmt.Errorf("token distribution cannot be nil")
+	}
+
+	if math.IsNaN(inputFraction) || inputFraction < 0 || inputFraction > 1 {
+		return nil, fmt.Errorf("input fraction must be between 0 and 1: %v", inputFraction)
+	}
+
+	inputTokens, outputTokens := SplitTotalTokens(dist.TotalTokens, inputFraction)
+
+	return cc.CalculateCost(inputTokens, outputTokens, model)
+}
+
 // GetPricingInfo 取得定價資訊（實作 CostCalculator 介面）
 func (cc *CostCalculatorImpl) GetPricingInfo(model string) (*types.PricingModel, error) {
 	cc.mutex.RLock()
@@ -275,8 +516,37 @@ func (cc *CostCalculatorImpl) CalculateOptimizationSavings(records []types.Usage
 		}, nil
 	}
 
-	optimizer := NewOptimizer(cc.pricingEngine)
-	return optimizer.AnalyzeAndSuggest(records)
+	return cc.optimizer.AnalyzeAndSuggest(records)
+}
+
+// SetOptimizationThresholds 設定優化建議閾值（零或無效值沿用預設值）
+func (cc *CostCalculatorImpl) SetOptimizationThresholds(cacheThreshold, batchThreshold int, confidenceMin, minSaving float64) {
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	cc.optimizer.SetThresholds(cacheThreshold, batchThreshold, confidenceMin, minSaving)
+}
+
+// SetOptimizationConfidenceFuncs 設定優化建議的自訂信心度函式（nil 表示使用預設實作）
+func (cc *CostCalculatorImpl) SetOptimizationConfidenceFuncs(
+	cache func(ActivityStats, *OptimizationContext) float64,
+	batch func(UsagePattern, *OptimizationContext) float64,
+	modelSwitch func(types.ActivityType, ActivityStats) float64,
+) {
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	cc.optimizer.CacheConfidenceFunc = cache
+	cc.optimizer.BatchConfidenceFunc = batch
+	cc.optimizer.ModelSwitchConfidenceFunc = modelSwitch
+}
+
+// GetOptimizationThresholds 取得目前的優化建議閾值
+func (cc *CostCalculatorImpl) GetOptimizationThresholds() map[string]interface{} {
+	cc.mutex.RLock()
+	defer cc.mutex.RUnlock()
+
+	return cc.optimizer.GetThresholds()
 }
 
 // AnalyzeCostTrends 分析成本趨勢（新增功能）
@@ -288,43 +558,56 @@ func (cc *CostCalculatorImpl) AnalyzeCostTrends(records []types.UsageRecord, tim
 		return nil, fmt.Errorf("no usage records provided")
 	}
 
+	return buildCostTrends(timeRange, cc.costDataPointsLocked(records, timeRange)), nil
+}
+
+// costDataPointsLocked 按時間分組並計算每個時間點的成本（呼叫者須持有讀取鎖，結果未排序）
+func (cc *CostCalculatorImpl) costDataPointsLocked(records []types.UsageRecord, timeRange string) []types.CostDataPoint {
 	// 按時間分組記錄
 	groupedRecords := cc.groupRecordsByTime(records, timeRange)
 
-	// 計算趨勢
-	trends := &types.CostTrendAnalysis{
-		TimeRange:   timeRange,
-		DataPoints:  make([]types.CostDataPoint, 0),
-		TotalCost:   0,
-		AverageCost: 0,
-		GrowthRate:  0,
-		Predictions: make([]types.CostPrediction, 0),
-	}
-
 	// 計算每個時間點的成本
+	dataPoints := make([]types.CostDataPoint, 0, len(groupedRecords))
 	for timeKey, timeRecords := range groupedRecords {
-		totalCost := 0.0
-		totalTokens := 0
+		dataPoint := types.CostDataPoint{
+			Timestamp:   timeKey,
+			RecordCount: len(timeRecords),
+		}
 
 		for _, record := range timeRecords {
 			// 計算該記錄的成本
-			breakdown, err := cc.CalculateCost(record.Tokens.Input, record.Tokens.Output, record.Cost.PricingModel)
+			inputTokens, outputTokens := cc.recordTokensLocked(record)
+			breakdown, err := cc.calculateCostLocked(inputTokens, outputTokens, cc.recordModelLocked(record))
 			if err != nil {
 				continue
 			}
-			totalCost += breakdown.TotalCost
-			totalTokens += record.Tokens.Total
+			dataPoint.Cost += breakdown.TotalCost
+			dataPoint.TokenCount += record.Tokens.Total
 		}
 
-		dataPoint := types.CostDataPoint{
-			Timestamp:   timeKey,
-			Cost:        totalCost,
-			TokenCount:  totalTokens,
-			RecordCount: len(timeRecords),
-		}
+		dataPoints = append(dataPoints, dataPoint)
+	}
+
+	return dataPoints
+}
+
+// buildCostTrends 由時間資料點建立趨勢分析（資料點依時間排序）
+func buildCostTrends(timeRange string, dataPoints []types.CostDataPoint) *types.CostTrendAnalysis {
+	sort.Slice(dataPoints, func(i, j int) bool {
+		return dataPoints[i].Timestamp.Before(dataPoints[j].Timestamp)
+	})
+
+	trends := &types.CostTrendAnalysis{
+		TimeRange:   timeRange,
+		DataPoints:  dataPoints,
+		TotalCost:   0,
+		AverageCost: 0,
+		GrowthRate:  0,
+		Predictions: make([]types.CostPrediction, 0),
+	}
 
-		trends.DataPoints = append(trends.DataPoints, dataPoint)
-		trends.TotalCost += totalCost
+	for _, dataPoint := range dataPoints {
+		trends.TotalCost += dataPoint.Cost
 	}
 
 	// 計算平均成本
@@ -342,12 +625,15 @@ func (cc *CostCalculatorImpl) AnalyzeCostTrends(records []types.UsageRecord, tim
 	}
 
 	// 生成預測
-	trends.Predictions = cc.generateCostPredictions(trends.DataPoints)
+	trends.Predictions = generateCostPredictions(trends.DataPoints)
 
-	return trends, nil
+	return trends
 }
 
 // GenerateCostReport 生成成本報告（新增功能）
+//
+// 記錄成本以其 Cost.Currency（未標示為 USD）計價。記錄幣別不同時依 options.FXRates 換算為報告幣別，
+// 缺少匯率時回傳列出所有幣別的錯誤，避免直接加總不同幣別的金額。
 func (cc *CostCalculatorImpl) GenerateCostReport(records []types.UsageRecord, options *types.ReportOptions) (*types.CostReport, error) {
 	cc.mutex.RLock()
 	defer cc.mutex.RUnlock()
@@ -356,84 +642,24 @@ func (cc *CostCalculatorImpl) GenerateCostReport(records []types.UsageRecord, op
 		return nil, fmt.Errorf("no usage records provided")
 	}
 
-	report := &types.CostReport{
-		GeneratedAt:  time.Now(),
-		TimeRange:    options.TimeRange,
-		TotalRecords: len(records),
-		Summary:      types.CostSummary{},
-		ByActivity:   make(map[types.ActivityType]types.CostSummary),
-		ByModel:      make(map[string]types.CostSummary),
-		Optimization: &types.OptimizationSuggestions{},
-		Trends:       &types.CostTrendAnalysis{},
+	if options != nil && options.Deduplicate {
+		records = DeduplicateRecordsBy(records, cc.recordKeyFunc)
 	}
 
-	// 計算總體摘要
-	totalCost := 0.0
-	totalTokens := 0
-	modelUsage := make(map[string]int)
-	activityUsage := make(map[types.ActivityType]int)
-
-	for _, record := range records {
-		// 計算成本
-		breakdown, err := cc.CalculateCost(record.Tokens.Input, record.Tokens.Output, record.Cost.PricingModel)
-		if err != nil {
-			continue
-		}
-
-		totalCost += breakdown.TotalCost
-		totalTokens += record.Tokens.Total
-		modelUsage[record.Cost.PricingModel]++
-		activityUsage[record.Activity.Type]++
+	breakdowns, _ := cc.calculateCostBatchLocked(records)
+	return cc.buildCostReportLocked(records, options, breakdowns)
+}
 
-		// 按活動類型分組
-		if _, exists := report.ByActivity[record.Activity.Type]; !exists {
-			report.ByActivity[record.Activity.Type] = types.CostSummary{}
-		}
-		activitySummary := report.ByActivity[record.Activity.Type]
-		activitySummary.TotalCost += breakdown.TotalCost
-		activitySummary.TotalTokens += record.Tokens.Total
-		activitySummary.RecordCount++
-		report.ByActivity[record.Activity.Type] = activitySummary
-
-		// 按模型分組
-		if _, exists := report.ByModel[record.Cost.PricingModel]; !exists {
-			report.ByModel[record.Cost.PricingModel] = types.CostSummary{}
-		}
-		modelSummary := report.ByModel[record.Cost.PricingModel]
-		modelSummary.TotalCost += breakdown.TotalCost
-		modelSummary.TotalTokens += record.Tokens.Total
-		modelSummary.RecordCount++
-		report.ByModel[record.Cost.PricingModel] = modelSummary
-	}
-
-	// 設定總體摘要
-	report.Summary.TotalCost = totalCost
-	report.Summary.TotalTokens = totalTokens
-	report.Summary.RecordCount = len(records)
-	if totalTokens > 0 {
-		report.Summary.AverageCostPerToken = totalCost / float64(totalTokens) * 1_000_000 // 每百萬 token 的成本
-	}
-
-	// 計算平均值
-	for activityType, summary := range report.ByActivity {
-		if summary.RecordCount > 0 {
-			summary.AverageCostPerRecord = summary.TotalCost / float64(summary.RecordCount)
-			if summary.TotalTokens > 0 {
-				summary.AverageCostPerToken = summary.TotalCost / float64(summary.TotalTokens) * 1_000_000
-			}
-			report.ByActivity[activityType] = summary
-		}
+// buildCostReportLocked 依記錄順序彙總已計算的成本並產生報告（呼叫者須持有讀取鎖）
+func (cc *CostCalculatorImpl) buildCostReportLocked(records []types.UsageRecord, options *types.ReportOptions, breakdowns []*types.CostBreakdown) (*types.CostReport, error) {
+	aggregator := newCostAggregator(cc, options)
+	for i, record := range records {
+		aggregator.addWithBreakdown(record, breakdowns[i])
 	}
-
-	for model, summary := range report.ByModel {
-		if summary.RecordCount > 0 {
-			summary.AverageCostPerRecord = summary.TotalCost / float64(summary.RecordCount)
-			if summary.TotalTokens > 0 {
-				summary.AverageCostPerToken = summary.TotalCost / float64(summary.TotalTokens) * 1_000_000
-			}
-			report.ByModel[model] = summary
-		}
+	if err := aggregator.currencyErr(); err != nil {
+		return nil, err
 	}
+	report := aggregator.finalize()
 
 	// 生成優化建議
 	optimization, err := cc.CalculateOptimizationSavings(records)
@@ -441,21 +667,15 @@ func (cc *CostCalculatorImpl) GenerateCostReport(records []types.UsageRecord, op
 		report.Optimization = optimization
 	}
 
-	// 生成趨勢分析
-	trends, err := cc.AnalyzeCostTrends(records, "daily")
-	if err == nil {
-		report.Trends = trends
-	}
-
 	return report, nil
 }
 
-// LoadPricingModels 載入定價模型（實作 CostCalculator 介面）
+// LoadPricingModels 載入定價模型（實作 CostCalculator 介面），支援 JSON 與 YAML 格式。
+// 模型由定價引擎在其鎖內重建，與 PricingEngine.LoadFromConfig 相同地驗證模型、保留所有欄位並觸發重新載入回呼
 func (cc *CostCalculatorImpl) LoadPricingModels(configPath string) error {
 	cc.mutex.Lock()
-	defer cc.mutex.Unlock()
-
 	cc.configPath = configPath
+	cc.mutex.Unlock()
 
 	// 檢查文件是否存在
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
@@ -464,36 +684,34 @@ func (cc *CostCalculatorImpl) LoadPricingModels(configPath string) error {
 		return nil
 	}
 
-	// 讀取配置文件
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		return fmt.Errorf("failed to read config file: %w", err)
-	}
-
-	var config ConfigData
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return fmt.Errorf("failed to parse config file: %w", err)
-	}
-
-	// 載入定價模型
-	cc.pricingEngine.models = make(map[string]*types.PricingModel)
-	for modelName, pricing := range config.Pricing {
-		cc.pricingEngine.AddPricingModel(modelName, &types.PricingModel{
-			Name:          modelName,
-			InputPrice:    pricing.Input,
-			OutputPrice:   pricing.Output,
-			CacheRead:     pricing.CacheRead,
-			CacheWrite:    pricing.CacheWrite,
-			BatchDiscount: pricing.BatchDiscount,
-		})
+	// 回呼可能查詢計算器，因此載入期間不持有計算器的鎖
+	if err := cc.pricingEngine.LoadFromConfig(configPath); err != nil {
+		return fmt.Errorf("failed to load config file: %w", err)
 	}
 
+	cc.mutex.Lock()
 	cc.lastConfigUpdate = time.Now()
-	log.Printf("Loaded %d pricing models from config", len(config.Pricing))
+	cc.mutex.Unlock()
 
 	return nil
 }
 
+// SetDefaultModel 設定未指定模型時使用的預設模型
+func (cc *CostCalculatorImpl) SetDefaultModel(name string) error {
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	return cc.pricingEngine.SetDefaultModel(name)
+}
+
+// GetDefaultModel 取得目前的預設模型
+func (cc *CostCalculatorImpl) GetDefaultModel() string {
+	cc.mutex.RLock()
+	defer cc.mutex.RUnlock()
+
+	return cc.pricingEngine.GetDefaultModel()
+}
+
 // GetSupportedModels 取得支援的模型（實作 CostCalculator 介面）
 func (cc *CostCalculatorImpl) GetSupportedModels() []string {
 	cc.mutex.RLock()
@@ -510,12 +728,66 @@ func (cc *CostCalculatorImpl) GetSessionCost(sessionID string) float64 {
 	return cc.sessionCosts[sessionID]
 }
 
+// ExternalCostModel 外部貢獻的成本（AddSessionCost）在每日成本中歸屬的模型鍵
+const ExternalCostModel = "external"
+
+// AddSessionCost 累加外部程序計算的會話成本，並計入當日成本（模型鍵為 ExternalCostModel）。
+// sessionID 為空時只計入每日成本；負值、NaN 與無限值會被忽略。
+func (cc *CostCalculatorImpl) AddSessionCost(sessionID string, cost float64) {
+	if cost < 0 || math.IsNaN(cost) || math.IsInf(cost, 0) {
+		return
+	}
+
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	cc.trackCost(&types.CostBreakdown{TotalCost: cost, PricingModel: ExternalCostModel}, &CostOptions{SessionID: sessionID})
+}
+
 // GetDailyCost 取得每日成本
 func (cc *CostCalculatorImpl) GetDailyCost(date string) float64 {
 	cc.mutex.RLock()
 	defer cc.mutex.RUnlock()
 
-	return cc.dailyCosts[date]
+	return cc.dailyTotal(date)
+}
+
+// GetDailyCostByModel 取得指定日期各模型的成本
+func (cc *CostCalculatorImpl) GetDailyCostByModel(date string) map[string]float64 {
+	cc.mutex.RLock()
+	defer cc.mutex.RUnlock()
+
+	byModel := make(map[string]float64, len(cc.dailyCosts[date]))
+	for model, cost := range cc.dailyCosts[date] {
+		byModel[model] = cost
+	}
+	return byModel
+}
+
+// GetMonthlyCostByModel 取得指定月份（YYYY-MM）各模型的成本
+func (cc *CostCalculatorImpl) GetMonthlyCostByModel(month string) map[string]float64 {
+	cc.mutex.RLock()
+	defer cc.mutex.RUnlock()
+
+	byModel := make(map[string]float64)
+	for date, costs := range cc.dailyCosts {
+		if !strings.HasPrefix(date, month+"-") {
+			continue
+		}
+		for model, cost := range costs {
+			byModel[model] += cost
+		}
+	}
+	return byModel
+}
+
+// dailyTotal 計算指定日期所有模型的成本總和（呼叫者須持有讀取鎖）
+func (cc *CostCalculatorImpl) dailyTotal(date string) float64 {
+	total := 0.0
+	for _, cost := range cc.dailyCosts[date] {
+		total += cost
+	}
+	return total
 }
 
 // GetDailyCostSummary 取得每日成本摘要
@@ -528,16 +800,41 @@ func (cc *CostCalculatorImpl) GetDailyCostSummary(days int) map[string]float64 {
 
 	for i := 0; i < days; i++ {
 		date := now.AddDate(0, 0, -i).Format("2006-01-02")
-		if cost, exists := cc.dailyCosts[date]; exists {
-			summary[date] = cost
-		} else {
-			summary[date] = 0
-		}
+		summary[date] = cc.dailyTotal(date)
 	}
 
 	return summary
 }
 
+// GetCostForTimeRange 計算時間範圍內（含起訖時間，零值表示不限制）記錄的總成本與 token 數
+func (cc *CostCalculatorImpl) GetCostForTimeRange(records []types.UsageRecord, start, end time.Time) (float64, int, error) {
+	if !start.IsZero() && !end.IsZero() && end.Before(start) {
+		return 0, 0, fmt.Errorf("end time %s is before start time %s", end.Format(time.RFC3339), start.Format(time.RFC3339))
+	}
+
+	totalCost := 0.0
+	totalTokens := 0
+
+	for _, record := range records {
+		if !start.IsZero() && record.Timestamp.Before(start) {
+			continue
+		}
+		if !end.IsZero() && record.Timestamp.After(end) {
+			continue
+		}
+
+		inputTokens, outputTokens := cc.recordTokens(record)
+		breakdown, err := cc.CalculateCost(inputTokens, outputTokens, cc.recordModel(record))
+		if err != nil {
+			continue
+		}
+		totalCost += breakdown.TotalCost
+		totalTokens += record.Tokens.Total
+	}
+
+	return totalCost, totalTokens, nil
+}
+
 // EstimateMonthlyBudget 估算月度預算
 func (cc *CostCalculatorImpl) EstimateMonthlyBudget(dailyTokens int, model string) (float64, error) {
 	cc.mutex.RLock()
@@ -554,6 +851,41 @@ func (cc *CostCalculatorImpl) ComparePricingModels(inputTokens, outputTokens int
 	return cc.pricingEngine.ComparePricingModels(inputTokens, outputTokens)
 }
 
+// GetCheapestModel 取得總成本最低的定價模型，成本相同時取名稱排序最前者
+func (cc *CostCalculatorImpl) GetCheapestModel(inputTokens, outputTokens int) (string, *types.CostBreakdown, error) {
+	return cc.extremeModel(inputTokens, outputTokens, func(a, b float64) bool { return a < b })
+}
+
+// GetMostExpensiveModel 取得總成本最高的定價模型，成本相同時取名稱排序最前者
+func (cc *CostCalculatorImpl) GetMostExpensiveModel(inputTokens, outputTokens int) (string, *types.CostBreakdown, error) {
+	return cc.extremeModel(inputTokens, outputTokens, func(a, b float64) bool { return a > b })
+}
+
+// extremeModel 比較所有定價模型，回傳 better 判定最佳的模型
+func (cc *CostCalculatorImpl) extremeModel(inputTokens, outputTokens int, better func(a, b float64) bool) (string, *types.CostBreakdown, error) {
+	comparison, err := cc.ComparePricingModels(inputTokens, outputTokens)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(comparison) == 0 {
+		return "", nil, fmt.Errorf("no pricing models available")
+	}
+
+	models := make([]string, 0, len(comparison))
+	for model := range comparison {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+
+	best := models[0]
+	for _, model := range models[1:] {
+		if better(comparison[model].TotalCost, comparison[best].TotalCost) {
+			best = model
+		}
+	}
+	return best, comparison[best], nil
+}
+
 // ReloadConfig 重新載入配置（用於熱更新）
 func (cc *CostCalculatorImpl) ReloadConfig() error {
 	if cc.configPath == "" {
@@ -584,17 +916,30 @@ func (cc *CostCalculatorImpl) ClearDailyCosts() {
 	cc.mutex.Lock()
 	defer cc.mutex.Unlock()
 
-	cc.dailyCosts = make(map[string]float64)
+	cc.dailyCosts = make(map[string]map[string]float64)
 }
 
+// maxTokenCount 單次計算允許的最大 token 數
+const maxTokenCount = 10_000_000
+
 // validateInput 驗證輸入參數
 func (cc *CostCalculatorImpl) validateInput(inputTokens, outputTokens int, model string, options *CostOptions) error {
 	if inputTokens < 0 || outputTokens < 0 {
 		return fmt.Errorf("token counts cannot be negative: input=%d, output=%d", inputTokens, outputTokens)
 	}
 
-	if inputTokens > 10_000_000 || outputTokens > 10_000_000 {
-		return fmt.Errorf("token counts exceed maximum limit (10M): input=%d, output=%d", inputTokens, outputTokens)
+	if inputTokens > maxTokenCount || outputTokens > maxTokenCount {
+		appErr := errors.Newf(errors.ErrCodeTokenCountExceeded,
+			"token counts exceed maximum limit (10M): input=%d, output=%d", inputTokens, outputTokens)
+		return appErr.WithContext(errors.ErrorContext{
+			Operation: "validate_input",
+			Component: "cost_calculator",
+			Parameters: map[string]interface{}{
+				"input_tokens":  inputTokens,
+				"output_tokens": outputTokens,
+				"limit":         maxTokenCount,
+			},
+		})
 	}
 
 	if model == "" {
@@ -606,6 +951,10 @@ func (cc *CostCalculatorImpl) validateInput(inputTokens, outputTokens int, model
 			return fmt.Errorf("cache token counts cannot be negative: read=%d, write=%d",
 				options.CacheReadTokens, options.CacheWriteTokens)
 		}
+
+		if options.ReasoningTokens < 0 {
+			return fmt.Errorf("reasoning token count cannot be negative: %d", options.ReasoningTokens)
+		}
 	}
 
 	return nil
@@ -616,34 +965,44 @@ func (cc *CostCalculatorImpl) groupRecordsByTime(records []types.UsageRecord, ti
 	grouped := make(map[time.Time][]types.UsageRecord)
 
 	for _, record := range records {
-		var timeKey time.Time
-
-		switch timeRange {
-		case "hourly":
-			timeKey = record.Timestamp.Truncate(time.Hour)
-		case "daily":
-			timeKey = record.Timestamp.Truncate(24 * time.Hour)
-		case "weekly":
-			// 取得週的開始時間（週一）
-			weekday := int(record.Timestamp.Weekday())
-			if weekday == 0 {
-				weekday = 7 // 將週日從0改為7
-			}
-			timeKey = record.Timestamp.AddDate(0, 0, -(weekday - 1)).Truncate(24 * time.Hour)
-		case "monthly":
-			timeKey = time.Date(record.Timestamp.Year(), record.Timestamp.Month(), 1, 0, 0, 0, 0, record.Timestamp.Location())
-		default:
-			timeKey = record.Timestamp.Truncate(24 * time.Hour)
-		}
-
+		timeKey := timeBucketKey(record.Timestamp, timeRange)
 		grouped[timeKey] = append(grouped[timeKey], record)
 	}
 
 	return grouped
 }
 
+// timeBucketKey 取得時間戳所屬的時間區間起點
+func timeBucketKey(timestamp time.Time, timeRange string) time.Time {
+	switch timeRange {
+	case "hourly":
+		return timestamp.Truncate(time.Hour)
+	case "daily":
+		return timestamp.Truncate(24 * time.Hour)
+	case "weekly":
+		// 取得週的開始時間（週一）
+		weekday := int(timestamp.Weekday())
+		if weekday == 0 {
+			weekday = 7 // 將週日從0改為7
+		}
+		return timestamp.AddDate(0, 0, -(weekday - 1)).Truncate(24 * time.Hour)
+	case "monthly":
+		return time.Date(timestamp.Year(), timestamp.Month(), 1, 0, 0, 0, 0, timestamp.Location())
+	default:
+		return timestamp.Truncate(24 * time.Hour)
+	}
+}
+
+// predictionHorizon 趨勢分析預測的未來時間點數
+const predictionHorizon = 3
+
 // generateCostPredictions 生成成本預測
-func (cc *CostCalculatorImpl) generateCostPredictions(dataPoints []types.CostDataPoint) []types.CostPrediction {
+func generateCostPredictions(dataPoints []types.CostDataPoint) []types.CostPrediction {
+	return generateCostPredictionsN(dataPoints, predictionHorizon)
+}
+
+// generateCostPredictionsN 生成未來 horizon 個時間點的成本預測
+func generateCostPredictionsN(dataPoints []types.CostDataPoint, horizon int) []types.CostPrediction {
 	if len(dataPoints) < 2 {
 		return []types.CostPrediction{}
 	}
@@ -668,10 +1027,10 @@ func (cc *CostCalculatorImpl) generateCostPredictions(dataPoints []types.CostDat
 			avgGrowthRate := totalGrowth / float64(validPeriods)
 			lastDataPoint := dataPoints[len(dataPoints)-1]
 
-			// 預測未來3個時間點
-			for i := 1; i <= 3; i++ {
+			// 預測未來 horizon 個時間點
+			for i := 1; i <= horizon; i++ {
 				predictedCost := lastDataPoint.Cost * (1 + avgGrowthRate*float64(i))
-				confidence := 1.0 - (float64(i) * 0.2) // 時間越遠信心度越低
+				confidence := math.Max(0, 1.0-(float64(i)*0.2)) // 時間越遠信心度越低
 
 				prediction := types.CostPrediction{
 					Date:          lastDataPoint.Timestamp.AddDate(0, 0, i),
@@ -710,7 +1069,8 @@ func (cc *CostCalculatorImpl) CalculateCostEfficiency(records []types.UsageRecor
 	})
 
 	for _, record := range records {
-		breakdown, err := cc.CalculateCost(record.Tokens.Input, record.Tokens.Output, record.Cost.PricingModel)
+		inputTokens, outputTokens := cc.recordTokensLocked(record)
+		breakdown, err := cc.calculateCostLocked(inputTokens, outputTokens, cc.recordModelLocked(record))
 		if err != nil {
 			continue
 		}
@@ -750,38 +1110,67 @@ func (cc *CostCalculatorImpl) CalculateCostEfficiency(records []types.UsageRecor
 
 // GetStatistics 取得統計資訊
 func (cc *CostCalculatorImpl) GetStatistics() map[string]interface{} {
+	typed := cc.GetStatisticsTyped()
+
+	stats := make(map[string]interface{})
+	stats["total_sessions"] = typed.TotalSessions
+	stats["total_session_cost"] = typed.TotalSessionCost
+	stats["total_days"] = typed.TotalDays
+	stats["total_daily_cost"] = typed.TotalDailyCost
+	stats["supported_models"] = typed.SupportedModels
+	stats["last_config_update"] = typed.LastConfigUpdate.Format(time.RFC3339)
+
+	if typed.TotalSessions > 0 {
+		stats["average_session_cost"] = typed.AverageSessionCost
+	}
+
+	if typed.TotalDays > 0 {
+		stats["average_daily_cost"] = typed.AverageDailyCost
+	}
+
+	return stats
+}
+
+// Statistics 成本計算器統計資訊（可穩定序列化為 JSON）
+type Statistics struct {
+	TotalSessions      int       `json:"total_sessions"`
+	TotalSessionCost   float64   `json:"total_session_cost"`
+	AverageSessionCost float64   `json:"average_session_cost"`
+	TotalDays          int       `json:"total_days"`
+	TotalDailyCost     float64   `json:"total_daily_cost"`
+	AverageDailyCost   float64   `json:"average_daily_cost"`
+	SupportedModels    int       `json:"supported_models"`
+	LastConfigUpdate   time.Time `json:"last_config_update"`
+}
+
+// GetStatisticsTyped 取得型別化的統計資訊
+func (cc *CostCalculatorImpl) GetStatisticsTyped() Statistics {
 	cc.mutex.RLock()
 	defer cc.mutex.RUnlock()
 
-	stats := make(map[string]interface{})
+	stats := Statistics{
+		TotalSessions:    len(cc.sessionCosts),
+		TotalDays:        len(cc.dailyCosts),
+		SupportedModels:  len(cc.pricingEngine.GetSupportedModels()),
+		LastConfigUpdate: cc.lastConfigUpdate,
+	}
 
 	// 會話統計
-	totalSessions := len(cc.sessionCosts)
-	totalSessionCost := 0.0
 	for _, cost := range cc.sessionCosts {
-		totalSessionCost += cost
+		stats.TotalSessionCost += cost
 	}
 
 	// 每日統計
-	totalDays := len(cc.dailyCosts)
-	totalDailyCost := 0.0
-	for _, cost := range cc.dailyCosts {
-		totalDailyCost += cost
+	for date := range cc.dailyCosts {
+		stats.TotalDailyCost += cc.dailyTotal(date)
 	}
 
-	stats["total_sessions"] = totalSessions
-	stats["total_session_cost"] = totalSessionCost
-	stats["total_days"] = totalDays
-	stats["total_daily_cost"] = totalDailyCost
-	stats["supported_models"] = len(cc.pricingEngine.models)
-	stats["last_config_update"] = cc.lastConfigUpdate.Format(time.RFC3339)
-
-	if totalSessions > 0 {
-		stats["average_session_cost"] = totalSessionCost / float64(totalSessions)
+	if stats.TotalSessions > 0 {
+		stats.AverageSessionCost = stats.TotalSessionCost / float64(stats.TotalSessions)
 	}
 
-	if totalDays > 0 {
-		stats["average_daily_cost"] = totalDailyCost / float64(totalDays)
+	if stats.TotalDays > 0 {
+		stats.AverageDailyCost = stats.TotalDailyCost / float64(stats.TotalDays)
 	}
 
 	return stats
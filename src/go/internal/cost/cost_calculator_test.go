@@ -1,10 +1,12 @@
 package cost
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
+	"token-monitor/internal/mathutil"
 	"token-monitor/internal/types"
 	"gopkg.in/yaml.v3"
 )
@@ -101,7 +103,7 @@ func TestCalculateCost(t *testing.T) {
 				t.Fatal("Expected non-nil result")
 			}
 			
-			if abs(result.TotalCost-tt.expectedCost) > 0.0001 {
+			if mathutil.AbsFloat(result.TotalCost-tt.expectedCost) > 0.0001 {
 				t.Errorf("Expected cost %.6f, got %.6f", tt.expectedCost, result.TotalCost)
 			}
 		})
@@ -367,6 +369,51 @@ func TestCalculateOptimizationSavings(t *testing.T) {
 	}
 }
 
+// TestSetOptimizationThresholds 測試設定優化建議閾值
+func TestSetOptimizationThresholds(t *testing.T) {
+	calculator := NewCostCalculator()
+
+	// 3 筆高成本低效率的編碼記錄：工作流程建議信心度為 0.6，預設會被過濾
+	records := make([]types.UsageRecord, 0, 3)
+	for i := 0; i < 3; i++ {
+		record := types.UsageRecord{
+			Timestamp: time.Now(),
+			Activity:  types.Activity{Type: types.ActivityCoding},
+		}
+		record.Tokens.Total = 1000
+		record.Cost.Total = 0.5
+		records = append(records, record)
+	}
+
+	result, err := calculator.CalculateOptimizationSavings(records)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(result.Suggestions) != 0 {
+		t.Errorf("Expected no suggestions with default confidence, got %d", len(result.Suggestions))
+	}
+
+	calculator.SetOptimizationThresholds(0, 0, 0.55, 0)
+
+	result, err = calculator.CalculateOptimizationSavings(records)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(result.Suggestions) != 1 || result.Suggestions[0].Type != "workflow" {
+		t.Errorf("Expected a single workflow suggestion after lowering confidence, got %+v", result.Suggestions)
+	}
+
+	// 閾值應保留在重複使用的優化器上，零值不覆蓋既有設定
+	calculator.SetOptimizationThresholds(2000, 0, 0, 0)
+	thresholds := calculator.GetOptimizationThresholds()
+	if thresholds["confidence_min"] != 0.55 {
+		t.Errorf("Expected confidence_min to persist as 0.55, got %v", thresholds["confidence_min"])
+	}
+	if thresholds["cache_threshold"] != 2000 {
+		t.Errorf("Expected cache_threshold 2000, got %v", thresholds["cache_threshold"])
+	}
+}
+
 // TestGetSupportedModels 測試取得支援的模型
 func TestGetSupportedModels(t *testing.T) {
 	calculator := NewCostCalculator()
@@ -477,6 +524,48 @@ func TestComparePricingModels(t *testing.T) {
 	}
 }
 
+// TestCheapestAndMostExpensiveModel 測試取得成本最低與最高的模型
+func TestCheapestAndMostExpensiveModel(t *testing.T) {
+	calculator := NewCostCalculator()
+
+	cheapest, cheapestCost, err := calculator.GetCheapestModel(1000, 500)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expensive, expensiveCost, err := calculator.GetMostExpensiveModel(1000, 500)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	comparison, _ := calculator.ComparePricingModels(1000, 500)
+	for model, breakdown := range comparison {
+		if breakdown.TotalCost < cheapestCost.TotalCost {
+			t.Errorf("Model %s is cheaper than %s", model, cheapest)
+		}
+		if breakdown.TotalCost > expensiveCost.TotalCost {
+			t.Errorf("Model %s is more expensive than %s", model, expensive)
+		}
+	}
+	if cheapest != "claude-haiku-3.5" || expensive != "claude-opus-4.0" {
+		t.Errorf("Expected haiku cheapest and opus most expensive, got %s and %s", cheapest, expensive)
+	}
+
+	// 成本相同時依名稱決定
+	haiku, err := calculator.pricingEngine.GetPricingModel("claude-haiku-3.5")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	calculator.pricingEngine.AddPricingModel("a-haiku-clone", &types.PricingModel{
+		Name:        "a-haiku-clone",
+		InputPrice:  haiku.InputPrice,
+		OutputPrice: haiku.OutputPrice,
+	})
+	cheapest, clone, err := calculator.GetCheapestModel(1000, 500)
+	if err != nil || cheapest != "a-haiku-clone" || clone.TotalCost != cheapestCost.TotalCost {
+		t.Errorf("Expected tie to resolve to a-haiku-clone, got %s (%v)", cheapest, err)
+	}
+}
+
 // TestReloadConfig 測試重新載入配置
 func TestReloadConfig(t *testing.T) {
 	calculator := NewCostCalculator()
@@ -559,6 +648,99 @@ func TestGetStatistics(t *testing.T) {
 	}
 }
 
+// TestCostFromDistribution 測試由 Token 分佈計算成本
+func TestCostFromDistribution(t *testing.T) {
+	calculator := NewCostCalculator()
+	dist := &types.TokenDistribution{EnglishTokens: 800, ChineseTokens: 200, TotalTokens: 1000, Method: "estimation"}
+
+	breakdown, err := calculator.CostFromDistribution(dist, 0.25, "claude-sonnet-4.0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if breakdown.TokenCounts.Input != 250 || breakdown.TokenCounts.Output != 750 {
+		t.Errorf("Expected 250/750 split, got %d/%d", breakdown.TokenCounts.Input, breakdown.TokenCounts.Output)
+	}
+
+	expected, _ := calculator.CalculateCost(250, 750, "claude-sonnet-4.0")
+	if breakdown.TotalCost != expected.TotalCost {
+		t.Errorf("Expected cost %f, got %f", expected.TotalCost, breakdown.TotalCost)
+	}
+
+	for _, fraction := range []float64{-0.1, 1.5} {
+		if _, err := calculator.CostFromDistribution(dist, fraction, "claude-sonnet-4.0"); err == nil {
+			t.Errorf("Expected error for fraction %v", fraction)
+		}
+	}
+	if _, err := calculator.CostFromDistribution(nil, 0.5, "claude-sonnet-4.0"); err == nil {
+		t.Errorf("Expected error for nil distribution")
+	}
+}
+
+// TestGetStatisticsTyped 測試型別化統計資訊與 JSON 序列化
+func TestGetStatisticsTyped(t *testing.T) {
+	calculator := NewCostCalculator()
+
+	options := &CostOptions{
+		Mode:      StandardBilling,
+		SessionID: "test-session",
+	}
+	if _, err := calculator.CalculateCostWithOptions(1000, 500, "claude-sonnet-4.0", options); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	stats := calculator.GetStatisticsTyped()
+	legacy := calculator.GetStatistics()
+
+	if stats.TotalSessions != 1 || legacy["total_sessions"] != stats.TotalSessions {
+		t.Errorf("Expected 1 session, got typed=%d map=%v", stats.TotalSessions, legacy["total_sessions"])
+	}
+	if stats.TotalSessionCost <= 0 || legacy["total_session_cost"] != stats.TotalSessionCost {
+		t.Errorf("Expected matching session cost, got typed=%f map=%v", stats.TotalSessionCost, legacy["total_session_cost"])
+	}
+	if stats.SupportedModels != len(calculator.GetSupportedModels()) {
+		t.Errorf("Expected %d supported models, got %d", len(calculator.GetSupportedModels()), stats.SupportedModels)
+	}
+
+	data, err := json.Marshal(stats)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var decoded Statistics
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !decoded.LastConfigUpdate.Equal(stats.LastConfigUpdate) || decoded.TotalDailyCost != stats.TotalDailyCost {
+		t.Errorf("Expected statistics to round-trip through JSON, got %+v", decoded)
+	}
+}
+
+// TestSetDefaultModel 測試設定預設模型
+func TestSetDefaultModel(t *testing.T) {
+	calculator := NewCostCalculator()
+
+	if err := calculator.SetDefaultModel("claude-haiku-3.5"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if calculator.GetDefaultModel() != "claude-haiku-3.5" {
+		t.Errorf("Expected default model claude-haiku-3.5, got %s", calculator.GetDefaultModel())
+	}
+
+	breakdown, err := calculator.CalculateCost(1000, 1000, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if breakdown.PricingModel != "claude-haiku-3.5" {
+		t.Errorf("Expected empty model to use claude-haiku-3.5, got %s", breakdown.PricingModel)
+	}
+
+	if err := calculator.SetDefaultModel("unknown-model"); err == nil {
+		t.Errorf("Expected error for unknown model")
+	}
+	if calculator.GetDefaultModel() != "claude-haiku-3.5" {
+		t.Errorf("Expected default model to be unchanged after error")
+	}
+}
+
 // TestConcurrentAccess 測試併發存取
 func TestConcurrentAccess(t *testing.T) {
 	calculator := NewCostCalculator()
@@ -590,13 +772,6 @@ func TestConcurrentAccess(t *testing.T) {
 	}
 }
 
-// abs 計算絕對值
-func abs(x float64) float64 {
-	if x < 0 {
-		return -x
-	}
-	return x
-}
 
 // BenchmarkCalculateCost 基準測試成本計算
 func BenchmarkCalculateCost(b *testing.B) {
@@ -629,4 +804,44 @@ func BenchmarkCalculateCostWithOptions(b *testing.B) {
 			b.Errorf("Benchmark error: %v", err)
 		}
 	}
-}
\ No newline at end of file
+}
+
+// TestAddSessionCost 測試外部程序並行累加會話與每日成本
+func TestAddSessionCost(t *testing.T) {
+	calculator := NewCostCalculator()
+	today := time.Now().Format("2006-01-02")
+
+	done := make(chan struct{})
+	for w := 0; w < 8; w++ {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			for i := 0; i < 100; i++ {
+				calculator.AddSessionCost("shared-session", 0.25)
+			}
+		}()
+	}
+	for w := 0; w < 8; w++ {
+		<-done
+	}
+
+	if cost := calculator.GetSessionCost("shared-session"); cost != 200 {
+		t.Errorf("Expected session cost 200, got %f", cost)
+	}
+	if cost := calculator.GetDailyCostByModel(today)[ExternalCostModel]; cost != 200 {
+		t.Errorf("Expected daily external cost 200, got %f", cost)
+	}
+
+	// 與內部追蹤合併
+	if _, err := calculator.CalculateDetailedCost(1000, 1000, "claude-sonnet-4.0", &CostOptions{SessionID: "shared-session"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cost := calculator.GetSessionCost("shared-session"); cost <= 200 {
+		t.Errorf("Expected internal tracking to add to external cost, got %f", cost)
+	}
+
+	calculator.AddSessionCost("shared-session", -1)
+	calculator.AddSessionCost("", 1)
+	if cost := calculator.GetDailyCostByModel(today)[ExternalCostModel]; cost != 201 {
+		t.Errorf("Expected negative cost ignored and session-less cost counted daily, got %f", cost)
+	}
+}
@@ -0,0 +1,76 @@
+package cost
+
+import (
+	"time"
+)
+
+// SetDailyTrackingRetention 設定每日成本保留天數（含今天），超過的舊記錄會在每次更新時移除。
+// days <= 0 表示不限制（預設）。
+func (cc *CostCalculatorImpl) SetDailyTrackingRetention(days int) {
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	if days < 0 {
+		days = 0
+	}
+	cc.dailyRetentionDays = days
+	cc.pruneDailyCosts(time.Now())
+}
+
+// SetDailyTrackingEnabled 啟用或停用每日成本追蹤；停用後不再累計，既有資料可用 ClearDailyCosts 清除
+func (cc *CostCalculatorImpl) SetDailyTrackingEnabled(enabled bool) {
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	cc.dailyTrackingDisabled = !enabled
+}
+
+// IsDailyTrackingEnabled 檢查是否啟用每日成本追蹤
+func (cc *CostCalculatorImpl) IsDailyTrackingEnabled() bool {
+	cc.mutex.RLock()
+	defer cc.mutex.RUnlock()
+
+	return !cc.dailyTrackingDisabled
+}
+
+// pruneDailyCosts 移除超過保留天數的每日成本記錄
+func (cc *CostCalculatorImpl) pruneDailyCosts(now time.Time) {
+	if cc.dailyRetentionDays <= 0 {
+		return
+	}
+
+	cutoff := now.AddDate(0, 0, -(cc.dailyRetentionDays - 1)).Format("2006-01-02")
+	for date := range cc.dailyCosts {
+		// 日期格式為 YYYY-MM-DD，可直接以字串比較先後
+		if date < cutoff {
+			delete(cc.dailyCosts, date)
+		}
+	}
+}
+
+// GetRollingAverages 計算各視窗大小（天數，含今天）的每日平均成本，無記錄的日期以 0 計算。
+// 未指定視窗時預設為 7 與 30 天，視窗 <= 0 會被忽略。
+func (cc *CostCalculatorImpl) GetRollingAverages(windows ...int) map[int]float64 {
+	cc.mutex.RLock()
+	defer cc.mutex.RUnlock()
+
+	if len(windows) == 0 {
+		windows = []int{7, 30}
+	}
+
+	now := time.Now()
+	averages := make(map[int]float64, len(windows))
+	for _, window := range windows {
+		if window <= 0 {
+			continue
+		}
+
+		total := 0.0
+		for i := 0; i < window; i++ {
+			total += cc.dailyTotal(now.AddDate(0, 0, -i).Format("2006-01-02"))
+		}
+		averages[window] = total / float64(window)
+	}
+
+	return averages
+}
@@ -0,0 +1,179 @@
+package cost
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestDailyTrackingRetention 測試每日成本保留天數
+func TestDailyTrackingRetention(t *testing.T) {
+	calculator := NewCostCalculator()
+	now := time.Now()
+
+	calculator.dailyCosts[now.AddDate(0, 0, -10).Format("2006-01-02")] = map[string]float64{"claude-sonnet-4.0": 1.0}
+	calculator.dailyCosts[now.AddDate(0, 0, -2).Format("2006-01-02")] = map[string]float64{"claude-sonnet-4.0": 2.0}
+
+	// 預設不限制
+	if _, err := calculator.CalculateDetailedCost(1000, 1000, "claude-sonnet-4.0", &CostOptions{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(calculator.dailyCosts) != 3 {
+		t.Errorf("Expected 3 daily entries by default, got %d", len(calculator.dailyCosts))
+	}
+
+	calculator.SetDailyTrackingRetention(3)
+	if len(calculator.dailyCosts) != 2 {
+		t.Errorf("Expected 2 daily entries within 3 days, got %d", len(calculator.dailyCosts))
+	}
+	if calculator.GetDailyCost(now.AddDate(0, 0, -10).Format("2006-01-02")) != 0 {
+		t.Errorf("Expected old entry to be pruned")
+	}
+
+	// 更新時也會移除超過保留天數的記錄
+	calculator.dailyCosts[now.AddDate(0, 0, -5).Format("2006-01-02")] = map[string]float64{"claude-sonnet-4.0": 1.0}
+	if _, err := calculator.CalculateDetailedCost(1000, 1000, "claude-sonnet-4.0", &CostOptions{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(calculator.dailyCosts) != 2 {
+		t.Errorf("Expected old entries to be pruned on update, got %d entries", len(calculator.dailyCosts))
+	}
+}
+
+// TestDisableDailyTracking 測試停用每日成本追蹤
+func TestDisableDailyTracking(t *testing.T) {
+	calculator := NewCostCalculator()
+	calculator.SetDailyTrackingEnabled(false)
+
+	if calculator.IsDailyTrackingEnabled() {
+		t.Errorf("Expected daily tracking to be disabled")
+	}
+	if _, err := calculator.CalculateDetailedCost(1000, 1000, "claude-sonnet-4.0", &CostOptions{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(calculator.dailyCosts) != 0 {
+		t.Errorf("Expected no daily entries when disabled, got %d", len(calculator.dailyCosts))
+	}
+
+	calculator.SetDailyTrackingEnabled(true)
+	if _, err := calculator.CalculateDetailedCost(1000, 1000, "claude-sonnet-4.0", &CostOptions{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(calculator.dailyCosts) != 1 {
+		t.Errorf("Expected daily entry after re-enabling, got %d", len(calculator.dailyCosts))
+	}
+}
+
+// TestDailyTrackingConcurrent 測試並行計算時建立新會話與每日記錄、同時清理過期記錄不會競爭
+func TestDailyTrackingConcurrent(t *testing.T) {
+	calculator := NewCostCalculator()
+	now := time.Now()
+	today := now.Format("2006-01-02")
+	for i := 5; i < 15; i++ {
+		calculator.dailyCosts[now.AddDate(0, 0, -i).Format("2006-01-02")] = map[string]float64{"claude-sonnet-4.0": 1.0}
+	}
+	calculator.SetDailyTrackingRetention(3)
+
+	single, err := calculator.CalculateDetailedCost(1000, 1000, "claude-sonnet-4.0", &CostOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	const workers = 20
+	const callsPerWorker = 10
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			sessionID := fmt.Sprintf("session-%d", w)
+			for i := 0; i < callsPerWorker; i++ {
+				if _, err := calculator.CalculateDetailedCost(1000, 1000, "claude-sonnet-4.0", &CostOptions{SessionID: sessionID}); err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				calculator.GetDailyCost(today)
+				calculator.GetSessionCost(sessionID)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	for w := 0; w < workers; w++ {
+		expected := single.TotalCost * callsPerWorker
+		if got := calculator.GetSessionCost(fmt.Sprintf("session-%d", w)); math.Abs(got-expected) > 1e-9 {
+			t.Errorf("Expected session %d cost %f, got %f", w, expected, got)
+		}
+	}
+	expectedDaily := single.TotalCost * (workers*callsPerWorker + 1)
+	if got := calculator.GetDailyCost(today); math.Abs(got-expectedDaily) > 1e-9 {
+		t.Errorf("Expected daily cost %f, got %f", expectedDaily, got)
+	}
+	if len(calculator.dailyCosts) != 1 {
+		t.Errorf("Expected expired entries to be pruned, got %d entries", len(calculator.dailyCosts))
+	}
+}
+
+// TestDailyCostByModel 測試依模型的每日與每月成本追蹤
+func TestDailyCostByModel(t *testing.T) {
+	calculator := NewCostCalculator()
+	now := time.Now()
+	today := now.Format("2006-01-02")
+
+	sonnet, err := calculator.CalculateDetailedCost(1000, 1000, "claude-sonnet-4.0", &CostOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	haiku, err := calculator.CalculateDetailedCost(1000, 1000, "claude-haiku-3.5", &CostOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	byModel := calculator.GetDailyCostByModel(today)
+	if len(byModel) != 2 || byModel["claude-sonnet-4.0"] != sonnet.TotalCost || byModel["claude-haiku-3.5"] != haiku.TotalCost {
+		t.Errorf("Unexpected daily cost by model: %v", byModel)
+	}
+
+	// GetDailyCost 為各模型加總
+	if total := calculator.GetDailyCost(today); total != sonnet.TotalCost+haiku.TotalCost {
+		t.Errorf("Expected summed daily cost %f, got %f", sonnet.TotalCost+haiku.TotalCost, total)
+	}
+
+	monthly := calculator.GetMonthlyCostByModel(now.Format("2006-01"))
+	if monthly["claude-sonnet-4.0"] != sonnet.TotalCost {
+		t.Errorf("Expected monthly sonnet cost %f, got %f", sonnet.TotalCost, monthly["claude-sonnet-4.0"])
+	}
+
+	calculator.ClearDailyCosts()
+	if len(calculator.GetDailyCostByModel(today)) != 0 || calculator.GetDailyCost(today) != 0 {
+		t.Errorf("Expected daily costs to be cleared")
+	}
+}
+
+// TestGetRollingAverages 測試每日成本移動平均
+func TestGetRollingAverages(t *testing.T) {
+	calculator := NewCostCalculator()
+	now := time.Now()
+
+	calculator.dailyCosts[now.Format("2006-01-02")] = map[string]float64{"claude-sonnet-4.0": 7.0}
+	calculator.dailyCosts[now.AddDate(0, 0, -3).Format("2006-01-02")] = map[string]float64{"claude-sonnet-4.0": 3.0, "claude-opus-4.0": 4.0}
+	calculator.dailyCosts[now.AddDate(0, 0, -20).Format("2006-01-02")] = map[string]float64{"claude-sonnet-4.0": 16.0}
+	calculator.dailyCosts[now.AddDate(0, 0, -40).Format("2006-01-02")] = map[string]float64{"claude-sonnet-4.0": 100.0}
+
+	averages := calculator.GetRollingAverages()
+	if len(averages) != 2 {
+		t.Fatalf("Expected default 7 and 30 day windows, got %v", averages)
+	}
+	if averages[7] != 2.0 {
+		t.Errorf("Expected 7-day average 2.0, got %v", averages[7])
+	}
+	if averages[30] != 1.0 {
+		t.Errorf("Expected 30-day average 1.0, got %v", averages[30])
+	}
+
+	averages = calculator.GetRollingAverages(1, 0, -5)
+	if len(averages) != 1 || averages[1] != 7.0 {
+		t.Errorf("Expected only the 1-day window, got %v", averages)
+	}
+}
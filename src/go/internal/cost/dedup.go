@@ -0,0 +1,48 @@
+package cost
+
+import (
+	"fmt"
+	"token-monitor/internal/types"
+)
+
+// RecordKeyFunc 產生使用記錄的去重鍵，鍵相同的記錄視為重複
+type RecordKeyFunc func(record types.UsageRecord) string
+
+// DefaultRecordKey 以 SessionID、時間戳、輸入/輸出 Token 數與定價模型組成去重鍵
+func DefaultRecordKey(record types.UsageRecord) string {
+	return fmt.Sprintf("%s|%d|%d|%d|%s",
+		record.SessionID, record.Timestamp.UnixNano(),
+		record.Tokens.Input, record.Tokens.Output, record.Cost.PricingModel)
+}
+
+// DeduplicateRecords 以 DefaultRecordKey 移除重複記錄，保留第一次出現的記錄與原始順序
+func DeduplicateRecords(records []types.UsageRecord) []types.UsageRecord {
+	return DeduplicateRecordsBy(records, DefaultRecordKey)
+}
+
+// DeduplicateRecordsBy 以指定的鍵函式移除重複記錄；keyFunc 為 nil 時使用 DefaultRecordKey
+func DeduplicateRecordsBy(records []types.UsageRecord, keyFunc RecordKeyFunc) []types.UsageRecord {
+	if keyFunc == nil {
+		keyFunc = DefaultRecordKey
+	}
+
+	seen := make(map[string]struct{}, len(records))
+	unique := make([]types.UsageRecord, 0, len(records))
+	for _, record := range records {
+		key := keyFunc(record)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		unique = append(unique, record)
+	}
+	return unique
+}
+
+// SetRecordKeyFunc 設定 GenerateCostReport 去重時使用的鍵函式；nil 表示使用 DefaultRecordKey
+func (cc *CostCalculatorImpl) SetRecordKeyFunc(keyFunc RecordKeyFunc) {
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	cc.recordKeyFunc = keyFunc
+}
@@ -0,0 +1,74 @@
+package cost
+
+import (
+	"testing"
+	"time"
+	"token-monitor/internal/types"
+)
+
+// TestDeduplicateRecords 測試重複記錄移除與順序保留
+func TestDeduplicateRecords(t *testing.T) {
+	now := time.Now()
+	first := newTestRecord(now, types.ActivityCoding, 1000, 500, "claude-sonnet-4.0")
+	first.SessionID = "s1"
+	second := newTestRecord(now.Add(time.Minute), types.ActivityChat, 200, 100, "claude-haiku-3.5")
+	second.SessionID = "s1"
+	otherModel := first
+	otherModel.Cost.PricingModel = "claude-opus-4.0"
+
+	records := []types.UsageRecord{first, second, first, otherModel, second}
+	unique := DeduplicateRecords(records)
+	if len(unique) != 3 {
+		t.Fatalf("Expected 3 unique records, got %d", len(unique))
+	}
+	if unique[0].Cost.PricingModel != "claude-sonnet-4.0" || unique[1].Activity.Type != types.ActivityChat ||
+		unique[2].Cost.PricingModel != "claude-opus-4.0" {
+		t.Errorf("Expected original order to be preserved, got %+v", unique)
+	}
+
+	// 自訂鍵函式：僅依 SessionID 去重
+	bySession := DeduplicateRecordsBy(records, func(record types.UsageRecord) string {
+		return record.SessionID
+	})
+	if len(bySession) != 1 {
+		t.Errorf("Expected 1 record when keyed by session, got %d", len(bySession))
+	}
+
+	if len(DeduplicateRecords(nil)) != 0 {
+		t.Error("Expected empty result for nil records")
+	}
+}
+
+// TestGenerateCostReportDeduplicate 測試報告選項啟用去重
+func TestGenerateCostReportDeduplicate(t *testing.T) {
+	calculator := NewCostCalculator()
+	record := newTestRecord(time.Now(), types.ActivityCoding, 1000, 500, "claude-sonnet-4.0")
+	records := []types.UsageRecord{record, record}
+
+	report, err := calculator.GenerateCostReport(records, &types.ReportOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if report.TotalRecords != 2 {
+		t.Errorf("Expected duplicates to be counted without Deduplicate, got %d", report.TotalRecords)
+	}
+
+	report, err = calculator.GenerateCostReport(records, &types.ReportOptions{Deduplicate: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if report.TotalRecords != 1 {
+		t.Errorf("Expected 1 record after deduplication, got %d", report.TotalRecords)
+	}
+
+	// 自訂鍵函式讓所有記錄都視為不同
+	counter := 0
+	calculator.SetRecordKeyFunc(func(types.UsageRecord) string {
+		counter++
+		return string(rune('a' + counter))
+	})
+	report, _ = calculator.GenerateCostReport(records, &types.ReportOptions{Deduplicate: true})
+	if report.TotalRecords != 2 {
+		t.Errorf("Expected custom key func to keep both records, got %d", report.TotalRecords)
+	}
+}
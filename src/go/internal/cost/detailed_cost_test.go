@@ -1,8 +1,12 @@
 package cost
 
 import (
+	"math"
+	"sync"
 	"testing"
 	"time"
+	"token-monitor/internal/errors"
+	"token-monitor/internal/mathutil"
 	"token-monitor/internal/types"
 )
 
@@ -54,13 +58,13 @@ func TestCalculateDetailedCost(t *testing.T) {
 				expectedOutputCost := 0.03
 				expectedTotalCost := expectedInputCost + expectedOutputCost
 
-				if absFloat(breakdown.InputCost-expectedInputCost) > 0.0001 {
+				if mathutil.AbsFloat(breakdown.InputCost-expectedInputCost) > 0.0001 {
 					t.Errorf("Expected input cost %.6f, got %.6f", expectedInputCost, breakdown.InputCost)
 				}
-				if absFloat(breakdown.OutputCost-expectedOutputCost) > 0.0001 {
+				if mathutil.AbsFloat(breakdown.OutputCost-expectedOutputCost) > 0.0001 {
 					t.Errorf("Expected output cost %.6f, got %.6f", expectedOutputCost, breakdown.OutputCost)
 				}
-				if absFloat(breakdown.TotalCost-expectedTotalCost) > 0.0001 {
+				if mathutil.AbsFloat(breakdown.TotalCost-expectedTotalCost) > 0.0001 {
 					t.Errorf("Expected total cost %.6f, got %.6f", expectedTotalCost, breakdown.TotalCost)
 				}
 			},
@@ -98,10 +102,10 @@ func TestCalculateDetailedCost(t *testing.T) {
 				expectedCacheReadCost := 0.00015
 				expectedCacheWriteCost := 0.001125
 
-				if absFloat(breakdown.CacheReadCost-expectedCacheReadCost) > 0.000001 {
+				if mathutil.AbsFloat(breakdown.CacheReadCost-expectedCacheReadCost) > 0.000001 {
 					t.Errorf("Expected cache read cost %.6f, got %.6f", expectedCacheReadCost, breakdown.CacheReadCost)
 				}
-				if absFloat(breakdown.CacheWriteCost-expectedCacheWriteCost) > 0.000001 {
+				if mathutil.AbsFloat(breakdown.CacheWriteCost-expectedCacheWriteCost) > 0.000001 {
 					t.Errorf("Expected cache write cost %.6f, got %.6f", expectedCacheWriteCost, breakdown.CacheWriteCost)
 				}
 			},
@@ -132,10 +136,10 @@ func TestCalculateDetailedCost(t *testing.T) {
 				expectedTotalCost := 0.0165
 				expectedBatchDiscount := 0.0165 // 50% 的折扣金額
 
-				if absFloat(breakdown.TotalCost-expectedTotalCost) > 0.0001 {
+				if mathutil.AbsFloat(breakdown.TotalCost-expectedTotalCost) > 0.0001 {
 					t.Errorf("Expected total cost %.6f, got %.6f", expectedTotalCost, breakdown.TotalCost)
 				}
-				if absFloat(breakdown.BatchDiscount-expectedBatchDiscount) > 0.0001 {
+				if mathutil.AbsFloat(breakdown.BatchDiscount-expectedBatchDiscount) > 0.0001 {
 					t.Errorf("Expected batch discount %.6f, got %.6f", expectedBatchDiscount, breakdown.BatchDiscount)
 				}
 			},
@@ -202,6 +206,398 @@ func TestCalculateDetailedCost(t *testing.T) {
 	}
 }
 
+// TestCalculateDetailedCostTokenLimit 測試超過 token 上限時的結構化錯誤
+func TestCalculateDetailedCostTokenLimit(t *testing.T) {
+	calculator := NewCostCalculator()
+
+	_, err := calculator.CalculateDetailedCost(maxTokenCount+1, 100, "claude-sonnet-4.0", nil)
+	if !errors.IsCode(err, errors.ErrCodeTokenCountExceeded) {
+		t.Fatalf("Expected ErrCodeTokenCountExceeded, got %v", err)
+	}
+
+	appErr, ok := err.(*errors.AppError)
+	if !ok {
+		t.Fatalf("Expected *errors.AppError, got %T", err)
+	}
+	if appErr.Context.Parameters["limit"] != maxTokenCount {
+		t.Errorf("Expected limit %d in context, got %v", maxTokenCount, appErr.Context.Parameters["limit"])
+	}
+	if appErr.Context.Parameters["input_tokens"] != maxTokenCount+1 {
+		t.Errorf("Expected input_tokens in context, got %v", appErr.Context.Parameters["input_tokens"])
+	}
+}
+
+// TestCalculateDetailedCostReasoning 測試推理 Token 另行計價
+func TestCalculateDetailedCostReasoning(t *testing.T) {
+	calculator := NewCostCalculator()
+	calculator.pricingEngine.AddPricingModel("reasoning-model", &types.PricingModel{
+		Name:           "reasoning-model",
+		InputPrice:     1.0,
+		OutputPrice:    2.0,
+		BatchDiscount:  0.5,
+		ReasoningPrice: 4.0,
+	})
+
+	breakdown, err := calculator.CalculateDetailedCost(1_000_000, 1_000_000, "reasoning-model", &CostOptions{
+		ReasoningTokens: 500_000,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if math.Abs(breakdown.ReasoningCost-2.0) > 1e-9 || math.Abs(breakdown.TotalCost-5.0) > 1e-9 {
+		t.Errorf("Expected reasoning cost 2.0 and total 5.0, got %f and %f", breakdown.ReasoningCost, breakdown.TotalCost)
+	}
+	if breakdown.TokenCounts.Reasoning != 500_000 || breakdown.TokenCounts.Total != 2_500_000 {
+		t.Errorf("Unexpected token counts: %+v", breakdown.TokenCounts)
+	}
+
+	// 批次折扣同樣套用於推理成本
+	breakdown, err = calculator.CalculateDetailedCost(1_000_000, 1_000_000, "reasoning-model", &CostOptions{
+		Mode:            BatchBilling,
+		IsBatch:         true,
+		ReasoningTokens: 500_000,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if math.Abs(breakdown.ReasoningCost-1.0) > 1e-9 || math.Abs(breakdown.TotalCost-2.5) > 1e-9 {
+		t.Errorf("Expected discounted reasoning cost 1.0 and total 2.5, got %f and %f", breakdown.ReasoningCost, breakdown.TotalCost)
+	}
+
+	// 未設定推理價格的模型維持原行為
+	standard, err := calculator.CalculateDetailedCost(1000, 1000, "claude-sonnet-4.0", &CostOptions{ReasoningTokens: 1000})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if standard.ReasoningCost != 0 || math.Abs(standard.TotalCost-(standard.InputCost+standard.OutputCost)) > 1e-12 {
+		t.Errorf("Expected no reasoning cost without price, got %+v", standard)
+	}
+
+	if _, err := calculator.CalculateDetailedCost(1000, 1000, "reasoning-model", &CostOptions{ReasoningTokens: -1}); err == nil {
+		t.Errorf("Expected error for negative reasoning tokens")
+	}
+}
+
+// TestCalculateDetailedCostBatchDiscountScope 測試批次折扣依適用範圍套用
+func TestCalculateDetailedCostBatchDiscountScope(t *testing.T) {
+	calculator := NewCostCalculator()
+
+	tests := []struct {
+		scope          string
+		expectedInput  float64
+		expectedOutput float64
+	}{
+		{"", 0.5, 1.0},
+		{BatchDiscountScopeAll, 0.5, 1.0},
+		{BatchDiscountScopeOutput, 1.0, 1.0},
+		{BatchDiscountScopeInput, 0.5, 2.0},
+	}
+
+	for _, tt := range tests {
+		name := "scope-model-" + tt.scope
+		calculator.pricingEngine.AddPricingModel(name, &types.PricingModel{
+			Name:               name,
+			InputPrice:         1.0,
+			OutputPrice:        2.0,
+			BatchDiscount:      0.5,
+			BatchDiscountScope: tt.scope,
+		})
+
+		breakdown, err := calculator.CalculateDetailedCost(1_000_000, 1_000_000, name, &CostOptions{
+			Mode:    BatchBilling,
+			IsBatch: true,
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		expectedTotal := tt.expectedInput + tt.expectedOutput
+		if math.Abs(breakdown.InputCost-tt.expectedInput) > 1e-9 ||
+			math.Abs(breakdown.OutputCost-tt.expectedOutput) > 1e-9 ||
+			math.Abs(breakdown.TotalCost-expectedTotal) > 1e-9 {
+			t.Errorf("Scope %q: expected input %f, output %f, total %f, got %f, %f, %f",
+				tt.scope, tt.expectedInput, tt.expectedOutput, expectedTotal,
+				breakdown.InputCost, breakdown.OutputCost, breakdown.TotalCost)
+		}
+		if math.Abs(breakdown.BatchDiscount-(3.0-expectedTotal)) > 1e-9 {
+			t.Errorf("Scope %q: expected batch discount %f, got %f", tt.scope, 3.0-expectedTotal, breakdown.BatchDiscount)
+		}
+	}
+}
+
+// TestCalculateDetailedCostEffectiveDiscount 測試相對標準價格的實際折扣百分比
+func TestCalculateDetailedCostEffectiveDiscount(t *testing.T) {
+	calculator := NewCostCalculator()
+	calculator.pricingEngine.AddPricingModel("discount-model", &types.PricingModel{
+		Name:          "discount-model",
+		InputPrice:    2.0,
+		OutputPrice:   4.0,
+		CacheRead:     0.2,
+		CacheWrite:    2.5,
+		BatchDiscount: 0.5,
+	})
+
+	tests := []struct {
+		name     string
+		options  *CostOptions
+		expected float64
+	}{
+		{"standard", &CostOptions{}, 0},
+		{"batch", &CostOptions{Mode: BatchBilling, IsBatch: true}, 50},
+		// 標準價格：(1M + 1M 快取讀取) * 2.0 + 1M * 4.0 = 8.0；實際：2.0 + 4.0 + 0.2 = 6.2
+		{"cache", &CostOptions{Mode: CacheBilling, CacheReadTokens: 1_000_000}, (1 - 6.2/8.0) * 100},
+	}
+
+	for _, tt := range tests {
+		breakdown, err := calculator.CalculateDetailedCost(1_000_000, 1_000_000, "discount-model", tt.options)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		if math.Abs(breakdown.EffectiveDiscountPercent-tt.expected) > 1e-9 {
+			t.Errorf("%s: expected effective discount %f%%, got %f%%", tt.name, tt.expected, breakdown.EffectiveDiscountPercent)
+		}
+	}
+}
+
+// TestCheapestBillingMode 測試選出成本最低的計費模式
+func TestCheapestBillingMode(t *testing.T) {
+	calculator := NewCostCalculator()
+	calculator.pricingEngine.AddPricingModel("mode-model", &types.PricingModel{
+		Name:          "mode-model",
+		InputPrice:    2.0,
+		OutputPrice:   4.0,
+		CacheRead:     0.2,
+		CacheWrite:    2.5,
+		BatchDiscount: 0.5,
+	})
+
+	tests := []struct {
+		name          string
+		cacheRead     int
+		cacheWrite    int
+		batchEligible bool
+		expectedMode  BillingMode
+		expectedCost  float64
+	}{
+		// 標準：1M*2 + 1M*4 = 6
+		{"standard only", 0, 0, false, StandardBilling, 6.0},
+		// 批次：6 * 0.5 = 3
+		{"batch eligible", 0, 0, true, BatchBilling, 3.0},
+		// 快取：1M*2 + 1M*4 + 4M*0.2 = 6.8；標準：5M*2 + 4 = 14
+		{"cache read", 4_000_000, 0, false, CacheBilling, 6.8},
+		// 快取 6.8 仍低於批次 7
+		{"cache beats batch", 4_000_000, 0, true, CacheBilling, 6.8},
+		// 快取寫入價格高於輸入：快取 2 + 4 + 2.5 = 8.5，標準 4 + 4 = 8
+		{"cache write", 0, 1_000_000, false, StandardBilling, 8.0},
+	}
+
+	for _, tt := range tests {
+		mode, breakdown, err := calculator.CheapestBillingMode(1_000_000, 1_000_000, tt.cacheRead, tt.cacheWrite, "mode-model", tt.batchEligible)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		if mode != tt.expectedMode || math.Abs(breakdown.TotalCost-tt.expectedCost) > 1e-9 {
+			t.Errorf("%s: expected %s at %f, got %s at %f", tt.name, tt.expectedMode, tt.expectedCost, mode, breakdown.TotalCost)
+		}
+		if breakdown.CostDetails.BillingMode != mode.String() {
+			t.Errorf("%s: expected breakdown billing mode %s, got %s", tt.name, mode, breakdown.CostDetails.BillingMode)
+		}
+	}
+
+	// 比較結果不記入每日追蹤
+	if total := calculator.GetDailyCost(time.Now().Format("2006-01-02")); total != 0 {
+		t.Errorf("Expected no tracked cost, got %f", total)
+	}
+
+	if _, _, err := calculator.CheapestBillingMode(1000, 1000, 0, 0, "unknown-model", true); err == nil {
+		t.Errorf("Expected error for unknown model")
+	}
+}
+
+// TestCalculateDetailedCostWithinBudget 測試預算上限檢查
+func TestCalculateDetailedCostWithinBudget(t *testing.T) {
+	calculator := NewCostCalculator()
+	options := &CostOptions{SessionID: "budget-session"}
+
+	breakdown, err := calculator.CalculateDetailedCostWithinBudget(1000, 1000, "claude-sonnet-4.0", options, 1.0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if calculator.GetSessionCost("budget-session") != breakdown.TotalCost {
+		t.Errorf("Expected session cost %f to be tracked, got %f", breakdown.TotalCost, calculator.GetSessionCost("budget-session"))
+	}
+
+	today := time.Now().Format("2006-01-02")
+	dailyBefore := calculator.GetDailyCost(today)
+
+	// 超出預算時不記錄追蹤
+	_, err = calculator.CalculateDetailedCostWithinBudget(1000, 1000, "claude-sonnet-4.0", options, 0.001)
+	if !errors.IsCode(err, errors.ErrCodeBudgetExceeded) {
+		t.Fatalf("Expected ErrCodeBudgetExceeded, got %v", err)
+	}
+	if calculator.GetSessionCost("budget-session") != breakdown.TotalCost {
+		t.Errorf("Expected session cost unchanged after rejection, got %f", calculator.GetSessionCost("budget-session"))
+	}
+	if calculator.GetDailyCost(today) != dailyBefore {
+		t.Errorf("Expected daily cost unchanged after rejection")
+	}
+}
+
+// TestCalculateDetailedCostWithinBudgetConcurrent 測試並行呼叫不會共同突破預算
+func TestCalculateDetailedCostWithinBudgetConcurrent(t *testing.T) {
+	calculator := NewCostCalculator()
+	options := &CostOptions{SessionID: "budget-concurrent"}
+
+	single, err := calculator.CalculateDetailedCost(1000, 1000, "claude-sonnet-4.0", &CostOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// 預算僅容許五次呼叫
+	budget := single.TotalCost*5 + single.TotalCost/2
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	accepted := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := calculator.CalculateDetailedCostWithinBudget(1000, 1000, "claude-sonnet-4.0", options, budget)
+			if err == nil {
+				mu.Lock()
+				accepted++
+				mu.Unlock()
+			} else if !errors.IsCode(err, errors.ErrCodeBudgetExceeded) {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if accepted != 5 {
+		t.Errorf("Expected 5 accepted calls, got %d", accepted)
+	}
+	if spent := calculator.GetSessionCost("budget-concurrent"); spent > budget {
+		t.Errorf("Expected session cost %f within budget %f", spent, budget)
+	}
+
+	if _, err := calculator.CalculateDetailedCostWithinBudget(1000, 1000, "claude-sonnet-4.0", nil, budget); err == nil {
+		t.Errorf("Expected error for nil options")
+	}
+}
+
+// TestGenerateCostReportCacheBilling 測試帶快取與批次資訊的記錄使用詳細計費
+func TestGenerateCostReportCacheBilling(t *testing.T) {
+	calculator := NewCostCalculator()
+	now := time.Now()
+
+	cached := newTestRecord(now, types.ActivityCoding, 1_000_000, 0, "claude-sonnet-4.0")
+	cached.Billing.CacheReadTokens = 1_000_000
+	batched := newTestRecord(now, types.ActivityChat, 1_000_000, 0, "claude-sonnet-4.0")
+	batched.Billing.Batch = true
+	plain := newTestRecord(now, types.ActivityChat, 1_000_000, 0, "claude-sonnet-4.0")
+
+	report, err := calculator.GenerateCostReport([]types.UsageRecord{cached, batched, plain}, &types.ReportOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// claude-sonnet-4.0：輸入 $3/MTok、快取讀取 $0.3/MTok、批次折扣 50%
+	coding := report.ByActivity[types.ActivityCoding]
+	if math.Abs(coding.TotalCost-3.3) > 1e-9 || math.Abs(coding.CacheCost-0.3) > 1e-9 || math.Abs(coding.NonCacheCost-3.0) > 1e-9 {
+		t.Errorf("Unexpected coding cache split: %+v", coding)
+	}
+	if math.Abs(coding.CacheSavings-2.7) > 1e-9 {
+		t.Errorf("Expected cache savings 2.7, got %f", coding.CacheSavings)
+	}
+
+	chat := report.ByActivity[types.ActivityChat]
+	if math.Abs(chat.TotalCost-4.5) > 1e-9 || chat.CacheCost != 0 {
+		t.Errorf("Expected batch-discounted chat cost 4.5 without cache cost, got %+v", chat)
+	}
+
+	if math.Abs(report.Summary.CacheSavings-2.7) > 1e-9 {
+		t.Errorf("Expected summary cache savings 2.7, got %f", report.Summary.CacheSavings)
+	}
+
+	// 報告計算不應影響日常追蹤
+	if daily := calculator.GetDailyCost(now.Format("2006-01-02")); daily != 0 {
+		t.Errorf("Expected no daily tracking from report generation, got %f", daily)
+	}
+}
+
+// TestGetCostForTimeRange 測試時間範圍內的成本加總
+func TestGetCostForTimeRange(t *testing.T) {
+	calculator := NewCostCalculator()
+	base := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	records := []types.UsageRecord{
+		newTestRecord(base, types.ActivityCoding, 1000, 2000, "claude-sonnet-4.0"),
+		newTestRecord(base.Add(24*time.Hour), types.ActivityChat, 1000, 1000, "claude-sonnet-4.0"),
+		newTestRecord(base.Add(48*time.Hour), types.ActivityChat, 1000, 1000, "claude-sonnet-4.0"),
+		newTestRecord(base.Add(24*time.Hour), types.ActivityChat, 1000, 1000, "unknown-model"),
+	}
+
+	cost, tokens, err := calculator.GetCostForTimeRange(records, base, base.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// 起訖時間皆包含在內：0.033 + 0.018
+	if tokens != 5000 {
+		t.Errorf("Expected 5000 tokens, got %d", tokens)
+	}
+	if diff := cost - 0.051; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("Expected cost 0.051, got %f", cost)
+	}
+
+	_, tokens, err = calculator.GetCostForTimeRange(records, base.Add(time.Hour), time.Time{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tokens != 4000 {
+		t.Errorf("Expected 4000 tokens with open end, got %d", tokens)
+	}
+
+	if _, _, err := calculator.GetCostForTimeRange(records, base.Add(time.Hour), base); err == nil {
+		t.Errorf("Expected error when end is before start")
+	}
+}
+
+// TestGenerateCostReportByMethod 測試按計算方法分組
+func TestGenerateCostReportByMethod(t *testing.T) {
+	calculator := NewCostCalculator()
+	now := time.Now()
+
+	estimated := newTestRecord(now, types.ActivityCoding, 1000, 2000, "claude-sonnet-4.0")
+	estimated.Tokens.CalculationMethod = "estimation"
+	counted := newTestRecord(now, types.ActivityCoding, 1000, 1000, "claude-sonnet-4.0")
+	counted.Tokens.CalculationMethod = "tiktoken"
+	unlabeled := newTestRecord(now, types.ActivityChat, 500, 500, "claude-sonnet-4.0")
+
+	report, err := calculator.GenerateCostReport([]types.UsageRecord{estimated, counted, unlabeled}, &types.ReportOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(report.ByMethod) != 3 {
+		t.Fatalf("Expected 3 calculation methods, got %d", len(report.ByMethod))
+	}
+	if summary := report.ByMethod["estimation"]; summary.RecordCount != 1 || summary.TotalTokens != 3000 {
+		t.Errorf("Expected estimation to have 1 record and 3000 tokens, got %+v", summary)
+	}
+	if summary := report.ByMethod["unknown"]; summary.RecordCount != 1 {
+		t.Errorf("Expected unlabeled record under unknown, got %+v", summary)
+	}
+
+	total := 0.0
+	for _, summary := range report.ByMethod {
+		total += summary.TotalCost
+	}
+	if diff := total - report.Summary.TotalCost; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("Expected method costs to sum to total %f, got %f", report.Summary.TotalCost, total)
+	}
+}
+
 // TestAnalyzeCostTrends 測試成本趨勢分析
 func TestAnalyzeCostTrends(t *testing.T) {
 	calculator := NewCostCalculator()
@@ -465,11 +861,3 @@ func TestGenerateCostReport(t *testing.T) {
 		t.Errorf("Expected trends analysis but got nil")
 	}
 }
-
-// absFloat 計算浮點數絕對值
-func absFloat(x float64) float64 {
-	if x < 0 {
-		return -x
-	}
-	return x
-}
@@ -0,0 +1,63 @@
+package cost
+
+import (
+	"fmt"
+	"sort"
+	"time"
+	"token-monitor/internal/types"
+)
+
+// EfficiencyPoint 單一時間區間內某活動類型的成本效率
+type EfficiencyPoint struct {
+	Timestamp       time.Time `json:"timestamp"`
+	TotalTokens     int       `json:"total_tokens"`
+	TotalCost       float64   `json:"total_cost"`
+	TokensPerDollar float64   `json:"tokens_per_dollar"`
+	RecordCount     int       `json:"record_count"`
+}
+
+// AnalyzeEfficiencyTrends 按時間區間分析各活動類型的效率（tokens per dollar）趨勢
+func (cc *CostCalculatorImpl) AnalyzeEfficiencyTrends(records []types.UsageRecord, timeRange string) (map[types.ActivityType][]EfficiencyPoint, error) {
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no usage records provided")
+	}
+
+	trends := make(map[types.ActivityType][]EfficiencyPoint)
+
+	for timeKey, timeRecords := range cc.groupRecordsByTime(records, timeRange) {
+		points := make(map[types.ActivityType]*EfficiencyPoint)
+
+		for _, record := range timeRecords {
+			inputTokens, outputTokens := cc.recordTokens(record)
+			breakdown, err := cc.CalculateCost(inputTokens, outputTokens, cc.recordModel(record))
+			if err != nil {
+				continue
+			}
+
+			point, exists := points[record.Activity.Type]
+			if !exists {
+				point = &EfficiencyPoint{Timestamp: timeKey}
+				points[record.Activity.Type] = point
+			}
+			point.TotalTokens += record.Tokens.Total
+			point.TotalCost += breakdown.TotalCost
+			point.RecordCount++
+		}
+
+		for activityType, point := range points {
+			if point.TotalCost > 0 {
+				point.TokensPerDollar = float64(point.TotalTokens) / point.TotalCost
+			}
+			trends[activityType] = append(trends[activityType], *point)
+		}
+	}
+
+	// 各活動類型的資料點依時間排序
+	for _, points := range trends {
+		sort.Slice(points, func(i, j int) bool {
+			return points[i].Timestamp.Before(points[j].Timestamp)
+		})
+	}
+
+	return trends, nil
+}
@@ -0,0 +1,56 @@
+package cost
+
+import (
+	"math"
+	"testing"
+	"time"
+	"token-monitor/internal/types"
+)
+
+// TestAnalyzeEfficiencyTrends 測試按時間區間的效率趨勢
+func TestAnalyzeEfficiencyTrends(t *testing.T) {
+	calculator := NewCostCalculator()
+	base := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+
+	records := []types.UsageRecord{
+		newTestRecord(base.AddDate(0, 0, 7), types.ActivityCoding, 1000, 1000, "claude-sonnet-4.0"),
+		newTestRecord(base, types.ActivityCoding, 1000, 2000, "claude-sonnet-4.0"),
+		newTestRecord(base.Add(time.Hour), types.ActivityCoding, 1000, 2000, "claude-sonnet-4.0"),
+		newTestRecord(base, types.ActivityChat, 1000, 1000, "claude-haiku-3.5"),
+	}
+
+	trends, err := calculator.AnalyzeEfficiencyTrends(records, "weekly")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	coding := trends[types.ActivityCoding]
+	if len(coding) != 2 {
+		t.Fatalf("Expected 2 weekly points for coding, got %d", len(coding))
+	}
+	if !coding[0].Timestamp.Before(coding[1].Timestamp) {
+		t.Errorf("Expected points sorted by time")
+	}
+	if coding[0].RecordCount != 2 || coding[0].TotalTokens != 6000 {
+		t.Errorf("Expected first week to have 2 records and 6000 tokens, got %+v", coding[0])
+	}
+
+	// 1000 輸入 + 2000 輸出（sonnet）= $0.033，效率 = 3000 / 0.033
+	expected := 6000 / 0.066
+	if math.Abs(coding[0].TokensPerDollar-expected) > 0.01 {
+		t.Errorf("Expected %.2f tokens per dollar, got %.2f", expected, coding[0].TokensPerDollar)
+	}
+	// 輸出比例較低的一週效率應較高
+	if coding[1].TokensPerDollar <= coding[0].TokensPerDollar {
+		t.Errorf("Expected second week to be more efficient, got %.2f <= %.2f",
+			coding[1].TokensPerDollar, coding[0].TokensPerDollar)
+	}
+
+	if len(trends[types.ActivityChat]) != 1 {
+		t.Errorf("Expected 1 point for chat, got %d", len(trends[types.ActivityChat]))
+	}
+
+	if _, err := calculator.AnalyzeEfficiencyTrends(nil, "daily"); err == nil {
+		t.Errorf("Expected error for empty records")
+	}
+}
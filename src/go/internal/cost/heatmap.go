@@ -0,0 +1,40 @@
+package cost
+
+import "token-monitor/internal/types"
+
+// CostHeatmap 依記錄計算每日各活動類型的成本（日期 -> 活動類型 -> 成本），
+// 日期取自記錄時間戳（YYYY-MM-DD），無法計算成本的記錄不計入
+func (cc *CostCalculatorImpl) CostHeatmap(records []types.UsageRecord) map[string]map[types.ActivityType]float64 {
+	result := make(map[string]map[types.ActivityType]float64)
+
+	for _, record := range records {
+		inputTokens, outputTokens := cc.recordTokens(record)
+		breakdown, err := cc.CalculateCost(inputTokens, outputTokens, cc.recordModel(record))
+		if err != nil {
+			continue
+		}
+
+		date := record.Timestamp.Format("2006-01-02")
+		if result[date] == nil {
+			result[date] = make(map[types.ActivityType]float64)
+		}
+		result[date][record.Activity.Type] += breakdown.TotalCost
+	}
+
+	return result
+}
+
+// TokenHeatmap 依記錄計算每日各活動類型的 Token 總數（日期 -> 活動類型 -> Token 數）
+func (cc *CostCalculatorImpl) TokenHeatmap(records []types.UsageRecord) map[string]map[types.ActivityType]int {
+	result := make(map[string]map[types.ActivityType]int)
+
+	for _, record := range records {
+		date := record.Timestamp.Format("2006-01-02")
+		if result[date] == nil {
+			result[date] = make(map[types.ActivityType]int)
+		}
+		result[date][record.Activity.Type] += record.Tokens.Total
+	}
+
+	return result
+}
@@ -0,0 +1,43 @@
+package cost
+
+import (
+	"math"
+	"testing"
+	"time"
+	"token-monitor/internal/types"
+)
+
+// TestCostAndTokenHeatmap 測試每日各活動類型的成本與 Token 熱度圖
+func TestCostAndTokenHeatmap(t *testing.T) {
+	calculator := NewCostCalculator()
+	day := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	records := []types.UsageRecord{
+		newTestRecord(day, types.ActivityCoding, 1000, 500, "claude-sonnet-4.0"),
+		newTestRecord(day.Add(3*time.Hour), types.ActivityCoding, 2000, 1000, "claude-sonnet-4.0"),
+		newTestRecord(day.Add(time.Hour), types.ActivityChat, 100, 100, "claude-haiku-3.5"),
+		newTestRecord(day.AddDate(0, 0, 1), types.ActivityDebugging, 400, 400, "claude-opus-4.0"),
+	}
+
+	costs := calculator.CostHeatmap(records)
+	if len(costs) != 2 {
+		t.Fatalf("Expected 2 days, got %d", len(costs))
+	}
+
+	first, _ := calculator.CalculateCost(1000, 500, "claude-sonnet-4.0")
+	second, _ := calculator.CalculateCost(2000, 1000, "claude-sonnet-4.0")
+	if got := costs["2024-03-01"][types.ActivityCoding]; math.Abs(got-(first.TotalCost+second.TotalCost)) > 1e-12 {
+		t.Errorf("Expected coding cost %.6f, got %.6f", first.TotalCost+second.TotalCost, got)
+	}
+	if costs["2024-03-02"][types.ActivityDebugging] <= 0 {
+		t.Error("Expected debugging cost on 2024-03-02")
+	}
+
+	tokens := calculator.TokenHeatmap(records)
+	if tokens["2024-03-01"][types.ActivityCoding] != 4500 || tokens["2024-03-01"][types.ActivityChat] != 200 {
+		t.Errorf("Unexpected token heatmap: %v", tokens)
+	}
+	if tokens["2024-03-02"][types.ActivityDebugging] != 800 {
+		t.Errorf("Expected 800 debugging tokens, got %d", tokens["2024-03-02"][types.ActivityDebugging])
+	}
+}
@@ -0,0 +1,162 @@
+package cost
+
+import (
+	"time"
+	"token-monitor/internal/errors"
+	"token-monitor/internal/types"
+)
+
+// MergeCostReports 合併多份成本報告（例如將每日報告彙總為每週報告），不需重新讀取記錄。
+// 摘要與分組統計相加後重新計算平均值，趨勢資料點依時間區間合併相加後排序，時間範圍取聯集。
+// 不檢查幣別；報告幣別可能不同時請使用 MergeCostReportsStrict。
+func MergeCostReports(reports ...*types.CostReport) *types.CostReport {
+	merged := &types.CostReport{
+		GeneratedAt:  time.Now(),
+		Summary:      types.CostSummary{},
+		ByActivity:   make(map[types.ActivityType]types.CostSummary),
+		ByModel:      make(map[string]types.CostSummary),
+		ByMethod:     make(map[string]types.CostSummary),
+		Optimization: &types.OptimizationSuggestions{},
+		Trends:       &types.CostTrendAnalysis{},
+	}
+
+	trendRange := ""
+	dataPoints := make([]types.CostDataPoint, 0)
+
+	for _, report := range reports {
+		if report == nil {
+			continue
+		}
+
+		merged.TimeRange = unionTimeRange(merged.TimeRange, report.TimeRange)
+		merged.TotalRecords += report.TotalRecords
+		merged.Summary = addCostSummary(merged.Summary, report.Summary)
+
+		for activityType, summary := range report.ByActivity {
+			merged.ByActivity[activityType] = addCostSummary(merged.ByActivity[activityType], summary)
+		}
+		for model, summary := range report.ByModel {
+			merged.ByModel[model] = addCostSummary(merged.ByModel[model], summary)
+		}
+		for method, summary := range report.ByMethod {
+			merged.ByMethod[method] = addCostSummary(merged.ByMethod[method], summary)
+		}
+
+		if report.Optimization != nil {
+			merged.Optimization.Suggestions = append(merged.Optimization.Suggestions, report.Optimization.Suggestions...)
+			merged.Optimization.TotalSavings += report.Optimization.TotalSavings
+			merged.Optimization.ConfidenceWeightedSavings += report.Optimization.ConfidenceWeightedSavings
+			merged.Optimization.CurrentCost += report.Optimization.CurrentCost
+			merged.Optimization.OptimizedCost += report.Optimization.OptimizedCost
+		}
+
+		if report.Trends != nil {
+			if trendRange == "" {
+				trendRange = report.Trends.TimeRange
+			}
+			dataPoints = append(dataPoints, report.Trends.DataPoints...)
+		}
+	}
+
+	// 重新計算平均值
+	if merged.Summary.TotalTokens > 0 {
+		merged.Summary.AverageCostPerToken = merged.Summary.TotalCost / float64(merged.Summary.TotalTokens) * 1_000_000
+	}
+	for activityType, summary := range merged.ByActivity {
+		merged.ByActivity[activityType] = withCostAverages(summary)
+	}
+	for model, summary := range merged.ByModel {
+		merged.ByModel[model] = withCostAverages(summary)
+	}
+	for method, summary := range merged.ByMethod {
+		merged.ByMethod[method] = withCostAverages(summary)
+	}
+
+	if trendRange == "" {
+		trendRange = "daily"
+	}
+	merged.Trends = buildCostTrends(trendRange, coalesceDataPoints(dataPoints, trendRange))
+
+	return merged
+}
+
+// MergeCostReportsStrict 與 MergeCostReports 相同，但報告幣別不一致時回傳錯誤而不相加（未設定幣別的報告視為相容）
+func MergeCostReportsStrict(reports ...*types.CostReport) (*types.CostReport, error) {
+	if err := checkReportCurrencies(reports); err != nil {
+		return nil, err
+	}
+	return MergeCostReports(reports...), nil
+}
+
+// checkReportCurrencies 確認所有報告使用相同幣別，避免將不同幣別的金額相加
+func checkReportCurrencies(reports []*types.CostReport) error {
+	currency := ""
+	for _, report := range reports {
+		if report == nil || report.Summary.Currency == "" {
+			continue
+		}
+		if currency == "" {
+			currency = report.Summary.Currency
+			continue
+		}
+		if report.Summary.Currency != currency {
+			return errors.Newf(errors.ErrCodeReportGeneration, "無法合併不同幣別的成本報告: %s 與 %s", currency, report.Summary.Currency)
+		}
+	}
+	return nil
+}
+
+// coalesceDataPoints 將落在同一時間區間的資料點相加為單一資料點
+func coalesceDataPoints(dataPoints []types.CostDataPoint, timeRange string) []types.CostDataPoint {
+	buckets := make(map[time.Time]*types.CostDataPoint)
+	keys := make([]time.Time, 0, len(dataPoints))
+	for _, point := range dataPoints {
+		key := timeBucketKey(point.Timestamp, timeRange)
+		bucket, exists := buckets[key]
+		if !exists {
+			bucket = &types.CostDataPoint{Timestamp: key}
+			buckets[key] = bucket
+			keys = append(keys, key)
+		}
+		bucket.Cost += point.Cost
+		bucket.TokenCount += point.TokenCount
+		bucket.RecordCount += point.RecordCount
+	}
+
+	coalesced := make([]types.CostDataPoint, 0, len(keys))
+	for _, key := range keys {
+		coalesced = append(coalesced, *buckets[key])
+	}
+	return coalesced
+}
+
+// addCostSummary 相加兩個成本摘要的累計值（平均值需另行計算）
+func addCostSummary(a, b types.CostSummary) types.CostSummary {
+	currency := a.Currency
+	if currency == "" {
+		currency = b.Currency
+	}
+
+	return types.CostSummary{
+		TotalCost:   a.TotalCost + b.TotalCost,
+		TotalTokens: a.TotalTokens + b.TotalTokens,
+		RecordCount: a.RecordCount + b.RecordCount,
+
+		CacheCost:    a.CacheCost + b.CacheCost,
+		NonCacheCost: a.NonCacheCost + b.NonCacheCost,
+		CacheSavings: a.CacheSavings + b.CacheSavings,
+		Currency:     currency,
+	}
+}
+
+// unionTimeRange 取得兩個時間範圍的聯集（零值時間視為未設定）
+func unionTimeRange(a, b types.TimeRange) types.TimeRange {
+	result := a
+	if !b.Start.IsZero() && (result.Start.IsZero() || b.Start.Before(result.Start)) {
+		result.Start = b.Start
+	}
+	if !b.End.IsZero() && (result.End.IsZero() || b.End.After(result.End)) {
+		result.End = b.End
+	}
+	return result
+}
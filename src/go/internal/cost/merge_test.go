@@ -0,0 +1,98 @@
+package cost
+
+import (
+	"math"
+	"testing"
+	"time"
+	"token-monitor/internal/errors"
+	"token-monitor/internal/types"
+)
+
+// TestMergeCostReports 測試合併多份成本報告與單次產生的報告一致
+func TestMergeCostReports(t *testing.T) {
+	calculator := NewCostCalculator()
+	day1 := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+
+	records1 := []types.UsageRecord{
+		newTestRecord(day1, types.ActivityCoding, 1000, 2000, "claude-sonnet-4.0"),
+		newTestRecord(day1, types.ActivityChat, 500, 500, "claude-haiku-3.5"),
+	}
+	records2 := []types.UsageRecord{
+		newTestRecord(day2, types.ActivityCoding, 3000, 1000, "claude-sonnet-4.0"),
+	}
+
+	report1, err := calculator.GenerateCostReport(records1, &types.ReportOptions{
+		TimeRange: types.TimeRange{Start: day1, End: day1.Add(time.Hour)},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	report2, err := calculator.GenerateCostReport(records2, &types.ReportOptions{
+		TimeRange: types.TimeRange{Start: day2, End: day2.Add(time.Hour)},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	combined, err := calculator.GenerateCostReport(append(append([]types.UsageRecord{}, records1...), records2...), &types.ReportOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// 傳入順序不影響趨勢排序
+	merged := MergeCostReports(report2, nil, report1)
+
+	if merged.TotalRecords != 3 {
+		t.Errorf("Expected 3 records, got %d", merged.TotalRecords)
+	}
+	assertSummaryEqual(t, "summary", combined.Summary, merged.Summary)
+	for model, summary := range combined.ByModel {
+		assertSummaryEqual(t, model, summary, merged.ByModel[model])
+	}
+	for activityType, summary := range combined.ByActivity {
+		assertSummaryEqual(t, string(activityType), summary, merged.ByActivity[activityType])
+	}
+
+	if !merged.TimeRange.Start.Equal(day1) || !merged.TimeRange.End.Equal(day2.Add(time.Hour)) {
+		t.Errorf("Expected union time range, got %v - %v", merged.TimeRange.Start, merged.TimeRange.End)
+	}
+
+	if len(merged.Trends.DataPoints) != 2 {
+		t.Fatalf("Expected 2 trend points, got %d", len(merged.Trends.DataPoints))
+	}
+	if !merged.Trends.DataPoints[0].Timestamp.Before(merged.Trends.DataPoints[1].Timestamp) {
+		t.Errorf("Expected trend points sorted by timestamp")
+	}
+	if math.Abs(merged.Trends.TotalCost-combined.Trends.TotalCost) > 1e-9 {
+		t.Errorf("Expected trend total %f, got %f", combined.Trends.TotalCost, merged.Trends.TotalCost)
+	}
+
+	// 相同日期的報告合併後趨勢資料點相加，而非重複
+	sameDay := MergeCostReports(report1, report1)
+	if len(sameDay.Trends.DataPoints) != 1 {
+		t.Fatalf("Expected 1 coalesced trend point, got %d", len(sameDay.Trends.DataPoints))
+	}
+	point := sameDay.Trends.DataPoints[0]
+	if point.RecordCount != 4 || math.Abs(point.Cost-2*report1.Summary.TotalCost) > 1e-9 {
+		t.Errorf("Expected coalesced point with 4 records and cost %f, got %+v", 2*report1.Summary.TotalCost, point)
+	}
+
+	empty := MergeCostReports()
+	if empty == nil || empty.TotalRecords != 0 || len(empty.ByModel) != 0 {
+		t.Errorf("Expected empty merged report, got %+v", empty)
+	}
+
+	// 嚴格模式拒絕合併不同幣別的報告
+	strict, err := MergeCostReportsStrict(report1, report2)
+	if err != nil || strict.TotalRecords != 3 {
+		t.Errorf("Expected strict merge of same-currency reports, got %+v (%v)", strict, err)
+	}
+	other := *report2
+	other.Summary.Currency = "EUR"
+	if report1.Summary.Currency == "EUR" {
+		other.Summary.Currency = "USD"
+	}
+	if _, err := MergeCostReportsStrict(report1, &other); !errors.IsCode(err, errors.ErrCodeReportGeneration) {
+		t.Errorf("Expected currency mismatch error, got %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package cost
+
+import (
+	"fmt"
+	"token-monitor/internal/types"
+)
+
+// MigrationImpact 模型遷移的成本影響（Baseline 為原模型成本，Current 為目標模型成本）
+type MigrationImpact struct {
+	FromModel   string                           `json:"from_model"`
+	ToModel     string                           `json:"to_model"`
+	RecordCount int                              `json:"record_count"`
+	TotalTokens int                              `json:"total_tokens"`
+	Total       CostDelta                        `json:"total"`
+	ByActivity  map[types.ActivityType]CostDelta `json:"by_activity"`
+	// OtherModels 使用其他模型、未納入遷移計算的記錄（依實際模型計價）
+	OtherModels map[string]types.CostSummary `json:"other_models"`
+}
+
+// ProjectModelMigration 依每筆記錄實際的輸入/輸出 token，估算將 fromModel 的記錄改用 toModel 後的成本差異
+func (cc *CostCalculatorImpl) ProjectModelMigration(records []types.UsageRecord, fromModel, toModel string) (*MigrationImpact, error) {
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no usage records provided")
+	}
+
+	cc.mutex.RLock()
+	defer cc.mutex.RUnlock()
+
+	for _, model := range []string{fromModel, toModel} {
+		if _, err := cc.pricingEngine.GetPricingModel(model); err != nil {
+			return nil, fmt.Errorf("failed to get pricing model %s: %w", model, err)
+		}
+	}
+
+	defaultModel := cc.pricingEngine.GetDefaultModel()
+	impact := &MigrationImpact{
+		FromModel:   fromModel,
+		ToModel:     toModel,
+		ByActivity:  make(map[types.ActivityType]CostDelta),
+		OtherModels: make(map[string]types.CostSummary),
+	}
+
+	fromTotal, toTotal := 0.0, 0.0
+	fromByActivity := make(map[types.ActivityType]float64)
+	toByActivity := make(map[types.ActivityType]float64)
+
+	for _, record := range records {
+		model := record.Cost.PricingModel
+		if model == "" {
+			model = defaultModel
+		}
+
+		inputTokens, outputTokens := cc.recordTokensLocked(record)
+		if model != fromModel {
+			breakdown, err := cc.calculateCostLocked(inputTokens, outputTokens, model)
+			if err != nil {
+				continue
+			}
+			summary := impact.OtherModels[model]
+			summary.TotalCost += breakdown.TotalCost
+			summary.TotalTokens += record.Tokens.Total
+			summary.RecordCount++
+			impact.OtherModels[model] = withCostAverages(summary)
+			continue
+		}
+
+		fromBreakdown, err := cc.calculateCostLocked(inputTokens, outputTokens, fromModel)
+		if err != nil {
+			continue
+		}
+		toBreakdown, err := cc.calculateCostLocked(inputTokens, outputTokens, toModel)
+		if err != nil {
+			continue
+		}
+
+		impact.RecordCount++
+		impact.TotalTokens += record.Tokens.Total
+		fromTotal += fromBreakdown.TotalCost
+		toTotal += toBreakdown.TotalCost
+		fromByActivity[record.Activity.Type] += fromBreakdown.TotalCost
+		toByActivity[record.Activity.Type] += toBreakdown.TotalCost
+	}
+
+	impact.Total = newCostDelta(toTotal, fromTotal)
+	for activityType, fromCost := range fromByActivity {
+		impact.ByActivity[activityType] = newCostDelta(toByActivity[activityType], fromCost)
+	}
+
+	return impact, nil
+}
@@ -0,0 +1,60 @@
+package cost
+
+import (
+	"math"
+	"testing"
+	"time"
+	"token-monitor/internal/types"
+)
+
+// TestProjectModelMigration 測試模型遷移成本估算
+func TestProjectModelMigration(t *testing.T) {
+	calculator := NewCostCalculator()
+	now := time.Now()
+
+	records := []types.UsageRecord{
+		newTestRecord(now, types.ActivityCoding, 1000, 2000, "claude-sonnet-4.0"),
+		newTestRecord(now, types.ActivityChat, 1000, 1000, "claude-sonnet-4.0"),
+		newTestRecord(now, types.ActivityChat, 1000, 1000, ""), // 空模型視為預設模型
+		newTestRecord(now, types.ActivityCoding, 500, 500, "claude-opus-4.0"),
+	}
+
+	impact, err := calculator.ProjectModelMigration(records, "claude-sonnet-4.0", "claude-haiku-3.5")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if impact.RecordCount != 3 {
+		t.Errorf("Expected 3 migrated records, got %d", impact.RecordCount)
+	}
+
+	// sonnet: (3000*3 + 4000*15) / 1M = 0.069；haiku: (3000*0.8 + 4000*4) / 1M = 0.0184
+	if math.Abs(impact.Total.Baseline-0.069) > 1e-9 || math.Abs(impact.Total.Current-0.0184) > 1e-9 {
+		t.Errorf("Expected 0.069 -> 0.0184, got %f -> %f", impact.Total.Baseline, impact.Total.Current)
+	}
+	if math.Abs(impact.Total.Delta-(0.0184-0.069)) > 1e-9 {
+		t.Errorf("Expected delta %f, got %f", 0.0184-0.069, impact.Total.Delta)
+	}
+	if impact.Total.PercentChange >= 0 {
+		t.Errorf("Expected negative percent change, got %f", impact.Total.PercentChange)
+	}
+
+	if len(impact.ByActivity) != 2 {
+		t.Errorf("Expected 2 activities, got %d", len(impact.ByActivity))
+	}
+	if math.Abs(impact.ByActivity[types.ActivityCoding].Baseline-0.033) > 1e-9 {
+		t.Errorf("Expected coding baseline 0.033, got %f", impact.ByActivity[types.ActivityCoding].Baseline)
+	}
+
+	opus, exists := impact.OtherModels["claude-opus-4.0"]
+	if !exists || opus.RecordCount != 1 {
+		t.Errorf("Expected opus record to be reported separately, got %+v", impact.OtherModels)
+	}
+
+	if _, err := calculator.ProjectModelMigration(records, "claude-sonnet-4.0", "unknown-model"); err == nil {
+		t.Errorf("Expected error for unknown target model")
+	}
+	if _, err := calculator.ProjectModelMigration(nil, "claude-sonnet-4.0", "claude-haiku-3.5"); err == nil {
+		t.Errorf("Expected error for empty records")
+	}
+}
@@ -0,0 +1,139 @@
+package cost
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+	"token-monitor/internal/errors"
+	"token-monitor/internal/types"
+)
+
+// openAIUsage OpenAI 使用量格式，支援完整回應（usage 巢狀）或單獨的 usage 物件
+type openAIUsage struct {
+	ID               string           `json:"id"`
+	Model            string           `json:"model"`
+	Created          int64            `json:"created"`
+	PromptTokens     int              `json:"prompt_tokens"`
+	CompletionTokens int              `json:"completion_tokens"`
+	TotalTokens      int              `json:"total_tokens"`
+	Usage            *openAIUsageBody `json:"usage"`
+}
+
+// openAIUsageBody OpenAI 回應中的 usage 欄位
+type openAIUsageBody struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// SetOpenAIModelAliases 設定 OpenAI 模型名稱對應的定價模型（取代既有設定）
+func (cc *CostCalculatorImpl) SetOpenAIModelAliases(aliases map[string]string) {
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	cc.openAIModelAliases = make(map[string]string, len(aliases))
+	for openAIModel, pricingModel := range aliases {
+		cc.openAIModelAliases[openAIModel] = pricingModel
+	}
+}
+
+// FromOpenAIUsage 解析 OpenAI 使用量 JSON（單一物件或陣列）為使用記錄。
+// 模型名稱依別名對應到定價模型，無法對應的模型使用預設定價模型，匯入完成後每個模型記錄一次警告。
+func (cc *CostCalculatorImpl) FromOpenAIUsage(data []byte) ([]types.UsageRecord, error) {
+	cc.mutex.RLock()
+	defer cc.mutex.RUnlock()
+
+	var entries []openAIUsage
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &entries); err != nil {
+			return nil, fmt.Errorf("failed to parse OpenAI usage: %w", err)
+		}
+	} else {
+		var entry openAIUsage
+		if err := json.Unmarshal(trimmed, &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse OpenAI usage: %w", err)
+		}
+		entries = []openAIUsage{entry}
+	}
+
+	records := make([]types.UsageRecord, 0, len(entries))
+	unknownModels := make(map[string]int)
+	var unknownOrder []string
+	for i, entry := range entries {
+		promptTokens, completionTokens, totalTokens := entry.PromptTokens, entry.CompletionTokens, entry.TotalTokens
+		if entry.Usage != nil {
+			promptTokens, completionTokens, totalTokens = entry.Usage.PromptTokens, entry.Usage.CompletionTokens, entry.Usage.TotalTokens
+		}
+
+		if promptTokens < 0 || completionTokens < 0 {
+			return nil, fmt.Errorf("OpenAI usage entry %d has negative token counts: prompt=%d, completion=%d",
+				i, promptTokens, completionTokens)
+		}
+		if totalTokens == 0 {
+			totalTokens = promptTokens + completionTokens
+		}
+
+		record := types.UsageRecord{
+			Timestamp: time.Now(),
+			SessionID: entry.ID,
+		}
+		if entry.Created > 0 {
+			record.Timestamp = time.Unix(entry.Created, 0)
+		}
+		record.Tokens.Input = promptTokens
+		record.Tokens.Output = completionTokens
+		record.Tokens.Total = totalTokens
+		record.Tokens.CalculationMethod = "api"
+		record.Cost.Currency = "USD"
+		pricingModel, known := cc.resolveOpenAIModel(entry.Model)
+		if !known {
+			if unknownModels[entry.Model] == 0 {
+				unknownOrder = append(unknownOrder, entry.Model)
+			}
+			unknownModels[entry.Model]++
+		}
+		record.Cost.PricingModel = pricingModel
+
+		records = append(records, record)
+	}
+
+	for _, model := range unknownOrder {
+		cc.warnUnknownOpenAIModel(model, unknownModels[model])
+	}
+
+	return records, nil
+}
+
+// resolveOpenAIModel 將 OpenAI 模型名稱對應到定價模型，無法對應時回傳預設模型與 false
+func (cc *CostCalculatorImpl) resolveOpenAIModel(model string) (string, bool) {
+	if alias, exists := cc.openAIModelAliases[model]; exists {
+		model = alias
+	}
+
+	if _, err := cc.pricingEngine.GetPricingModel(model); model == "" || err != nil {
+		return cc.pricingEngine.GetDefaultModel(), false
+	}
+
+	return model, true
+}
+
+// warnUnknownOpenAIModel 透過錯誤處理器記錄無法對應的 OpenAI 模型
+func (cc *CostCalculatorImpl) warnUnknownOpenAIModel(model string, records int) {
+	defaultModel := cc.pricingEngine.GetDefaultModel()
+	warnErr := errors.Newf(errors.ErrCodeInvalidPricingModel,
+		"未知的 OpenAI 模型 '%s'（%d 筆記錄），使用預設定價模型 %s", model, records, defaultModel)
+	warnErr.Severity = errors.SeverityLow
+	warnErr = warnErr.WithContext(errors.ErrorContext{
+		Operation: "import_openai_usage",
+		Component: "cost_calculator",
+		Parameters: map[string]interface{}{
+			"openai_model":  model,
+			"record_count":  records,
+			"default_model": defaultModel,
+		},
+	})
+	cc.pricingEngine.errorHandler.Handle(context.Background(), warnErr)
+}
@@ -0,0 +1,100 @@
+package cost
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"token-monitor/internal/types"
+)
+
+// TestFromOpenAIUsage 測試解析 OpenAI 使用量格式
+func TestFromOpenAIUsage(t *testing.T) {
+	calculator := NewCostCalculator()
+	calculator.pricingEngine.AddPricingModel("gpt-4o", &types.PricingModel{Name: "gpt-4o", InputPrice: 2.5, OutputPrice: 10})
+	calculator.SetOpenAIModelAliases(map[string]string{"gpt-4o-2024-08-06": "gpt-4o"})
+
+	data := []byte(`[
+		{"id": "chatcmpl-1", "model": "gpt-4o-2024-08-06", "created": 1700000000,
+		 "usage": {"prompt_tokens": 100, "completion_tokens": 50, "total_tokens": 150}},
+		{"model": "gpt-unknown", "prompt_tokens": 10, "completion_tokens": 5}
+	]`)
+
+	records, err := calculator.FromOpenAIUsage(data)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+
+	first := records[0]
+	if first.Cost.PricingModel != "gpt-4o" {
+		t.Errorf("Expected alias to map to gpt-4o, got %s", first.Cost.PricingModel)
+	}
+	if first.Tokens.Input != 100 || first.Tokens.Output != 50 || first.Tokens.Total != 150 {
+		t.Errorf("Unexpected token counts: %+v", first.Tokens)
+	}
+	if !first.Timestamp.Equal(time.Unix(1700000000, 0)) || first.SessionID != "chatcmpl-1" {
+		t.Errorf("Unexpected timestamp or session: %v %s", first.Timestamp, first.SessionID)
+	}
+
+	// 未知模型使用預設定價模型，total 由輸入輸出推算
+	second := records[1]
+	if second.Cost.PricingModel != calculator.GetDefaultModel() {
+		t.Errorf("Expected unknown model to map to default %s, got %s", calculator.GetDefaultModel(), second.Cost.PricingModel)
+	}
+	if second.Tokens.Total != 15 {
+		t.Errorf("Expected derived total 15, got %d", second.Tokens.Total)
+	}
+}
+
+// TestFromOpenAIUsageSingleObject 測試單一物件與錯誤輸入
+func TestFromOpenAIUsageSingleObject(t *testing.T) {
+	calculator := NewCostCalculator()
+
+	records, err := calculator.FromOpenAIUsage([]byte(`{"model": "claude-haiku-3.5", "prompt_tokens": 3, "completion_tokens": 4}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(records) != 1 || records[0].Cost.PricingModel != "claude-haiku-3.5" {
+		t.Errorf("Expected single record with known model, got %+v", records)
+	}
+
+	if _, err := calculator.FromOpenAIUsage([]byte(`{invalid`)); err == nil {
+		t.Errorf("Expected error for invalid JSON")
+	}
+	if _, err := calculator.FromOpenAIUsage([]byte(`{"prompt_tokens": -1}`)); err == nil {
+		t.Errorf("Expected error for negative token counts")
+	}
+}
+
+// TestFromOpenAIUsageUnknownModelWarning 測試未知模型在每次匯入中只記錄一次警告
+func TestFromOpenAIUsageUnknownModelWarning(t *testing.T) {
+	calculator := NewCostCalculator()
+	logger := &recordingLogger{}
+	calculator.pricingEngine.errorHandler.SetLogger(logger)
+
+	data := []byte(`[
+		{"model": "gpt-unknown", "prompt_tokens": 1, "completion_tokens": 1},
+		{"model": "gpt-unknown", "prompt_tokens": 2, "completion_tokens": 2},
+		{"model": "gpt-other", "prompt_tokens": 3, "completion_tokens": 3},
+		{"model": "gpt-unknown", "prompt_tokens": 4, "completion_tokens": 4}
+	]`)
+	if _, err := calculator.FromOpenAIUsage(data); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	counts := map[string]int{}
+	for _, message := range logger.messages {
+		for _, model := range []string{"gpt-unknown", "gpt-other"} {
+			if strings.Contains(message, "'"+model+"'") {
+				counts[model]++
+			}
+		}
+	}
+	if counts["gpt-unknown"] != 1 || counts["gpt-other"] != 1 {
+		t.Errorf("Expected one warning per unknown model, got %v", counts)
+	}
+}
@@ -16,6 +16,11 @@ type Optimizer struct {
 	batchThreshold   int     // 批次處理閾值
 	confidenceMin    float64 // 最小信心度
 	minSaving        float64 // 最小節省金額（USD）
+
+	// 自訂信心度函式，nil 時使用預設的 calculate*Confidence；結果會截斷至 [0, 1]
+	CacheConfidenceFunc       func(stats ActivityStats, context *OptimizationContext) float64
+	BatchConfidenceFunc       func(pattern UsagePattern, context *OptimizationContext) float64
+	ModelSwitchConfidenceFunc func(activityType types.ActivityType, stats ActivityStats) float64
 }
 
 // OptimizationContext 優化分析上下文
@@ -41,6 +46,7 @@ type ActivityStats struct {
 
 // UsagePattern 使用模式
 type UsagePattern struct {
+	Key         string // 模式識別鍵（活動類型-token 範圍）
 	Type        string
 	Frequency   int
 	TokensUsed  int
@@ -77,36 +83,41 @@ func (o *Optimizer) AnalyzeAndSuggest(records []types.UsageRecord) (*types.Optim
 	}
 	
 	var suggestions []types.OptimizationSuggestion
-	totalSavings := 0.0
 	
 	// 分析快取機會
-	cacheSuggestions, cacheSavings := o.analyzeCacheOpportunities(context)
+	cacheSuggestions, _ := o.analyzeCacheOpportunities(context)
 	suggestions = append(suggestions, cacheSuggestions...)
-	totalSavings += cacheSavings
 	
 	// 分析批次處理機會
-	batchSuggestions, batchSavings := o.analyzeBatchOpportunities(context)
+	batchSuggestions, _ := o.analyzeBatchOpportunities(context)
 	suggestions = append(suggestions, batchSuggestions...)
-	totalSavings += batchSavings
 	
 	// 分析模型選擇優化
-	modelSuggestions, modelSavings := o.analyzeModelOptimization(context)
+	modelSuggestions, _ := o.analyzeModelOptimization(context)
 	suggestions = append(suggestions, modelSuggestions...)
-	totalSavings += modelSavings
 	
 	// 分析工作流程優化
-	workflowSuggestions, workflowSavings := o.analyzeWorkflowOptimization(context)
+	workflowSuggestions, _ := o.analyzeWorkflowOptimization(context)
 	suggestions = append(suggestions, workflowSuggestions...)
-	totalSavings += workflowSavings
 	
 	// 過濾低信心度和低節省的建議
 	filteredSuggestions := o.filterSuggestions(suggestions)
+
+	// 指派穩定識別碼；總節省與信心度加權節省皆只計入保留的建議
+	totalSavings := 0.0
+	weightedSavings := 0.0
+	for i := range filteredSuggestions {
+		filteredSuggestions[i].ID = SuggestionID(filteredSuggestions[i])
+		totalSavings += filteredSuggestions[i].PotentialSaving
+		weightedSavings += filteredSuggestions[i].PotentialSaving * filteredSuggestions[i].Confidence
+	}
 	
 	return &types.OptimizationSuggestions{
-		Suggestions:   filteredSuggestions,
-		TotalSavings:  totalSavings,
-		CurrentCost:   context.TotalCost,
-		OptimizedCost: context.TotalCost - totalSavings,
+		Suggestions:               filteredSuggestions,
+		TotalSavings:              totalSavings,
+		CurrentCost:               context.TotalCost,
+		OptimizedCost:             context.TotalCost - totalSavings,
+		ConfidenceWeightedSavings: weightedSavings,
 	}, nil
 }
 
@@ -180,10 +191,11 @@ func (o *Optimizer) analyzeCacheOpportunities(context *OptimizationContext) ([]t
 				saving := (originalCost.TotalCost - cachedCost.TotalCost) * float64(stats.Count)
 				
 				if saving > o.minSaving {
-					confidence := o.calculateCacheConfidence(stats, context)
+					confidence := o.cacheConfidence(stats, context)
 					
 					suggestions = append(suggestions, types.OptimizationSuggestion{
 						Type:            "cache",
+						Target:          string(activityType),
 						Description:     fmt.Sprintf("為 %s 活動啟用提示快取，可節省重複計算成本", activityType),
 						PotentialSaving: saving,
 						Confidence:      confidence,
@@ -210,10 +222,11 @@ func (o *Optimizer) analyzeBatchOpportunities(context *OptimizationContext) ([]t
 			batchSaving := pattern.Cost * 0.5 // 50% 批次折扣
 			
 			if batchSaving > o.minSaving {
-				confidence := o.calculateBatchConfidence(pattern, context)
+				confidence := o.batchConfidence(pattern, context)
 				
 				suggestions = append(suggestions, types.OptimizationSuggestion{
 					Type:            "batch",
+					Target:          pattern.Key,
 					Description:     fmt.Sprintf("將 %d 個相似請求合併為批次處理，享受50%%折扣", pattern.Frequency),
 					PotentialSaving: batchSaving,
 					Confidence:      confidence,
@@ -246,10 +259,11 @@ func (o *Optimizer) analyzeModelOptimization(context *OptimizationContext) ([]ty
 				saving := (currentCost.TotalCost - cheaperCost.TotalCost) * float64(stats.Count)
 				
 				if saving > o.minSaving {
-					confidence := o.calculateModelSwitchConfidence(activityType, stats)
+					confidence := o.modelSwitchConfidence(activityType, stats)
 					
 					suggestions = append(suggestions, types.OptimizationSuggestion{
 						Type:            "model-switch",
+						Target:          string(activityType),
 						Description:     fmt.Sprintf("對於 %s 活動使用 Claude Haiku 3.5 替代 Sonnet 4.0", activityType),
 						PotentialSaving: saving,
 						Confidence:      confidence,
@@ -281,6 +295,7 @@ func (o *Optimizer) analyzeWorkflowOptimization(context *OptimizationContext) ([
 				if potentialSaving > o.minSaving {
 					suggestions = append(suggestions, types.OptimizationSuggestion{
 						Type:            "workflow",
+						Target:          string(activityType),
 						Description:     fmt.Sprintf("優化 %s 工作流程，減少不必要的往返對話", activityType),
 						PotentialSaving: potentialSaving,
 						Confidence:      0.6, // 工作流程優化信心度相對較低
@@ -328,6 +343,7 @@ func (o *Optimizer) extractUsagePatterns(records []types.UsageRecord) []UsagePat
 			pattern.Cost += record.Cost.Total
 		} else {
 			patterns[patternKey] = &UsagePattern{
+				Key:        patternKey,
 				Type:       "repetitive",
 				Frequency:  1,
 				TokensUsed: record.Tokens.Total,
@@ -358,6 +374,38 @@ func (o *Optimizer) getTokenRange(tokens int) string {
 	}
 }
 
+// cacheConfidence 計算快取信心度，優先使用 CacheConfidenceFunc
+func (o *Optimizer) cacheConfidence(stats ActivityStats, context *OptimizationContext) float64 {
+	if o.CacheConfidenceFunc != nil {
+		return clampConfidence(o.CacheConfidenceFunc(stats, context))
+	}
+	return o.calculateCacheConfidence(stats, context)
+}
+
+// batchConfidence 計算批次處理信心度，優先使用 BatchConfidenceFunc
+func (o *Optimizer) batchConfidence(pattern UsagePattern, context *OptimizationContext) float64 {
+	if o.BatchConfidenceFunc != nil {
+		return clampConfidence(o.BatchConfidenceFunc(pattern, context))
+	}
+	return o.calculateBatchConfidence(pattern, context)
+}
+
+// modelSwitchConfidence 計算模型切換信心度，優先使用 ModelSwitchConfidenceFunc
+func (o *Optimizer) modelSwitchConfidence(activityType types.ActivityType, stats ActivityStats) float64 {
+	if o.ModelSwitchConfidenceFunc != nil {
+		return clampConfidence(o.ModelSwitchConfidenceFunc(activityType, stats))
+	}
+	return o.calculateModelSwitchConfidence(activityType, stats)
+}
+
+// clampConfidence 將信心度限制在 [0, 1]，NaN 視為 0
+func clampConfidence(confidence float64) float64 {
+	if math.IsNaN(confidence) {
+		return 0
+	}
+	return math.Max(0, math.Min(confidence, 1.0))
+}
+
 // calculateCacheConfidence 計算快取信心度
 func (o *Optimizer) calculateCacheConfidence(stats ActivityStats, context *OptimizationContext) float64 {
 	confidence := 0.5 // 基礎信心度
@@ -0,0 +1,73 @@
+package cost
+
+import (
+	"math"
+	"testing"
+	"token-monitor/internal/types"
+)
+
+// newTestOptimizationRecords 產生會觸發批次與模型切換建議的重複記錄
+func newTestOptimizationRecords() []types.UsageRecord {
+	var records []types.UsageRecord
+	for i := 0; i < 12; i++ {
+		record := types.UsageRecord{Activity: types.Activity{Type: types.ActivityChat}}
+		record.Tokens.Total = 20000
+		record.Cost.Total = 1.0
+		records = append(records, record)
+	}
+	return records
+}
+
+// TestOptimizerCustomConfidenceFuncs 測試自訂信心度函式取代預設實作
+func TestOptimizerCustomConfidenceFuncs(t *testing.T) {
+	records := newTestOptimizationRecords()
+
+	optimizer := NewOptimizer(NewPricingEngine())
+	optimizer.BatchConfidenceFunc = func(UsagePattern, *OptimizationContext) float64 { return 0.92 }
+	optimizer.ModelSwitchConfidenceFunc = func(types.ActivityType, ActivityStats) float64 { return 1.5 }
+
+	suggestions, err := optimizer.AnalyzeAndSuggest(records)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := map[string]float64{"batch": 0.92, "model-switch": 1.0}
+	for suggestionType, confidence := range expected {
+		matched := GetSuggestionsByType(suggestions, suggestionType)
+		if len(matched) == 0 {
+			t.Errorf("Expected %s suggestion", suggestionType)
+			continue
+		}
+		for _, suggestion := range matched {
+			if suggestion.Confidence != confidence {
+				t.Errorf("Expected %s confidence %.2f, got %.2f", suggestionType, confidence, suggestion.Confidence)
+			}
+		}
+	}
+
+	// 低於最小信心度的建議會被過濾
+	calculator := NewCostCalculator()
+	calculator.SetOptimizationConfidenceFuncs(nil, func(UsagePattern, *OptimizationContext) float64 { return 0.1 }, nil)
+	suggestions, err = calculator.CalculateOptimizationSavings(records)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(GetSuggestionsByType(suggestions, "batch")) != 0 {
+		t.Error("Expected low-confidence batch suggestions to be filtered")
+	}
+	if len(GetSuggestionsByType(suggestions, "model-switch")) == 0 {
+		t.Error("Expected default model-switch confidence to be kept")
+	}
+
+	// 總節省與優化後成本僅計入保留的建議，與信心度加權節省一致
+	keptSavings := 0.0
+	for _, suggestion := range suggestions.Suggestions {
+		keptSavings += suggestion.PotentialSaving
+	}
+	if math.Abs(suggestions.TotalSavings-keptSavings) > 1e-9 {
+		t.Errorf("Expected total savings %f from kept suggestions, got %f", keptSavings, suggestions.TotalSavings)
+	}
+	if math.Abs(suggestions.OptimizedCost-(suggestions.CurrentCost-keptSavings)) > 1e-9 {
+		t.Errorf("Expected optimized cost %f, got %f", suggestions.CurrentCost-keptSavings, suggestions.OptimizedCost)
+	}
+}
@@ -0,0 +1,35 @@
+package cost
+
+import (
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ExportPricing 將目前生效的定價模型、預設模型與驗證規則以 YAML 寫出（與 LoadFromConfig 讀取的格式相同）
+func (pe *PricingEngine) ExportPricing(w io.Writer) error {
+	pe.mutex.RLock()
+	config := PricingConfig{
+		Pricing:    make(map[string]PricingModelConfig, len(pe.models)),
+		Validation: make(map[string]ValidationRule, len(pe.validationRules)),
+		Default:    pe.defaultModel,
+	}
+	for name, model := range pe.models {
+		config.Pricing[name] = pricingModelConfigFrom(*model)
+	}
+	for name, rule := range pe.validationRules {
+		config.Validation[name] = rule
+	}
+	pe.mutex.RUnlock()
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&config); err != nil {
+		return fmt.Errorf("failed to encode pricing config: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("failed to flush pricing config: %w", err)
+	}
+	return nil
+}
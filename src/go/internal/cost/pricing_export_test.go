@@ -0,0 +1,45 @@
+package cost
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"token-monitor/internal/types"
+)
+
+// TestExportPricingRoundTrip 測試匯出的定價配置可重新載入為相同的定價
+func TestExportPricingRoundTrip(t *testing.T) {
+	engine := NewPricingEngine()
+	engine.AddPricingModel("custom-model", &types.PricingModel{
+		Name:               "custom-model",
+		InputPrice:         2.0,
+		OutputPrice:        10.0,
+		CacheRead:          0.2,
+		BatchDiscount:      0.4,
+		BatchDiscountScope: BatchDiscountScopeOutput,
+	})
+	if err := engine.SetDefaultModel("custom-model"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	engine.validationRules["custom-model"] = ValidationRule{MinPrice: 0.1, MaxPrice: 50}
+
+	var buf bytes.Buffer
+	if err := engine.ExportPricing(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	restored := NewPricingEngine()
+	if err := restored.LoadFromConfig(writeTestConfig(t, "pricing.yaml", buf.String())); err != nil {
+		t.Fatalf("Failed to reload exported pricing: %v", err)
+	}
+
+	if !reflect.DeepEqual(engine.models, restored.models) {
+		t.Errorf("Expected restored models to match:\n%+v\n%+v", engine.models, restored.models)
+	}
+	if restored.GetDefaultModel() != "custom-model" {
+		t.Errorf("Expected default model custom-model, got %s", restored.GetDefaultModel())
+	}
+	if !reflect.DeepEqual(engine.validationRules, restored.validationRules) {
+		t.Errorf("Expected validation rules to match, got %+v", restored.validationRules)
+	}
+}
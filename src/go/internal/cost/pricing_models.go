@@ -10,41 +10,98 @@ import (
 	"time"
 	"token-monitor/internal/errors"
 	"token-monitor/internal/types"
-	"gopkg.in/yaml.v3"
 )
 
 // PricingEngine 定價引擎
 type PricingEngine struct {
-	models           map[string]*types.PricingModel
-	mutex            sync.RWMutex
-	lastUpdate       time.Time
-	defaultModel     string
-	validationRules  map[string]ValidationRule
-	errorHandler     errors.ErrorHandler
+	models               map[string]*types.PricingModel
+	mutex                sync.RWMutex
+	lastUpdate           time.Time
+	defaultModel         string
+	validationRules      map[string]ValidationRule
+	errorHandler         errors.ErrorHandler
+	reloadCallbacks      []func()
+	warnOutputBelowInput bool // 輸出價格低於輸入價格時發出警告
+
+	// 已淘汰模型的警告時間，同一模型在 deprecationInterval 內只警告一次
+	deprecationMutex    sync.Mutex
+	deprecationWarned   map[string]time.Time
+	deprecationInterval time.Duration
+
+	// 自動重新整理（StartAutoRefresh），由 refreshMutex 保護
+	refreshMutex  sync.Mutex
+	refreshCancel context.CancelFunc
+	refreshDone   chan struct{}
+
+	// GetModelInfo 顯示價格使用的單位（RateUnitMTok 或 RateUnitKTok）
+	rateUnit string
 }
 
+// DefaultDeprecationWarningInterval 同一已淘汰模型兩次警告之間的預設間隔
+const DefaultDeprecationWarningInterval = time.Hour
+
 // ValidationRule 定價模型驗證規則
 type ValidationRule struct {
-	MinPrice     float64 `yaml:"min_price"`
-	MaxPrice     float64 `yaml:"max_price"`
-	RequireCache bool    `yaml:"require_cache"`
-	RequireBatch bool    `yaml:"require_batch"`
+	MinPrice     float64 `yaml:"min_price" json:"min_price"`
+	MaxPrice     float64 `yaml:"max_price" json:"max_price"`
+	RequireCache bool    `yaml:"require_cache" json:"require_cache"`
+	RequireBatch bool    `yaml:"require_batch" json:"require_batch"`
 }
 
 // PricingConfig 定價配置結構
 type PricingConfig struct {
-	Pricing    map[string]PricingModelConfig `yaml:"pricing"`
-	Validation map[string]ValidationRule     `yaml:"validation"`
-	Default    string                        `yaml:"default"`
+	Pricing    map[string]PricingModelConfig `yaml:"pricing" json:"pricing"`
+	Validation map[string]ValidationRule     `yaml:"validation" json:"validation"`
+	Default    string                        `yaml:"default" json:"default"`
 }
 
 // PricingModelConfig 配置文件中的定價模型
 type PricingModelConfig struct {
-	Input         float64 `yaml:"input"`
-	Output        float64 `yaml:"output"`
-	CacheRead     float64 `yaml:"cache_read"`
-	CacheWrite    float64 `yaml:"cache_write"`
-	BatchDiscount float64 `yaml:"batch_discount"`
+	Input         float64 `yaml:"input" json:"input"`
+	Output        float64 `yaml:"output" json:"output"`
+	CacheRead     float64 `yaml:"cache_read" json:"cache_read"`
+	CacheWrite    float64 `yaml:"cache_write" json:"cache_write"`
+	BatchDiscount float64 `yaml:"batch_discount" json:"batch_discount"`
+	Reasoning     float64 `yaml:"reasoning" json:"reasoning"`
+	// BatchDiscountScope 批次折扣適用範圍（all、output、input），空值視為 all
+	BatchDiscountScope string `yaml:"batch_discount_scope" json:"batch_discount_scope"`
+	Deprecated         bool   `yaml:"deprecated" json:"deprecated"`
+	ReplacedBy         string `yaml:"replaced_by" json:"replaced_by"`
+}
+
+// pricingModelConfigFrom 將定價模型轉為配置格式（用於驗證與匯出）
+func pricingModelConfigFrom(model types.PricingModel) PricingModelConfig {
+	return PricingModelConfig{
+		Input:         model.InputPrice,
+		Output:        model.OutputPrice,
+		CacheRead:     model.CacheRead,
+		CacheWrite:    model.CacheWrite,
+		BatchDiscount: model.BatchDiscount,
+		Reasoning:     model.ReasoningPrice,
+
+		BatchDiscountScope: model.BatchDiscountScope,
+		Deprecated:         model.Deprecated,
+		ReplacedBy:         model.ReplacedBy,
+	}
+}
+
+// 批次折扣適用範圍
+const (
+	BatchDiscountScopeAll    = "all"
+	BatchDiscountScopeOutput = "output"
+	BatchDiscountScopeInput  = "input"
+)
+
+// batchDiscountTargets 取得批次折扣是否適用於輸入與輸出（推理 Token 視為輸出）
+func batchDiscountTargets(scope string) (input, output bool) {
+	switch scope {
+	case BatchDiscountScopeOutput:
+		return false, true
+	case BatchDiscountScopeInput:
+		return true, false
+	default:
+		return true, true
+	}
 }
 
 // NewPricingEngine 創建新的定價引擎
@@ -54,6 +111,11 @@ func NewPricingEngine() *PricingEngine {
 		validationRules: make(map[string]ValidationRule),
 		defaultModel:    "claude-sonnet-4.0",
 		errorHandler:    errors.NewErrorHandler(),
+
+		deprecationWarned:   make(map[string]time.Time),
+		deprecationInterval: DefaultDeprecationWarningInterval,
+
+		rateUnit: RateUnitMTok,
 	}
 	
 	// 載入預設模型
@@ -71,8 +133,43 @@ func (pe *PricingEngine) AddPricingModel(name string, model *types.PricingModel)
 	pe.lastUpdate = time.Now()
 }
 
-// LoadFromConfig 從配置文件載入定價模型
+// LoadFromConfig 從配置文件載入定價模型（依副檔名解析 JSON 或 YAML）
 func (pe *PricingEngine) LoadFromConfig(configPath string) error {
+	reloaded, err := pe.loadFromConfig(configPath)
+	if err != nil || !reloaded {
+		return err
+	}
+	
+	pe.fireReloadCallbacks()
+	return nil
+}
+
+// fireReloadCallbacks 執行重新載入回呼（在釋放鎖之後通知，讓回呼可以安全地查詢定價引擎）
+func (pe *PricingEngine) fireReloadCallbacks() {
+	pe.mutex.RLock()
+	callbacks := make([]func(), len(pe.reloadCallbacks))
+	copy(callbacks, pe.reloadCallbacks)
+	pe.mutex.RUnlock()
+	
+	for _, callback := range callbacks {
+		callback()
+	}
+}
+
+// OnReload 註冊定價重新載入成功後要執行的回呼（例如清除成本快取）
+func (pe *PricingEngine) OnReload(callback func()) {
+	if callback == nil {
+		return
+	}
+	
+	pe.mutex.Lock()
+	defer pe.mutex.Unlock()
+	
+	pe.reloadCallbacks = append(pe.reloadCallbacks, callback)
+}
+
+// loadFromConfig 在鎖內載入配置，回傳是否實際更新了定價模型
+func (pe *PricingEngine) loadFromConfig(configPath string) (bool, error) {
 	ctx := context.Background()
 	pe.mutex.Lock()
 	defer pe.mutex.Unlock()
@@ -88,13 +185,13 @@ func (pe *PricingEngine) LoadFromConfig(configPath string) error {
 			},
 		})
 		pe.errorHandler.Handle(ctx, warnErr)
-		return nil
+		return false, nil
 	}
 	
 	// 讀取配置文件
 	data, err := os.ReadFile(configPath)
 	if err != nil {
-		appErr := errors.Wrap(err, errors.ErrCodeConfigLoad, "讀取配置文件失敗")
+		appErr := errors.Wrapf(err, errors.ErrCodeConfigLoad, "讀取配置文件失敗: %v", err)
 		appErr = appErr.WithContext(errors.ErrorContext{
 			Operation:  "read_config_file",
 			Component:  "pricing_engine",
@@ -102,12 +199,12 @@ func (pe *PricingEngine) LoadFromConfig(configPath string) error {
 				"config_path": configPath,
 			},
 		})
-		return pe.errorHandler.Handle(ctx, appErr)
+		return false, pe.errorHandler.Handle(ctx, appErr)
 	}
 	
 	var config PricingConfig
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		appErr := errors.Wrap(err, errors.ErrCodeInvalidConfigFormat, "配置文件格式無效")
+	if err := unmarshalPricingConfig(configPath, data, &config); err != nil {
+		appErr := errors.Wrapf(err, errors.ErrCodeInvalidConfigFormat, "配置文件格式無效: %v", err)
 		appErr = appErr.WithContext(errors.ErrorContext{
 			Operation:  "parse_config_file",
 			Component:  "pricing_engine",
@@ -115,7 +212,7 @@ func (pe *PricingEngine) LoadFromConfig(configPath string) error {
 				"config_path": configPath,
 			},
 		})
-		return pe.errorHandler.Handle(ctx, appErr)
+		return false, pe.errorHandler.Handle(ctx, appErr)
 	}
 	
 	// 清除現有模型
@@ -137,15 +234,23 @@ func (pe *PricingEngine) LoadFromConfig(configPath string) error {
 		}
 		
 		pe.models[name] = &types.PricingModel{
-			Name:          name,
-			InputPrice:    modelConfig.Input,
-			OutputPrice:   modelConfig.Output,
-			CacheRead:     modelConfig.CacheRead,
-			CacheWrite:    modelConfig.CacheWrite,
-			BatchDiscount: modelConfig.BatchDiscount,
+			Name:           name,
+			InputPrice:     modelConfig.Input,
+			OutputPrice:    modelConfig.Output,
+			CacheRead:      modelConfig.CacheRead,
+			CacheWrite:     modelConfig.CacheWrite,
+			BatchDiscount:  modelConfig.BatchDiscount,
+			ReasoningPrice: modelConfig.Reasoning,
+
+			BatchDiscountScope: modelConfig.BatchDiscountScope,
+			Deprecated:         modelConfig.Deprecated,
+			ReplacedBy:         modelConfig.ReplacedBy,
 		}
 	}
 	
+	// 沒有任何有效模型時還原預設模型，避免所有計算都失敗
+	pe.restoreDefaultsIfEmptyLocked(ctx, configPath)
+	
 	// 載入驗證規則
 	pe.validationRules = config.Validation
 	
@@ -157,13 +262,20 @@ func (pe *PricingEngine) LoadFromConfig(configPath string) error {
 	pe.lastUpdate = time.Now()
 	log.Printf("Loaded %d pricing models from config", len(pe.models))
 	
-	return nil
+	return true, nil
 }
 
-// GetPricingModel 取得定價模型
+// SetOutputBelowInputWarning 設定載入配置時是否對輸出價格低於輸入價格的模型發出低嚴重度警告（預設關閉）
+func (pe *PricingEngine) SetOutputBelowInputWarning(enabled bool) {
+	pe.mutex.Lock()
+	defer pe.mutex.Unlock()
+	
+	pe.warnOutputBelowInput = enabled
+}
+
+// GetPricingModel 取得定價模型，模型已淘汰時發出低嚴重度警告（同一模型依間隔限制次數）
 func (pe *PricingEngine) GetPricingModel(name string) (*types.PricingModel, error) {
 	pe.mutex.RLock()
-	defer pe.mutex.RUnlock()
 	
 	// 如果名稱為空，使用預設模型
 	if name == "" {
@@ -171,12 +283,55 @@ func (pe *PricingEngine) GetPricingModel(name string) (*types.PricingModel, erro
 	}
 	
 	model, exists := pe.models[name]
+	pe.mutex.RUnlock()
 	if !exists {
 		return nil, errors.Newf(errors.ErrCodeInvalidPricingModel, "定價模型 '%s' 不存在", name)
 	}
+
+	if model.Deprecated {
+		pe.warnDeprecated(name, model)
+	}
 	return model, nil
 }
 
+// SetDeprecationWarningInterval 設定同一已淘汰模型兩次警告之間的間隔（<= 0 表示每個模型只警告一次）
+func (pe *PricingEngine) SetDeprecationWarningInterval(interval time.Duration) {
+	pe.deprecationMutex.Lock()
+	defer pe.deprecationMutex.Unlock()
+
+	pe.deprecationInterval = interval
+}
+
+// warnDeprecated 透過錯誤處理器發出已淘汰模型的警告
+func (pe *PricingEngine) warnDeprecated(name string, model *types.PricingModel) {
+	now := time.Now()
+
+	pe.deprecationMutex.Lock()
+	last, warned := pe.deprecationWarned[name]
+	if warned && (pe.deprecationInterval <= 0 || now.Sub(last) < pe.deprecationInterval) {
+		pe.deprecationMutex.Unlock()
+		return
+	}
+	pe.deprecationWarned[name] = now
+	pe.deprecationMutex.Unlock()
+
+	message := fmt.Sprintf("定價模型 '%s' 已淘汰", name)
+	if model.ReplacedBy != "" {
+		message += fmt.Sprintf("，請改用 '%s'", model.ReplacedBy)
+	}
+
+	warnErr := errors.New(errors.ErrCodeDeprecatedModel, message)
+	warnErr = warnErr.WithContext(errors.ErrorContext{
+		Operation:  "get_pricing_model",
+		Component:  "pricing_engine",
+		Parameters: map[string]interface{}{
+			"model_name":  name,
+			"replaced_by": model.ReplacedBy,
+		},
+	})
+	pe.errorHandler.Handle(context.Background(), warnErr)
+}
+
 // GetSupportedModels 取得支援的模型列表
 func (pe *PricingEngine) GetSupportedModels() []string {
 	pe.mutex.RLock()
@@ -231,10 +386,37 @@ func (pe *PricingEngine) validateModelConfig(name string, config PricingModelCon
 	if config.CacheRead < 0 || config.CacheWrite < 0 {
 		return fmt.Errorf("cache prices cannot be negative")
 	}
+
+	if config.Reasoning < 0 {
+		return fmt.Errorf("reasoning price cannot be negative")
+	}
 	
 	if config.BatchDiscount < 0 || config.BatchDiscount > 1 {
 		return fmt.Errorf("batch discount must be between 0 and 1")
 	}
+
+	switch config.BatchDiscountScope {
+	case "", BatchDiscountScopeAll, BatchDiscountScopeOutput, BatchDiscountScopeInput:
+	default:
+		return fmt.Errorf("invalid batch discount scope: %s", config.BatchDiscountScope)
+	}
+	
+	// 輸出價格通常高於輸入價格，低於時多半是設定時對調了價格
+	if pe.warnOutputBelowInput && config.Output < config.Input {
+		warnErr := errors.Newf(errors.ErrCodeConfigValidation,
+			"定價模型 '%s' 的輸出價格 (%.4f) 低於輸入價格 (%.4f)，請確認是否對調", name, config.Output, config.Input)
+		warnErr.Severity = errors.SeverityLow
+		warnErr = warnErr.WithContext(errors.ErrorContext{
+			Operation:  "validate_model_config",
+			Component:  "pricing_engine",
+			Parameters: map[string]interface{}{
+				"model_name":   name,
+				"input_price":  config.Input,
+				"output_price": config.Output,
+			},
+		})
+		pe.errorHandler.Handle(context.Background(), warnErr)
+	}
 	
 	// 如果有特定驗證規則
 	if rule, exists := pe.validationRules[name]; exists {
@@ -270,10 +452,10 @@ func (pe *PricingEngine) GetModelInfo(name string) (map[string]interface{}, erro
 	
 	info := map[string]interface{}{
 		"name":            model.Name,
-		"input_price":     fmt.Sprintf("$%.2f/MTok", model.InputPrice),
-		"output_price":    fmt.Sprintf("$%.2f/MTok", model.OutputPrice),
-		"cache_read":      fmt.Sprintf("$%.2f/MTok", model.CacheRead),
-		"cache_write":     fmt.Sprintf("$%.2f/MTok", model.CacheWrite),
+		"input_price":     FormatRate(model.InputPrice, pe.rateUnit),
+		"output_price":    FormatRate(model.OutputPrice, pe.rateUnit),
+		"cache_read":      FormatRate(model.CacheRead, pe.rateUnit),
+		"cache_write":     FormatRate(model.CacheWrite, pe.rateUnit),
 		"batch_discount":  fmt.Sprintf("%.0f%%", model.BatchDiscount*100),
 		"cost_ratio":      model.OutputPrice / model.InputPrice,
 		"cache_savings":   (model.InputPrice - model.CacheRead) / model.InputPrice * 100,
@@ -282,6 +464,23 @@ func (pe *PricingEngine) GetModelInfo(name string) (map[string]interface{}, erro
 	return info, nil
 }
 
+// GetModelCostRatio 取得模型輸出/輸入價格比，輸入價格為 0 時回傳錯誤
+func (pe *PricingEngine) GetModelCostRatio(name string) (float64, error) {
+	pe.mutex.RLock()
+	defer pe.mutex.RUnlock()
+
+	model, exists := pe.models[name]
+	if !exists {
+		return 0, fmt.Errorf("model '%s' not found", name)
+	}
+
+	if model.InputPrice == 0 {
+		return 0, fmt.Errorf("model '%s' has zero input price", name)
+	}
+
+	return model.OutputPrice / model.InputPrice, nil
+}
+
 // GetModelComparison 取得模型比較資訊
 func (pe *PricingEngine) GetModelComparison() []map[string]interface{} {
 	pe.mutex.RLock()
@@ -307,35 +506,67 @@ func (pe *PricingEngine) GetModelComparison() []map[string]interface{} {
 
 // LoadDefaultModels 載入預設定價模型
 func (pe *PricingEngine) LoadDefaultModels() {
-	// Claude Sonnet 4.0
-	pe.AddPricingModel("claude-sonnet-4.0", &types.PricingModel{
-		Name:          "claude-sonnet-4.0",
-		InputPrice:    3.0,   // $3/MTok
-		OutputPrice:   15.0,  // $15/MTok
-		CacheRead:     0.30,  // $0.30/MTok
-		CacheWrite:    3.75,  // $3.75/MTok
-		BatchDiscount: 0.5,   // 50% discount
-	})
+	for name, model := range defaultPricingModels() {
+		pe.AddPricingModel(name, model)
+	}
+}
 
-	// Claude Opus 4.0
-	pe.AddPricingModel("claude-opus-4.0", &types.PricingModel{
-		Name:          "claude-opus-4.0",
-		InputPrice:    15.0,  // $15/MTok
-		OutputPrice:   75.0,  // $75/MTok
-		CacheRead:     1.5,   // $1.5/MTok
-		CacheWrite:    18.75, // $18.75/MTok
-		BatchDiscount: 0.5,   // 50% discount
-	})
+// restoreDefaultsIfEmptyLocked 模型為空時還原預設模型並透過錯誤處理器發出警告，回傳是否已還原（呼叫者須持有 mutex）
+func (pe *PricingEngine) restoreDefaultsIfEmptyLocked(ctx context.Context, configPath string) bool {
+	if len(pe.models) > 0 {
+		return false
+	}
+
+	pe.models = defaultPricingModels()
+	if pe.models[pe.defaultModel] == nil {
+		pe.defaultModel = "claude-sonnet-4.0"
+	}
 
-	// Claude Haiku 3.5
-	pe.AddPricingModel("claude-haiku-3.5", &types.PricingModel{
-		Name:          "claude-haiku-3.5",
-		InputPrice:    0.8, // $0.8/MTok
-		OutputPrice:   4.0, // $4/MTok
-		CacheRead:     0.08, // $0.08/MTok
-		CacheWrite:    1.0,  // $1.0/MTok
-		BatchDiscount: 0.5,  // 50% discount
+	warnErr := errors.New(errors.ErrCodePricingDataMissing, "配置文件沒有有效的定價模型，已還原預設模型")
+	warnErr = warnErr.WithContext(errors.ErrorContext{
+		Operation: "load_config",
+		Component: "pricing_engine",
+		Parameters: map[string]interface{}{
+			"config_path": configPath,
+		},
 	})
+	pe.errorHandler.Handle(ctx, warnErr)
+	return true
+}
+
+// defaultPricingModels 建立預設定價模型（每次呼叫回傳新的實例）
+func defaultPricingModels() map[string]*types.PricingModel {
+	return map[string]*types.PricingModel{
+		// Claude Sonnet 4.0
+		"claude-sonnet-4.0": {
+			Name:          "claude-sonnet-4.0",
+			InputPrice:    3.0,  // $3/MTok
+			OutputPrice:   15.0, // $15/MTok
+			CacheRead:     0.30, // $0.30/MTok
+			CacheWrite:    3.75, // $3.75/MTok
+			BatchDiscount: 0.5,  // 50% discount
+		},
+
+		// Claude Opus 4.0
+		"claude-opus-4.0": {
+			Name:          "claude-opus-4.0",
+			InputPrice:    15.0,  // $15/MTok
+			OutputPrice:   75.0,  // $75/MTok
+			CacheRead:     1.5,   // $1.5/MTok
+			CacheWrite:    18.75, // $18.75/MTok
+			BatchDiscount: 0.5,   // 50% discount
+		},
+
+		// Claude Haiku 3.5
+		"claude-haiku-3.5": {
+			Name:          "claude-haiku-3.5",
+			InputPrice:    0.8,  // $0.8/MTok
+			OutputPrice:   4.0,  // $4/MTok
+			CacheRead:     0.08, // $0.08/MTok
+			CacheWrite:    1.0,  // $1.0/MTok
+			BatchDiscount: 0.5,  // 50% discount
+		},
+	}
 }
 
 // CalculateBasicCost 計算基本成本（不含快取和批次折扣）
@@ -429,11 +660,16 @@ func (pe *PricingEngine) CalculateCostWithBatchDiscount(inputTokens, outputToken
 			return nil, err
 		}
 
-		// 應用批次折扣
+		// 依適用範圍應用批次折扣
 		discountMultiplier := 1.0 - model.BatchDiscount
-		breakdown.InputCost *= discountMultiplier
-		breakdown.OutputCost *= discountMultiplier
-		breakdown.TotalCost *= discountMultiplier
+		discountInput, discountOutput := batchDiscountTargets(model.BatchDiscountScope)
+		if discountInput {
+			breakdown.InputCost *= discountMultiplier
+		}
+		if discountOutput {
+			breakdown.OutputCost *= discountMultiplier
+		}
+		breakdown.TotalCost = breakdown.InputCost + breakdown.OutputCost
 	}
 
 	return breakdown, nil
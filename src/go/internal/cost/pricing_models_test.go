@@ -0,0 +1,326 @@
+package cost
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"token-monitor/internal/types"
+)
+
+// TestPricingEngineOnReload 測試定價重新載入回呼
+func TestPricingEngineOnReload(t *testing.T) {
+	engine := NewPricingEngine()
+
+	calls := make([]string, 0)
+	engine.OnReload(func() { calls = append(calls, "first") })
+	engine.OnReload(func() {
+		// 回呼中可以安全地查詢定價引擎
+		if _, err := engine.GetPricingModel("custom-model"); err != nil {
+			t.Errorf("Expected reloaded model in callback, got %v", err)
+		}
+		calls = append(calls, "second")
+	})
+
+	configPath := filepath.Join(t.TempDir(), "pricing.yaml")
+	config := "pricing:\n  custom-model:\n    input: 1.0\n    output: 2.0\ndefault: custom-model\n"
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	if err := engine.LoadFromConfig(configPath); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(calls) != 2 || calls[0] != "first" || calls[1] != "second" {
+		t.Errorf("Expected both callbacks to fire in order, got %v", calls)
+	}
+
+	// 配置文件不存在或格式錯誤時不應觸發回呼
+	calls = calls[:0]
+	engine.LoadFromConfig(filepath.Join(t.TempDir(), "missing.yaml"))
+
+	invalidPath := filepath.Join(t.TempDir(), "invalid.yaml")
+	if err := os.WriteFile(invalidPath, []byte("pricing: [::"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if err := engine.LoadFromConfig(invalidPath); err == nil {
+		t.Errorf("Expected error for invalid config")
+	}
+
+	if len(calls) != 0 {
+		t.Errorf("Expected no callbacks on failed reload, got %v", calls)
+	}
+}
+
+// recordingLogger 記錄錯誤處理器輸出的測試用日誌記錄器
+type recordingLogger struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (l *recordingLogger) record(message string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.messages = append(l.messages, message)
+}
+
+func (l *recordingLogger) Error(ctx context.Context, err error, fields map[string]interface{}) {
+	l.record(err.Error())
+}
+
+func (l *recordingLogger) Warn(ctx context.Context, message string, fields map[string]interface{}) {
+	l.record(message)
+}
+
+func (l *recordingLogger) Info(ctx context.Context, message string, fields map[string]interface{}) {
+	l.record(message)
+}
+
+func (l *recordingLogger) Debug(ctx context.Context, message string, fields map[string]interface{}) {
+	l.record(message)
+}
+
+// TestOutputBelowInputWarning 測試輸出價格低於輸入價格的警告
+func TestOutputBelowInputWarning(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "pricing.yaml")
+	config := "pricing:\n  swapped-model:\n    input: 15.0\n    output: 3.0\n"
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	countWarnings := func(enabled bool) int {
+		engine := NewPricingEngine()
+		logger := &recordingLogger{}
+		engine.errorHandler.SetLogger(logger)
+		engine.SetOutputBelowInputWarning(enabled)
+
+		if err := engine.LoadFromConfig(configPath); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		// 警告不應阻止模型載入
+		if _, err := engine.GetPricingModel("swapped-model"); err != nil {
+			t.Errorf("Expected model to load despite warning, got %v", err)
+		}
+
+		logger.mu.Lock()
+		defer logger.mu.Unlock()
+		count := 0
+		for _, message := range logger.messages {
+			if strings.Contains(message, "swapped-model") {
+				count++
+			}
+		}
+		return count
+	}
+
+	if count := countWarnings(false); count != 0 {
+		t.Errorf("Expected no warning by default, got %d", count)
+	}
+	if count := countWarnings(true); count != 1 {
+		t.Errorf("Expected 1 warning when enabled, got %d", count)
+	}
+}
+
+// TestGetModelCostRatio 測試模型輸出/輸入價格比
+func TestGetModelCostRatio(t *testing.T) {
+	pe := NewPricingEngine()
+
+	ratio, err := pe.GetModelCostRatio("claude-sonnet-4.0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ratio != 5.0 {
+		t.Errorf("Expected cost ratio 5.0, got %f", ratio)
+	}
+
+	pe.AddPricingModel("free-input", &types.PricingModel{Name: "free-input", OutputPrice: 1.0})
+	if _, err := pe.GetModelCostRatio("free-input"); err == nil {
+		t.Errorf("Expected error for zero input price")
+	}
+
+	if _, err := pe.GetModelCostRatio("unknown-model"); err == nil {
+		t.Errorf("Expected error for unknown model")
+	}
+}
+
+// TestBatchDiscountScopeConfig 測試從配置載入批次折扣範圍並拒絕無效值
+func TestBatchDiscountScopeConfig(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "pricing.yaml")
+	config := "pricing:\n" +
+		"  output-only:\n    input: 1.0\n    output: 2.0\n    batch_discount: 0.5\n    batch_discount_scope: output\n" +
+		"  bad-scope:\n    input: 1.0\n    output: 2.0\n    batch_discount: 0.5\n    batch_discount_scope: cache\n"
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	engine := NewPricingEngine()
+	engine.errorHandler.SetLogger(&recordingLogger{})
+	if err := engine.LoadFromConfig(configPath); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	model, err := engine.GetPricingModel("output-only")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if model.BatchDiscountScope != BatchDiscountScopeOutput {
+		t.Errorf("Expected scope %q, got %q", BatchDiscountScopeOutput, model.BatchDiscountScope)
+	}
+
+	// 無效範圍的模型不應被載入
+	if _, err := engine.GetPricingModel("bad-scope"); err == nil {
+		t.Errorf("Expected model with invalid scope to be rejected")
+	}
+}
+
+// TestDeprecatedModelWarning 測試使用已淘汰模型時發出有次數限制的警告
+func TestDeprecatedModelWarning(t *testing.T) {
+	calculator := NewCostCalculator()
+	logger := &recordingLogger{}
+	calculator.pricingEngine.errorHandler.SetLogger(logger)
+	calculator.pricingEngine.AddPricingModel("legacy-model", &types.PricingModel{
+		Name:        "legacy-model",
+		InputPrice:  1.0,
+		OutputPrice: 2.0,
+		Deprecated:  true,
+		ReplacedBy:  "claude-sonnet-4.0",
+	})
+
+	countWarnings := func() int {
+		logger.mu.Lock()
+		defer logger.mu.Unlock()
+		count := 0
+		for _, message := range logger.messages {
+			if strings.Contains(message, "legacy-model") && strings.Contains(message, "claude-sonnet-4.0") {
+				count++
+			}
+		}
+		return count
+	}
+
+	// 淘汰模型仍可正常計算，且間隔內只警告一次
+	for i := 0; i < 3; i++ {
+		if _, err := calculator.CalculateCost(1000, 1000, "legacy-model"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if count := countWarnings(); count != 1 {
+		t.Errorf("Expected 1 warning within interval, got %d", count)
+	}
+
+	// 間隔過後再次警告
+	calculator.pricingEngine.SetDeprecationWarningInterval(time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if _, err := calculator.CalculateCost(1000, 1000, "legacy-model"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	warnings := countWarnings()
+	if warnings < 2 {
+		t.Errorf("Expected another warning after interval, got %d", warnings)
+	}
+
+	// 未淘汰的模型不警告
+	if _, err := calculator.CalculateCost(1000, 1000, "claude-haiku-3.5"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if count := countWarnings(); count != warnings {
+		t.Errorf("Expected no warning for current model, got %d", count-warnings)
+	}
+}
+
+// TestLoadPricingModelsKeepsDeprecation 測試成本計算器載入配置時保留淘汰標記與替代模型
+func TestLoadPricingModelsKeepsDeprecation(t *testing.T) {
+	config := "pricing:\n  legacy-model:\n    input: 1.0\n    output: 2.0\n    deprecated: true\n    replaced_by: claude-sonnet-4.0\n"
+
+	calculator := NewCostCalculator()
+	if err := calculator.LoadPricingModels(writeTestConfig(t, "pricing.yaml", config)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	model, err := calculator.pricingEngine.GetPricingModel("legacy-model")
+	if err != nil {
+		t.Fatalf("Expected legacy-model to be loaded: %v", err)
+	}
+	if !model.Deprecated || model.ReplacedBy != "claude-sonnet-4.0" {
+		t.Errorf("Expected deprecation flags to be kept, got %+v", model)
+	}
+}
+
+// TestLoadConfigWithOnlyInvalidModelsRestoresDefaults 測試配置沒有有效模型時還原預設模型並發出警告
+func TestLoadConfigWithOnlyInvalidModelsRestoresDefaults(t *testing.T) {
+	config := "pricing:\n  broken-model:\n    input: -1.0\n    output: 3.0\n  bad-discount:\n    input: 1.0\n    output: 3.0\n    batch_discount: 2.0\n"
+	configPath := filepath.Join(t.TempDir(), "pricing.yaml")
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	engine := NewPricingEngine()
+	logger := &recordingLogger{}
+	engine.errorHandler.SetLogger(logger)
+
+	if err := engine.LoadFromConfig(configPath); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := engine.GetPricingModel(""); err != nil {
+		t.Errorf("Expected default model to be usable after restore, got %v", err)
+	}
+	if len(engine.GetSupportedModels()) != len(defaultPricingModels()) {
+		t.Errorf("Expected default models to be restored, got %v", engine.GetSupportedModels())
+	}
+
+	logger.mu.Lock()
+	warned := false
+	for _, message := range logger.messages {
+		if strings.Contains(message, "已還原預設模型") {
+			warned = true
+		}
+	}
+	logger.mu.Unlock()
+	if !warned {
+		t.Error("Expected a warning about restored default models")
+	}
+
+	// 成本計算器的載入路徑同樣驗證模型並還原預設模型
+	for _, content := range []string{"pricing: {}\n", config} {
+		calculator := NewCostCalculator()
+		if err := calculator.LoadPricingModels(writeTestConfig(t, "pricing.yaml", content)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := calculator.CalculateCost(1000, 1000, ""); err != nil {
+			t.Errorf("Expected cost calculation to work after restore, got %v", err)
+		}
+		if len(calculator.GetSupportedModels()) != len(defaultPricingModels()) {
+			t.Errorf("Expected only default models after restore, got %v", calculator.GetSupportedModels())
+		}
+	}
+}
+
+// TestLoadPricingModelsConcurrent 測試載入定價模型與查詢並行時不發生資料競爭（以 -race 執行）
+func TestLoadPricingModelsConcurrent(t *testing.T) {
+	configPath := writeTestConfig(t, "pricing.yaml", "pricing:\n  test-model:\n    input: 2.0\n    output: 10.0\n")
+	calculator := NewCostCalculator()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if err := calculator.LoadPricingModels(configPath); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			_ = calculator.pricingEngine.GetSupportedModels()
+			_, _ = calculator.pricingEngine.GetPricingModel("test-model")
+		}()
+	}
+	wg.Wait()
+
+	if _, err := calculator.pricingEngine.GetPricingModel("test-model"); err != nil {
+		t.Errorf("Expected test-model to be loaded, got %v", err)
+	}
+}
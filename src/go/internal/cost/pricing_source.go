@@ -0,0 +1,129 @@
+package cost
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"token-monitor/internal/errors"
+	"token-monitor/internal/types"
+)
+
+// PricingSource 定價資料來源（例如遠端定價服務），回傳模型名稱 -> 定價模型
+type PricingSource interface {
+	Fetch(ctx context.Context) (map[string]types.PricingModel, error)
+}
+
+// DefaultPricingRefreshInterval StartAutoRefresh 間隔無效時使用的預設間隔
+const DefaultPricingRefreshInterval = time.Hour
+
+// RefreshFromSource 從定價來源取得模型並一次性替換現有模型，成功後執行重新載入回呼。
+// 無效的模型會被略過；取得失敗或沒有有效模型時保留現有模型並回傳錯誤。
+// 預設模型不在新模型中時沿用原設定。
+func (pe *PricingEngine) RefreshFromSource(ctx context.Context, source PricingSource) error {
+	if source == nil {
+		return errors.New(errors.ErrCodePricingDataMissing, "定價來源不能為空")
+	}
+
+	fetched, err := source.Fetch(ctx)
+	if err != nil {
+		appErr := errors.Wrap(err, errors.ErrCodePricingDataMissing, "從定價來源取得模型失敗")
+		appErr = appErr.WithContext(errors.ErrorContext{
+			Operation: "refresh_pricing",
+			Component: "pricing_engine",
+		})
+		return pe.errorHandler.Handle(ctx, appErr)
+	}
+
+	pe.mutex.Lock()
+	models := make(map[string]*types.PricingModel, len(fetched))
+	for name, model := range fetched {
+		if err := pe.validateModelConfig(name, pricingModelConfigFrom(model)); err != nil {
+			warnErr := errors.Wrap(err, errors.ErrCodeConfigValidation, fmt.Sprintf("無效的定價模型: %s", name))
+			warnErr = warnErr.WithContext(errors.ErrorContext{
+				Operation: "validate_model_config",
+				Component: "pricing_engine",
+				Parameters: map[string]interface{}{
+					"model_name": name,
+				},
+			})
+			pe.errorHandler.Handle(ctx, warnErr)
+			continue
+		}
+
+		model := model
+		model.Name = name
+		models[name] = &model
+	}
+
+	if len(models) == 0 {
+		pe.mutex.Unlock()
+		return errors.New(errors.ErrCodePricingDataMissing, "定價來源沒有回傳有效的模型")
+	}
+
+	pe.models = models
+	pe.lastUpdate = time.Now()
+	pe.mutex.Unlock()
+
+	pe.fireReloadCallbacks()
+	return nil
+}
+
+// StartAutoRefresh 立即並每隔 interval 從定價來源重新整理模型（interval <= 0 時使用 DefaultPricingRefreshInterval）。
+// 已在執行的自動重新整理會先停止；重新整理失敗時保留現有模型，錯誤交由錯誤處理器記錄。
+// 停止舊的與啟動新的重新整理在同一個鎖內完成，並行呼叫時只會保留一個重新整理 goroutine。
+func (pe *PricingEngine) StartAutoRefresh(source PricingSource, interval time.Duration) {
+	if source == nil {
+		return
+	}
+	if interval <= 0 {
+		interval = DefaultPricingRefreshInterval
+	}
+
+	pe.refreshMutex.Lock()
+	defer pe.refreshMutex.Unlock()
+
+	pe.stopAutoRefreshLocked()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	pe.refreshCancel = cancel
+	pe.refreshDone = done
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			pe.RefreshFromSource(ctx, source)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// StopAutoRefresh 停止自動重新整理並等待進行中的重新整理結束；未啟動時不做任何事
+func (pe *PricingEngine) StopAutoRefresh() {
+	pe.refreshMutex.Lock()
+	defer pe.refreshMutex.Unlock()
+
+	pe.stopAutoRefreshLocked()
+}
+
+// stopAutoRefreshLocked 停止自動重新整理並等待其結束（呼叫者須持有 refreshMutex；
+// 重新整理本身不取得 refreshMutex，因此等待期間不會死結）
+func (pe *PricingEngine) stopAutoRefreshLocked() {
+	cancel, done := pe.refreshCancel, pe.refreshDone
+	pe.refreshCancel, pe.refreshDone = nil, nil
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
@@ -0,0 +1,147 @@
+package cost
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+	"token-monitor/internal/types"
+)
+
+// fakePricingSource 測試用定價來源，每次取得時價格遞增
+type fakePricingSource struct {
+	mutex   sync.Mutex
+	fetches int
+	err     error
+}
+
+func (s *fakePricingSource) Fetch(ctx context.Context) (map[string]types.PricingModel, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.fetches++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return map[string]types.PricingModel{
+		"remote-model":      {InputPrice: float64(s.fetches), OutputPrice: float64(s.fetches) * 5},
+		"claude-sonnet-4.0": {InputPrice: 3, OutputPrice: 15},
+		"invalid-model":     {InputPrice: -1, OutputPrice: 1},
+	}, nil
+}
+
+func (s *fakePricingSource) fetchCount() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.fetches
+}
+
+// TestPricingEngineRefreshFromSource 測試從定價來源替換模型
+func TestPricingEngineRefreshFromSource(t *testing.T) {
+	engine := NewPricingEngine()
+	reloads := 0
+	engine.OnReload(func() { reloads++ })
+
+	source := &fakePricingSource{}
+	if err := engine.RefreshFromSource(context.Background(), source); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	model, err := engine.GetPricingModel("remote-model")
+	if err != nil {
+		t.Fatalf("Expected remote-model to be loaded: %v", err)
+	}
+	if model.Name != "remote-model" || model.InputPrice != 1 {
+		t.Errorf("Unexpected remote model: %+v", model)
+	}
+	if _, err := engine.GetPricingModel("invalid-model"); err == nil {
+		t.Error("Expected invalid model to be skipped")
+	}
+	if _, err := engine.GetPricingModel("claude-opus-4.0"); err == nil {
+		t.Error("Expected models missing from the source to be removed")
+	}
+	if reloads != 1 {
+		t.Errorf("Expected 1 reload callback, got %d", reloads)
+	}
+
+	// 取得失敗時保留現有模型
+	source.err = fmt.Errorf("service unavailable")
+	if err := engine.RefreshFromSource(context.Background(), source); err == nil {
+		t.Error("Expected error when fetch fails")
+	}
+	if _, err := engine.GetPricingModel("remote-model"); err != nil {
+		t.Error("Expected existing models to be kept after a failed refresh")
+	}
+	if reloads != 1 {
+		t.Errorf("Expected no reload callback after a failed refresh, got %d", reloads)
+	}
+}
+
+// TestPricingEngineAutoRefresh 測試定期重新整理與停止
+func TestPricingEngineAutoRefresh(t *testing.T) {
+	engine := NewPricingEngine()
+	source := &fakePricingSource{}
+
+	engine.StartAutoRefresh(source, 5*time.Millisecond)
+	deadline := time.Now().Add(2 * time.Second)
+	for source.fetchCount() < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	engine.StopAutoRefresh()
+
+	fetches := source.fetchCount()
+	if fetches < 3 {
+		t.Fatalf("Expected at least 3 refreshes, got %d", fetches)
+	}
+	model, err := engine.GetPricingModel("remote-model")
+	if err != nil || model.InputPrice < 1 {
+		t.Errorf("Expected refreshed remote model, got %+v (%v)", model, err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if source.fetchCount() != fetches {
+		t.Error("Expected no refreshes after StopAutoRefresh")
+	}
+
+	// 未啟動時停止不應阻塞
+	engine.StopAutoRefresh()
+}
+
+// TestPricingEngineAutoRefreshConcurrentStart 測試並行啟動時只保留一個重新整理 goroutine，停止後不再重新整理
+func TestPricingEngineAutoRefreshConcurrentStart(t *testing.T) {
+	for round := 0; round < 20; round++ {
+		engine := NewPricingEngine()
+		sources := make([]*fakePricingSource, 8)
+		for i := range sources {
+			sources[i] = &fakePricingSource{}
+		}
+
+		start := make(chan struct{})
+		var wg sync.WaitGroup
+		for _, source := range sources {
+			wg.Add(1)
+			go func(source *fakePricingSource) {
+				defer wg.Done()
+				<-start
+				engine.StartAutoRefresh(source, time.Millisecond)
+			}(source)
+		}
+		close(start)
+		wg.Wait()
+		engine.StopAutoRefresh()
+
+		total := func() int {
+			count := 0
+			for _, source := range sources {
+				count += source.fetchCount()
+			}
+			return count
+		}
+		fetches := total()
+		time.Sleep(5 * time.Millisecond)
+		if total() != fetches {
+			t.Fatalf("Round %d: expected no refreshes after StopAutoRefresh, a refresh goroutine was leaked", round)
+		}
+	}
+}
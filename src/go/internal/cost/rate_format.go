@@ -0,0 +1,62 @@
+package cost
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 價格顯示單位（內部計算一律使用每百萬 Token 價格）
+const (
+	RateUnitMTok = "MTok"
+	RateUnitKTok = "KTok"
+)
+
+// FormatRate 將每百萬 Token 價格格式化為指定單位（MTok、KTok，不分大小寫），未知單位視為 MTok
+func FormatRate(ratePerMTok float64, unit string) string {
+	if strings.EqualFold(unit, RateUnitKTok) {
+		return fmt.Sprintf("$%.4f/%s", ratePerMTok/1000, RateUnitKTok)
+	}
+	return fmt.Sprintf("$%.2f/%s", ratePerMTok, RateUnitMTok)
+}
+
+// normalizeRateUnit 將單位正規化為 RateUnitMTok 或 RateUnitKTok
+func normalizeRateUnit(unit string) (string, error) {
+	switch {
+	case strings.EqualFold(unit, RateUnitMTok):
+		return RateUnitMTok, nil
+	case strings.EqualFold(unit, RateUnitKTok):
+		return RateUnitKTok, nil
+	default:
+		return "", fmt.Errorf("unsupported rate unit %q (expected %s or %s)", unit, RateUnitMTok, RateUnitKTok)
+	}
+}
+
+// SetRateUnit 設定 GetModelInfo 等顯示輸出使用的價格單位，不影響成本計算
+func (pe *PricingEngine) SetRateUnit(unit string) error {
+	normalized, err := normalizeRateUnit(unit)
+	if err != nil {
+		return err
+	}
+
+	pe.mutex.Lock()
+	defer pe.mutex.Unlock()
+
+	pe.rateUnit = normalized
+	return nil
+}
+
+// GetRateUnit 取得顯示輸出使用的價格單位
+func (pe *PricingEngine) GetRateUnit() string {
+	pe.mutex.RLock()
+	defer pe.mutex.RUnlock()
+
+	return pe.rateUnit
+}
+
+// SetRateUnit 設定定價資訊顯示使用的價格單位（MTok 或 KTok）
+func (cc *CostCalculatorImpl) SetRateUnit(unit string) error {
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	return cc.pricingEngine.SetRateUnit(unit)
+}
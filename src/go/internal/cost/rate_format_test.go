@@ -0,0 +1,54 @@
+package cost
+
+import "testing"
+
+// TestFormatRate 測試價格顯示單位轉換
+func TestFormatRate(t *testing.T) {
+	tests := []struct {
+		rate     float64
+		unit     string
+		expected string
+	}{
+		{3.0, RateUnitMTok, "$3.00/MTok"},
+		{3.0, RateUnitKTok, "$0.0030/KTok"},
+		{15.0, "ktok", "$0.0150/KTok"},
+		{0.8, "", "$0.80/MTok"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatRate(tt.rate, tt.unit); got != tt.expected {
+			t.Errorf("FormatRate(%v, %q) = %s, expected %s", tt.rate, tt.unit, got, tt.expected)
+		}
+	}
+}
+
+// TestSetRateUnit 測試定價資訊依設定單位顯示且不影響計算
+func TestSetRateUnit(t *testing.T) {
+	calculator := NewCostCalculator()
+	before, _ := calculator.CalculateCost(1000, 1000, "claude-sonnet-4.0")
+
+	info, _ := calculator.pricingEngine.GetModelInfo("claude-sonnet-4.0")
+	if info["input_price"] != "$3.00/MTok" {
+		t.Errorf("Expected MTok display by default, got %v", info["input_price"])
+	}
+
+	if err := calculator.SetRateUnit("KTok"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	info, _ = calculator.pricingEngine.GetModelInfo("claude-sonnet-4.0")
+	if info["input_price"] != "$0.0030/KTok" || info["output_price"] != "$0.0150/KTok" {
+		t.Errorf("Expected KTok display, got %v / %v", info["input_price"], info["output_price"])
+	}
+
+	after, _ := calculator.CalculateCost(1000, 1000, "claude-sonnet-4.0")
+	if after.TotalCost != before.TotalCost {
+		t.Errorf("Expected rate unit not to affect cost, got %f vs %f", after.TotalCost, before.TotalCost)
+	}
+
+	if err := calculator.SetRateUnit("per-token"); err == nil {
+		t.Error("Expected error for unsupported unit")
+	}
+	if calculator.pricingEngine.GetRateUnit() != RateUnitKTok {
+		t.Errorf("Expected invalid unit to keep KTok, got %s", calculator.pricingEngine.GetRateUnit())
+	}
+}
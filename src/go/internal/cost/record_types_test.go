@@ -0,0 +1,60 @@
+package cost
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+	"token-monitor/internal/types"
+)
+
+// TestUsageRecordJSONRoundTrip 測試具名 Token 與成本型別保留原有 JSON 欄位名稱
+func TestUsageRecordJSONRoundTrip(t *testing.T) {
+	record := types.UsageRecord{
+		Timestamp: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		SessionID: "s1",
+		Activity:  types.Activity{Type: types.ActivityCoding},
+		Tokens:    types.NewRecordTokens(1000, 500, "tiktoken"),
+		Cost:      types.NewRecordCost(0.25, 0.5, "USD", "claude-sonnet-4.0"),
+	}
+	if record.Tokens.Total != 1500 || record.Cost.Total != 0.75 {
+		t.Errorf("Expected constructors to fill totals, got %+v %+v", record.Tokens, record.Cost)
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	raw := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	fields := make(map[string]map[string]interface{})
+	for _, key := range []string{"tokens", "cost"} {
+		var nested map[string]interface{}
+		if err := json.Unmarshal(raw[key], &nested); err != nil {
+			t.Fatalf("Expected %s object: %v", key, err)
+		}
+		fields[key] = nested
+	}
+
+	for _, key := range []string{"input", "output", "total", "calculation_method"} {
+		if _, ok := fields["tokens"][key]; !ok {
+			t.Errorf("Expected tokens.%s in JSON: %s", key, data)
+		}
+	}
+	for _, key := range []string{"input", "output", "total", "currency", "pricing_model"} {
+		if _, ok := fields["cost"][key]; !ok {
+			t.Errorf("Expected cost.%s in JSON: %s", key, data)
+		}
+	}
+
+	var decoded types.UsageRecord
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(record, decoded) {
+		t.Errorf("Expected round trip to preserve record:\n%+v\n%+v", record, decoded)
+	}
+}
@@ -0,0 +1,244 @@
+package cost
+
+import (
+	"time"
+	"token-monitor/internal/types"
+)
+
+// costAggregator 逐筆累計使用記錄的成本統計，不保留原始記錄
+type costAggregator struct {
+	calculator  *CostCalculatorImpl
+	report      *types.CostReport
+	dailyPoints map[time.Time]*types.CostDataPoint
+	currency    *currencyConverter
+
+	includeAllActivityTypes bool
+	fillGaps                bool
+}
+
+// newCostAggregator 建立成本累計器
+func newCostAggregator(cc *CostCalculatorImpl, options *types.ReportOptions) *costAggregator {
+	report := &types.CostReport{
+		GeneratedAt:  time.Now(),
+		Summary:      types.CostSummary{},
+		ByActivity:   make(map[types.ActivityType]types.CostSummary),
+		ByModel:      make(map[string]types.CostSummary),
+		ByMethod:     make(map[string]types.CostSummary),
+		Optimization: &types.OptimizationSuggestions{},
+		Trends:       &types.CostTrendAnalysis{},
+	}
+	if options != nil {
+		report.TimeRange = options.TimeRange
+	}
+
+	return &costAggregator{
+		calculator:  cc,
+		report:      report,
+		dailyPoints: make(map[time.Time]*types.CostDataPoint),
+		currency:    newCurrencyConverter(options),
+
+		includeAllActivityTypes: options != nil && options.IncludeAllActivityTypes,
+		fillGaps:                options != nil && options.FillGaps,
+	}
+}
+
+// add 計算並累計單筆記錄的成本
+func (a *costAggregator) add(record types.UsageRecord) {
+	a.calculator.mutex.RLock()
+	breakdown, err := a.calculator.calculateRecordCostLocked(record)
+	a.calculator.mutex.RUnlock()
+	if err != nil {
+		breakdown = nil
+	}
+	a.addWithBreakdown(record, breakdown)
+}
+
+// addWithBreakdown 以已計算的成本累計單筆記錄（breakdown 為 nil 時只計入記錄數）
+func (a *costAggregator) addWithBreakdown(record types.UsageRecord, breakdown *types.CostBreakdown) {
+	a.report.TotalRecords++
+	a.report.Summary.RecordCount++
+
+	timeKey := timeBucketKey(record.Timestamp, "daily")
+	dataPoint, exists := a.dailyPoints[timeKey]
+	if !exists {
+		dataPoint = &types.CostDataPoint{Timestamp: timeKey}
+		a.dailyPoints[timeKey] = dataPoint
+	}
+	dataPoint.RecordCount++
+
+	if breakdown == nil {
+		return
+	}
+
+	// 換算為報告幣別，缺少匯率的記錄不計入成本（由 currencyErr 回報）
+	rate, ok := a.currency.rate(record)
+	if !ok {
+		return
+	}
+	if rate != 1 {
+		breakdown = scaleBreakdown(breakdown, rate, a.currency.target)
+	}
+
+	a.report.Summary.TotalCost += breakdown.TotalCost
+	a.report.Summary.TotalTokens += record.Tokens.Total
+	addCacheSplit(&a.report.Summary, breakdown)
+	dataPoint.Cost += breakdown.TotalCost
+	dataPoint.TokenCount += record.Tokens.Total
+
+	// 按活動類型分組
+	activitySummary := a.report.ByActivity[record.Activity.Type]
+	activitySummary.TotalCost += breakdown.TotalCost
+	activitySummary.TotalTokens += record.Tokens.Total
+	activitySummary.RecordCount++
+	addCacheSplit(&activitySummary, breakdown)
+	a.report.ByActivity[record.Activity.Type] = activitySummary
+
+	// 按實際計價的模型分組（含活動類型覆寫）
+	model := breakdown.PricingModel
+	if model == "" {
+		model = record.Cost.PricingModel
+	}
+	modelSummary := a.report.ByModel[model]
+	modelSummary.TotalCost += breakdown.TotalCost
+	modelSummary.TotalTokens += record.Tokens.Total
+	modelSummary.RecordCount++
+	a.report.ByModel[model] = modelSummary
+
+	// 按計算方法分組
+	method := calculationMethodKey(record)
+	methodSummary := a.report.ByMethod[method]
+	methodSummary.TotalCost += breakdown.TotalCost
+	methodSummary.TotalTokens += record.Tokens.Total
+	methodSummary.RecordCount++
+	a.report.ByMethod[method] = methodSummary
+}
+
+// finalize 計算平均值與每日趨勢並回傳報告
+func (a *costAggregator) finalize() *types.CostReport {
+	report := a.report
+
+	if report.Summary.TotalTokens > 0 {
+		report.Summary.AverageCostPerToken = report.Summary.TotalCost / float64(report.Summary.TotalTokens) * 1_000_000 // 每百萬 token 的成本
+	}
+
+	if a.includeAllActivityTypes {
+		for _, activityType := range types.AllActivityTypes() {
+			if _, exists := report.ByActivity[activityType]; !exists {
+				report.ByActivity[activityType] = types.CostSummary{}
+			}
+		}
+	}
+
+	for activityType, summary := range report.ByActivity {
+		report.ByActivity[activityType] = withCostAverages(summary)
+	}
+	for model, summary := range report.ByModel {
+		report.ByModel[model] = withCostAverages(summary)
+	}
+	for method, summary := range report.ByMethod {
+		report.ByMethod[method] = withCostAverages(summary)
+	}
+	a.applyCurrency()
+
+	dataPoints := make([]types.CostDataPoint, 0, len(a.dailyPoints))
+	for _, dataPoint := range a.dailyPoints {
+		dataPoints = append(dataPoints, *dataPoint)
+	}
+	if a.fillGaps {
+		dataPoints = fillCostGaps(dataPoints, "daily")
+	}
+	report.Trends = buildCostTrends("daily", dataPoints)
+
+	return report
+}
+
+// currencyErr 回傳記錄幣別無法換算時的錯誤
+func (a *costAggregator) currencyErr() error {
+	return a.currency.err()
+}
+
+// applyCurrency 在摘要與各分組標示報告幣別
+func (a *costAggregator) applyCurrency() {
+	currency := a.currency.target
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+
+	report := a.report
+	report.Summary.Currency = currency
+	for activityType, summary := range report.ByActivity {
+		summary.Currency = currency
+		report.ByActivity[activityType] = summary
+	}
+	for model, summary := range report.ByModel {
+		summary.Currency = currency
+		report.ByModel[model] = summary
+	}
+	for method, summary := range report.ByMethod {
+		summary.Currency = currency
+		report.ByMethod[method] = summary
+	}
+}
+
+// calculateRecordCostLocked 計算單筆記錄成本，套用活動類型的模型覆寫；帶有快取或批次資訊的記錄使用詳細計算（呼叫者須持有讀取鎖，不更新追蹤）
+//
+// 同時帶有快取 Token 與批次標記時以快取計費為準。
+func (cc *CostCalculatorImpl) calculateRecordCostLocked(record types.UsageRecord) (*types.CostBreakdown, error) {
+	inputTokens, outputTokens := cc.recordTokensLocked(record)
+	billing := record.Billing
+	hasCache := billing.CacheReadTokens > 0 || billing.CacheWriteTokens > 0
+	model := cc.recordModelLocked(record)
+	if !hasCache && !billing.Batch {
+		return cc.calculateCostLocked(inputTokens, outputTokens, model)
+	}
+
+	if model == "" {
+		model = cc.pricingEngine.GetDefaultModel()
+	}
+
+	options := &CostOptions{
+		Mode:         BatchBilling,
+		IsBatch:      billing.Batch,
+		SessionID:    record.SessionID,
+		ActivityType: record.Activity.Type,
+	}
+	if hasCache {
+		options.Mode = CacheBilling
+		options.CacheReadTokens = billing.CacheReadTokens
+		options.CacheWriteTokens = billing.CacheWriteTokens
+	}
+
+	return cc.computeDetailedCost(inputTokens, outputTokens, model, options)
+}
+
+// addCacheSplit 累計快取與非快取成本及快取節省
+func addCacheSplit(summary *types.CostSummary, breakdown *types.CostBreakdown) {
+	cacheCost := breakdown.CacheReadCost + breakdown.CacheWriteCost
+	cacheTokens := breakdown.TokenCounts.CacheRead + breakdown.TokenCounts.CacheWrite
+
+	summary.CacheCost += cacheCost
+	summary.NonCacheCost += breakdown.TotalCost - cacheCost
+	if cacheTokens > 0 {
+		standardCost := float64(cacheTokens) / 1_000_000 * breakdown.CostDetails.InputRate
+		summary.CacheSavings += standardCost - cacheCost
+	}
+}
+
+// calculationMethodKey 取得記錄的 Token 計算方法，未標記時為 "unknown"
+func calculationMethodKey(record types.UsageRecord) string {
+	if record.Tokens.CalculationMethod == "" {
+		return "unknown"
+	}
+	return record.Tokens.CalculationMethod
+}
+
+// withCostAverages 計算摘要的平均每筆與每百萬 token 成本
+func withCostAverages(summary types.CostSummary) types.CostSummary {
+	if summary.RecordCount > 0 {
+		summary.AverageCostPerRecord = summary.TotalCost / float64(summary.RecordCount)
+		if summary.TotalTokens > 0 {
+			summary.AverageCostPerToken = summary.TotalCost / float64(summary.TotalTokens) * 1_000_000
+		}
+	}
+	return summary
+}
@@ -0,0 +1,113 @@
+package cost
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"token-monitor/internal/types"
+)
+
+// DefaultCurrency 未標示幣別的記錄視為此幣別
+const DefaultCurrency = "USD"
+
+// recordCurrency 取得記錄的幣別（大寫），未標示時為 DefaultCurrency
+func recordCurrency(record types.UsageRecord) string {
+	currency := strings.ToUpper(strings.TrimSpace(record.Cost.Currency))
+	if currency == "" {
+		return DefaultCurrency
+	}
+	return currency
+}
+
+// currencyConverter 將記錄成本換算為報告幣別，並記錄出現過的幣別與缺少匯率的幣別
+type currencyConverter struct {
+	target     string
+	fxRates    map[string]float64
+	seen       map[string]bool
+	missing    map[string]bool
+	autoTarget bool // 未指定報告幣別時以第一筆記錄的幣別為準
+}
+
+// newCurrencyConverter 依報告選項建立幣別換算器。
+// 未指定報告幣別時，有提供匯率則以 DefaultCurrency 為報告幣別，否則採用第一筆記錄的幣別。
+func newCurrencyConverter(options *types.ReportOptions) *currencyConverter {
+	converter := &currencyConverter{
+		fxRates: make(map[string]float64),
+		seen:    make(map[string]bool),
+		missing: make(map[string]bool),
+	}
+
+	if options != nil {
+		converter.target = strings.ToUpper(strings.TrimSpace(options.Currency))
+		for currency, rate := range options.FXRates {
+			converter.fxRates[strings.ToUpper(strings.TrimSpace(currency))] = rate
+		}
+	}
+	if converter.target == "" {
+		if len(converter.fxRates) > 0 {
+			converter.target = DefaultCurrency
+		} else {
+			converter.autoTarget = true
+		}
+	}
+
+	return converter
+}
+
+// rate 取得記錄幣別換算為報告幣別的匯率，無可用匯率時回傳 false
+func (c *currencyConverter) rate(record types.UsageRecord) (float64, bool) {
+	currency := recordCurrency(record)
+	c.seen[currency] = true
+
+	if c.autoTarget && c.target == "" {
+		c.target = currency
+	}
+	if currency == c.target {
+		return 1, true
+	}
+
+	rate, ok := c.fxRates[currency]
+	if !ok || rate <= 0 {
+		c.missing[currency] = true
+		return 0, false
+	}
+	return rate, true
+}
+
+// err 有記錄缺少匯率時回傳列出所有幣別的錯誤
+func (c *currencyConverter) err() error {
+	if len(c.missing) == 0 {
+		return nil
+	}
+	return fmt.Errorf("usage records use mixed currencies (%s) without FX rates to %s for: %s",
+		strings.Join(sortedCurrencies(c.seen), ", "), c.target, strings.Join(sortedCurrencies(c.missing), ", "))
+}
+
+// sortedCurrencies 取得排序後的幣別列表
+func sortedCurrencies(currencies map[string]bool) []string {
+	result := make([]string, 0, len(currencies))
+	for currency := range currencies {
+		result = append(result, currency)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// scaleBreakdown 複製成本分解並將金額乘以匯率
+func scaleBreakdown(breakdown *types.CostBreakdown, rate float64, currency string) *types.CostBreakdown {
+	scaled := *breakdown
+	scaled.InputCost *= rate
+	scaled.OutputCost *= rate
+	scaled.CacheReadCost *= rate
+	scaled.CacheWriteCost *= rate
+	scaled.BatchDiscount *= rate
+	scaled.ReasoningCost *= rate
+	scaled.TotalCost *= rate
+	scaled.CostDetails.InputRate *= rate
+	scaled.CostDetails.OutputRate *= rate
+	scaled.CostDetails.CacheReadRate *= rate
+	scaled.CostDetails.CacheWriteRate *= rate
+	scaled.CostDetails.ReasoningRate *= rate
+	scaled.Currency = currency
+	return &scaled
+}
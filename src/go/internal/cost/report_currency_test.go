@@ -0,0 +1,96 @@
+package cost
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+	"token-monitor/internal/types"
+)
+
+// newCurrencyRecord 建立指定幣別的測試記錄
+func newCurrencyRecord(timestamp time.Time, currency string) types.UsageRecord {
+	record := newTestRecord(timestamp, types.ActivityCoding, 1_000_000, 0, "claude-sonnet-4.0")
+	record.Cost.Currency = currency
+	return record
+}
+
+// TestGenerateCostReportMixedCurrencies 測試混合幣別未提供匯率時回傳錯誤
+func TestGenerateCostReportMixedCurrencies(t *testing.T) {
+	calculator := NewCostCalculator()
+	now := time.Now()
+	records := []types.UsageRecord{
+		newCurrencyRecord(now, "USD"),
+		newCurrencyRecord(now, "eur"),
+		newCurrencyRecord(now, ""),
+	}
+
+	_, err := calculator.GenerateCostReport(records, &types.ReportOptions{})
+	if err == nil {
+		t.Fatal("Expected error for mixed currencies")
+	}
+	if !strings.Contains(err.Error(), "EUR, USD") {
+		t.Errorf("Expected error to list currencies present, got %v", err)
+	}
+
+	// 串流報告同樣檢查
+	ch := make(chan types.UsageRecord, len(records))
+	for _, record := range records {
+		ch <- record
+	}
+	close(ch)
+	var buf bytes.Buffer
+	if err := calculator.StreamCostReport(ch, nil, &buf); err == nil {
+		t.Errorf("Expected stream error for mixed currencies")
+	}
+}
+
+// TestGenerateCostReportCurrencyConversion 測試依匯率換算為報告幣別
+func TestGenerateCostReportCurrencyConversion(t *testing.T) {
+	calculator := NewCostCalculator()
+	now := time.Now()
+	records := []types.UsageRecord{
+		newCurrencyRecord(now, "USD"),
+		newCurrencyRecord(now, "EUR"),
+	}
+
+	report, err := calculator.GenerateCostReport(records, &types.ReportOptions{
+		FXRates: map[string]float64{"eur": 1.1},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// claude-sonnet-4.0 輸入 1M tokens = $3；EUR 記錄換算為 3 * 1.1
+	expected := 3.0 + 3.0*1.1
+	if math.Abs(report.Summary.TotalCost-expected) > 1e-9 {
+		t.Errorf("Expected converted total %f, got %f", expected, report.Summary.TotalCost)
+	}
+	if report.Summary.Currency != "USD" || report.ByModel["claude-sonnet-4.0"].Currency != "USD" {
+		t.Errorf("Expected report currency USD, got %s", report.Summary.Currency)
+	}
+}
+
+// TestGenerateCostReportSingleCurrency 測試單一幣別的記錄沿用其幣別
+func TestGenerateCostReportSingleCurrency(t *testing.T) {
+	calculator := NewCostCalculator()
+	now := time.Now()
+
+	report, err := calculator.GenerateCostReport([]types.UsageRecord{newCurrencyRecord(now, "EUR")}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if report.Summary.Currency != "EUR" || math.Abs(report.Summary.TotalCost-3.0) > 1e-9 {
+		t.Errorf("Expected EUR report with total 3.0, got %s %f", report.Summary.Currency, report.Summary.TotalCost)
+	}
+
+	// 無幣別標示時為 USD
+	report, err = calculator.GenerateCostReport([]types.UsageRecord{newCurrencyRecord(now, "")}, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if report.Summary.Currency != DefaultCurrency {
+		t.Errorf("Expected default currency %s, got %s", DefaultCurrency, report.Summary.Currency)
+	}
+}
@@ -0,0 +1,109 @@
+package cost
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"token-monitor/internal/errors"
+	"token-monitor/internal/types"
+)
+
+// ExportCostReport 將成本報告匯出為指定格式（json、markdown）
+func (cc *CostCalculatorImpl) ExportCostReport(report *types.CostReport, format string) ([]byte, error) {
+	if report == nil {
+		return nil, errors.New(errors.ErrCodeReportDataMissing, "成本報告不能為空")
+	}
+
+	switch strings.ToLower(format) {
+	case "json":
+		return json.MarshalIndent(report, "", "  ")
+	case "markdown", "md":
+		return []byte(renderCostReportMarkdown(report)), nil
+	default:
+		return nil, errors.Newf(errors.ErrCodeInvalidReportFormat, "不支援的報告格式: %s", format)
+	}
+}
+
+// renderCostReportMarkdown 產生 Markdown 格式的成本報告
+func renderCostReportMarkdown(report *types.CostReport) string {
+	var sb strings.Builder
+
+	sb.WriteString("# 成本報告\n\n")
+	sb.WriteString(fmt.Sprintf("- 生成時間: %s\n", report.GeneratedAt.Format(time.RFC3339)))
+	if !report.TimeRange.Start.IsZero() || !report.TimeRange.End.IsZero() {
+		sb.WriteString(fmt.Sprintf("- 時間範圍: %s 至 %s\n",
+			report.TimeRange.Start.Format("2006-01-02"), report.TimeRange.End.Format("2006-01-02")))
+	}
+	sb.WriteString(fmt.Sprintf("- 總記錄數: %d\n\n", report.TotalRecords))
+
+	// 摘要表格
+	sb.WriteString("## 摘要\n\n")
+	sb.WriteString("| 項目 | 數值 |\n")
+	sb.WriteString("| --- | --- |\n")
+	sb.WriteString(fmt.Sprintf("| 總成本 (USD) | %.4f |\n", report.Summary.TotalCost))
+	sb.WriteString(fmt.Sprintf("| 總 Token 數 | %d |\n", report.Summary.TotalTokens))
+	sb.WriteString(fmt.Sprintf("| 記錄數 | %d |\n", report.Summary.RecordCount))
+	sb.WriteString(fmt.Sprintf("| 平均每百萬 Token 成本 (USD) | %.4f |\n\n", report.Summary.AverageCostPerToken))
+
+	// 按模型分組
+	sb.WriteString("## 按模型統計\n\n")
+	writeCostSummaryTable(&sb, "模型", report.SortedModels(), func(key string) types.CostSummary {
+		return report.ByModel[key]
+	})
+
+	// 按活動類型分組
+	sb.WriteString("## 按活動類型統計\n\n")
+	activities := make([]string, 0, len(report.ByActivity))
+	for _, activityType := range report.SortedActivities() {
+		activities = append(activities, string(activityType))
+	}
+	writeCostSummaryTable(&sb, "活動類型", activities, func(key string) types.CostSummary {
+		return report.ByActivity[types.ActivityType(key)]
+	})
+
+	// 按計算方法分組
+	sb.WriteString("## 按計算方法統計\n\n")
+	methods := make([]string, 0, len(report.ByMethod))
+	for method := range report.ByMethod {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	writeCostSummaryTable(&sb, "計算方法", methods, func(key string) types.CostSummary {
+		return report.ByMethod[key]
+	})
+
+	// 優化建議
+	sb.WriteString("## 優化建議\n\n")
+	if report.Optimization == nil || len(report.Optimization.Suggestions) == 0 {
+		sb.WriteString("- 無優化建議\n")
+	} else {
+		for _, suggestion := range report.Optimization.Suggestions {
+			sb.WriteString(fmt.Sprintf("- **%s**: %s（可節省 $%.4f，信心度 %.0f%%）\n",
+				suggestion.Type, suggestion.Description, suggestion.PotentialSaving, suggestion.Confidence*100))
+		}
+		sb.WriteString(fmt.Sprintf("\n總潛在節省: $%.4f\n", report.Optimization.TotalSavings))
+		sb.WriteString(fmt.Sprintf("依信心度加權的預期節省: $%.4f\n", report.Optimization.ConfidenceWeightedSavings))
+	}
+
+	return sb.String()
+}
+
+// writeCostSummaryTable 寫入成本摘要表格
+func writeCostSummaryTable(sb *strings.Builder, keyHeader string, keys []string, lookup func(string) types.CostSummary) {
+	if len(keys) == 0 {
+		sb.WriteString("無資料\n\n")
+		return
+	}
+
+	sb.WriteString(fmt.Sprintf("| %s | 成本 (USD) | Token 數 | 記錄數 | 平均每筆成本 (USD) | 平均每百萬 Token 成本 (USD) |\n", keyHeader))
+	sb.WriteString("| --- | ---: | ---: | ---: | ---: | ---: |\n")
+	for _, key := range keys {
+		summary := lookup(key)
+		sb.WriteString(fmt.Sprintf("| %s | %.4f | %d | %d | %.4f | %.4f |\n",
+			key, summary.TotalCost, summary.TotalTokens, summary.RecordCount,
+			summary.AverageCostPerRecord, summary.AverageCostPerToken))
+	}
+	sb.WriteString("\n")
+}
@@ -0,0 +1,185 @@
+package cost
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+	"token-monitor/internal/errors"
+	"token-monitor/internal/types"
+)
+
+// newTestRecord 建立測試用的使用記錄
+func newTestRecord(timestamp time.Time, activityType types.ActivityType, input, output int, model string) types.UsageRecord {
+	return types.UsageRecord{
+		Timestamp: timestamp,
+		Activity:  types.Activity{Type: activityType},
+		Tokens:    types.NewRecordTokens(input, output, ""),
+		Cost:      types.RecordCost{PricingModel: model},
+	}
+}
+
+// TestExportCostReportMarkdown 測試 Markdown 格式匯出
+func TestExportCostReportMarkdown(t *testing.T) {
+	calculator := NewCostCalculator()
+	now := time.Now()
+
+	records := []types.UsageRecord{
+		newTestRecord(now.AddDate(0, 0, -1), types.ActivityCoding, 1000, 2000, "claude-sonnet-4.0"),
+		newTestRecord(now, types.ActivityChat, 500, 500, "claude-haiku-3.5"),
+	}
+
+	report, err := calculator.GenerateCostReport(records, &types.ReportOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	report.Optimization = &types.OptimizationSuggestions{
+		Suggestions: []types.OptimizationSuggestion{
+			{Type: "cache", Description: "啟用快取", PotentialSaving: 0.1234, Confidence: 0.8},
+		},
+		TotalSavings:              0.1234,
+		ConfidenceWeightedSavings: 0.0987,
+	}
+
+	data, err := calculator.ExportCostReport(report, "markdown")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	output := string(data)
+
+	expectedParts := []string{
+		"# 成本報告",
+		"## 摘要",
+		"| 總成本 (USD) | 0.0354 |",
+		"## 按模型統計",
+		"| claude-haiku-3.5 | 0.0024 | 1000 | 1 |",
+		"| claude-sonnet-4.0 | 0.0330 | 3000 | 1 |",
+		"## 按活動類型統計",
+		"| chat | 0.0024 |",
+		"| coding | 0.0330 |",
+		"## 優化建議",
+		"- **cache**: 啟用快取（可節省 $0.1234",
+		"依信心度加權的預期節省: $0.0987",
+	}
+	for _, part := range expectedParts {
+		if !strings.Contains(output, part) {
+			t.Errorf("Expected markdown to contain %q, got:\n%s", part, output)
+		}
+	}
+
+	// 模型表格應按名稱排序
+	if strings.Index(output, "claude-haiku-3.5") > strings.Index(output, "claude-sonnet-4.0") {
+		t.Errorf("Expected models to be sorted by name")
+	}
+}
+
+// TestExportCostReportFormats 測試其他格式與錯誤處理
+func TestExportCostReportFormats(t *testing.T) {
+	calculator := NewCostCalculator()
+	records := []types.UsageRecord{
+		newTestRecord(time.Now(), types.ActivityCoding, 1000, 1000, "claude-sonnet-4.0"),
+	}
+
+	report, err := calculator.GenerateCostReport(records, &types.ReportOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	data, err := calculator.ExportCostReport(report, "json")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var decoded types.CostReport
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Errorf("Expected valid JSON, got error: %v", err)
+	}
+
+	if _, err := calculator.ExportCostReport(report, "pdf"); !errors.IsCode(err, errors.ErrCodeInvalidReportFormat) {
+		t.Errorf("Expected ErrCodeInvalidReportFormat, got %v", err)
+	}
+
+	if _, err := calculator.ExportCostReport(nil, "markdown"); err == nil {
+		t.Errorf("Expected error for nil report")
+	}
+}
+
+// TestCostReportSortedKeys 測試成本報告的排序鍵存取與 Markdown 輸出可重現
+func TestCostReportSortedKeys(t *testing.T) {
+	calculator := NewCostCalculator()
+	now := time.Now()
+	records := []types.UsageRecord{
+		newTestRecord(now, types.ActivityDebugging, 1000, 500, "claude-sonnet-4.0"),
+		newTestRecord(now, types.ActivityChat, 1000, 500, "claude-opus-4.0"),
+		newTestRecord(now, types.ActivityCoding, 1000, 500, "claude-sonnet-4.0"),
+	}
+
+	report, err := calculator.GenerateCostReport(records, &types.ReportOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	activities := report.SortedActivities()
+	expectedActivities := []types.ActivityType{types.ActivityChat, types.ActivityCoding, types.ActivityDebugging}
+	if len(activities) != len(expectedActivities) {
+		t.Fatalf("Expected %v, got %v", expectedActivities, activities)
+	}
+	for i := range expectedActivities {
+		if activities[i] != expectedActivities[i] {
+			t.Errorf("Expected %v, got %v", expectedActivities, activities)
+			break
+		}
+	}
+
+	models := report.SortedModels()
+	if len(models) != 2 || models[0] != "claude-opus-4.0" || models[1] != "claude-sonnet-4.0" {
+		t.Errorf("Unexpected model order: %v", models)
+	}
+
+	first, err := calculator.ExportCostReport(report, "markdown")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		again, _ := calculator.ExportCostReport(report, "markdown")
+		if string(again) != string(first) {
+			t.Fatal("Expected reproducible markdown output")
+		}
+	}
+}
+
+// TestGenerateCostReportIncludeAllActivityTypes 測試報告包含所有已知活動類型
+func TestGenerateCostReportIncludeAllActivityTypes(t *testing.T) {
+	calculator := NewCostCalculator()
+	records := []types.UsageRecord{
+		newTestRecord(time.Now(), types.ActivityCoding, 1000, 500, "claude-sonnet-4.0"),
+	}
+
+	report, err := calculator.GenerateCostReport(records, &types.ReportOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(report.ByActivity) != 1 {
+		t.Errorf("Expected only recorded activity types by default, got %v", report.SortedActivities())
+	}
+
+	report, err = calculator.GenerateCostReport(records, &types.ReportOptions{IncludeAllActivityTypes: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(report.ByActivity) != len(types.AllActivityTypes()) {
+		t.Fatalf("Expected all activity types, got %v", report.SortedActivities())
+	}
+	for _, activityType := range types.AllActivityTypes() {
+		summary, exists := report.ByActivity[activityType]
+		if !exists {
+			t.Errorf("Expected %s to be present", activityType)
+			continue
+		}
+		if activityType != types.ActivityCoding && (summary.TotalCost != 0 || summary.RecordCount != 0) {
+			t.Errorf("Expected zero summary for %s, got %+v", activityType, summary)
+		}
+	}
+	if report.ByActivity[types.ActivityCoding].RecordCount != 1 {
+		t.Errorf("Expected coding summary to be kept, got %+v", report.ByActivity[types.ActivityCoding])
+	}
+}
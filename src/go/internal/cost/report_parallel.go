@@ -0,0 +1,60 @@
+package cost
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"token-monitor/internal/types"
+)
+
+// GenerateCostReportParallel 以多個 worker 並行計算每筆記錄的成本後產生報告（workers <= 0 時使用 CPU 數量）。
+// 彙總仍依記錄順序進行，結果與 GenerateCostReport 完全相同。
+func (cc *CostCalculatorImpl) GenerateCostReportParallel(records []types.UsageRecord, options *types.ReportOptions, workers int) (*types.CostReport, error) {
+	cc.mutex.RLock()
+	defer cc.mutex.RUnlock()
+
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no usage records provided")
+	}
+
+	if options != nil && options.Deduplicate {
+		records = DeduplicateRecordsBy(records, cc.recordKeyFunc)
+	}
+
+	breakdowns := cc.calculateCostBatchParallelLocked(records, workers)
+	return cc.buildCostReportLocked(records, options, breakdowns)
+}
+
+// calculateCostBatchParallelLocked 並行計算記錄成本，失敗的記錄在結果中為 nil（呼叫者須持有讀取鎖）
+func (cc *CostCalculatorImpl) calculateCostBatchParallelLocked(records []types.UsageRecord, workers int) []*types.CostBreakdown {
+	breakdowns := make([]*types.CostBreakdown, len(records))
+
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(records) {
+		workers = len(records)
+	}
+
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				if breakdown, err := cc.calculateRecordCostLocked(records[i]); err == nil {
+					breakdowns[i] = breakdown
+				}
+			}
+		}()
+	}
+
+	for i := range records {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+
+	return breakdowns
+}
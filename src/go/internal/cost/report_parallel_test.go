@@ -0,0 +1,50 @@
+package cost
+
+import (
+	"reflect"
+	"testing"
+	"time"
+	"token-monitor/internal/types"
+)
+
+// TestGenerateCostReportParallelMatchesSequential 測試並行報告與循序報告結果一致
+func TestGenerateCostReportParallelMatchesSequential(t *testing.T) {
+	calculator := NewCostCalculator()
+	models := []string{"claude-sonnet-4.0", "claude-opus-4.0", "claude-haiku-3.5", "unknown-model"}
+	activities := []types.ActivityType{types.ActivityCoding, types.ActivityDebugging, types.ActivityChat}
+	start := time.Now().AddDate(0, 0, -10)
+
+	records := make([]types.UsageRecord, 0, 200)
+	for i := 0; i < 200; i++ {
+		record := newTestRecord(start.Add(time.Duration(i)*time.Hour), activities[i%len(activities)],
+			100+i*37, 50+i*13, models[i%len(models)])
+		if i%5 == 0 {
+			record.Billing.CacheReadTokens = 20 + i
+		}
+		if i%7 == 0 {
+			record.Billing.Batch = true
+		}
+		records = append(records, record)
+	}
+
+	options := &types.ReportOptions{IncludeAllActivityTypes: true}
+	expected, err := calculator.GenerateCostReport(records, options)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, workers := range []int{0, 1, 4, 500} {
+		report, err := calculator.GenerateCostReportParallel(records, options, workers)
+		if err != nil {
+			t.Fatalf("workers=%d: unexpected error: %v", workers, err)
+		}
+		report.GeneratedAt = expected.GeneratedAt
+		if !reflect.DeepEqual(expected, report) {
+			t.Errorf("workers=%d: expected parallel report to match sequential report", workers)
+		}
+	}
+
+	if _, err := calculator.GenerateCostReportParallel(nil, options, 4); err == nil {
+		t.Error("Expected error for empty records")
+	}
+}
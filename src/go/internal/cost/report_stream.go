@@ -0,0 +1,54 @@
+package cost
+
+import (
+	"encoding/json"
+	"io"
+	"token-monitor/internal/errors"
+	"token-monitor/internal/types"
+)
+
+// StreamCostReport 從通道逐筆讀取使用記錄並累計成本，讀取完畢後將 JSON 報告寫入 w。
+// 記錄不會被保留，因此報告不含需要完整記錄集合的優化建議；其餘統計與 GenerateCostReport 相同。
+// opts.Deduplicate 為 true 時以 SetRecordKeyFunc 設定的鍵函式略過重複記錄（僅保留已見過的鍵）。
+func (cc *CostCalculatorImpl) StreamCostReport(records <-chan types.UsageRecord, opts *types.ReportOptions, w io.Writer) error {
+	if records == nil {
+		return errors.New(errors.ErrCodeReportDataMissing, "使用記錄通道不能為空")
+	}
+	if w == nil {
+		return errors.New(errors.ErrCodeReportDataMissing, "報告輸出不能為空")
+	}
+
+	var seen map[string]struct{}
+	keyFunc := RecordKeyFunc(DefaultRecordKey)
+	if opts != nil && opts.Deduplicate {
+		seen = make(map[string]struct{})
+		cc.mutex.RLock()
+		if cc.recordKeyFunc != nil {
+			keyFunc = cc.recordKeyFunc
+		}
+		cc.mutex.RUnlock()
+	}
+
+	aggregator := newCostAggregator(cc, opts)
+	for record := range records {
+		if seen != nil {
+			key := keyFunc(record)
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+		}
+		aggregator.add(record)
+	}
+
+	if aggregator.report.TotalRecords == 0 {
+		return errors.New(errors.ErrCodeReportDataMissing, "沒有可用的使用記錄")
+	}
+	if err := aggregator.currencyErr(); err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(aggregator.finalize())
+}
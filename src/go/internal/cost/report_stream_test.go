@@ -0,0 +1,146 @@
+package cost
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+	"token-monitor/internal/errors"
+	"token-monitor/internal/types"
+)
+
+// TestStreamCostReportMatchesBatch 測試串流報告與批次報告的統計一致
+func TestStreamCostReportMatchesBatch(t *testing.T) {
+	calculator := NewCostCalculator()
+	base := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	records := []types.UsageRecord{
+		newTestRecord(base, types.ActivityCoding, 1000, 2000, "claude-sonnet-4.0"),
+		newTestRecord(base.Add(2*time.Hour), types.ActivityChat, 500, 500, "claude-haiku-3.5"),
+		newTestRecord(base.AddDate(0, 0, 1), types.ActivityCoding, 3000, 1000, "claude-opus-4.0"),
+		newTestRecord(base.AddDate(0, 0, 2), types.ActivityDebugging, 200, 800, "claude-sonnet-4.0"),
+	}
+
+	batch, err := calculator.GenerateCostReport(records, &types.ReportOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ch := make(chan types.UsageRecord)
+	go func() {
+		defer close(ch)
+		for _, record := range records {
+			ch <- record
+		}
+	}()
+
+	var buf bytes.Buffer
+	if err := calculator.StreamCostReport(ch, &types.ReportOptions{}, &buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var streamed types.CostReport
+	if err := json.Unmarshal(buf.Bytes(), &streamed); err != nil {
+		t.Fatalf("Expected valid JSON, got error: %v", err)
+	}
+
+	if streamed.TotalRecords != batch.TotalRecords {
+		t.Errorf("Expected %d records, got %d", batch.TotalRecords, streamed.TotalRecords)
+	}
+	assertSummaryEqual(t, "summary", batch.Summary, streamed.Summary)
+
+	if len(streamed.ByModel) != len(batch.ByModel) {
+		t.Errorf("Expected %d models, got %d", len(batch.ByModel), len(streamed.ByModel))
+	}
+	for model, summary := range batch.ByModel {
+		assertSummaryEqual(t, model, summary, streamed.ByModel[model])
+	}
+
+	if len(streamed.ByActivity) != len(batch.ByActivity) {
+		t.Errorf("Expected %d activities, got %d", len(batch.ByActivity), len(streamed.ByActivity))
+	}
+	for activityType, summary := range batch.ByActivity {
+		assertSummaryEqual(t, string(activityType), summary, streamed.ByActivity[activityType])
+	}
+
+	if len(streamed.Trends.DataPoints) != len(batch.Trends.DataPoints) {
+		t.Fatalf("Expected %d trend points, got %d", len(batch.Trends.DataPoints), len(streamed.Trends.DataPoints))
+	}
+	for i, point := range batch.Trends.DataPoints {
+		if !point.Timestamp.Equal(streamed.Trends.DataPoints[i].Timestamp) ||
+			math.Abs(point.Cost-streamed.Trends.DataPoints[i].Cost) > 1e-9 {
+			t.Errorf("Trend point %d mismatch: expected %+v, got %+v", i, point, streamed.Trends.DataPoints[i])
+		}
+	}
+}
+
+// TestStreamCostReportDeduplicate 測試串流報告依 Deduplicate 選項略過重複記錄
+func TestStreamCostReportDeduplicate(t *testing.T) {
+	calculator := NewCostCalculator()
+	base := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	record := newTestRecord(base, types.ActivityCoding, 1000, 2000, "claude-sonnet-4.0")
+	other := newTestRecord(base.Add(time.Hour), types.ActivityChat, 500, 500, "claude-haiku-3.5")
+
+	stream := func(opts *types.ReportOptions) types.CostReport {
+		t.Helper()
+		ch := make(chan types.UsageRecord)
+		go func() {
+			defer close(ch)
+			for _, r := range []types.UsageRecord{record, other, record} {
+				ch <- r
+			}
+		}()
+
+		var buf bytes.Buffer
+		if err := calculator.StreamCostReport(ch, opts, &buf); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var report types.CostReport
+		if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+			t.Fatalf("Expected valid JSON, got error: %v", err)
+		}
+		return report
+	}
+
+	if report := stream(&types.ReportOptions{}); report.TotalRecords != 3 {
+		t.Errorf("Expected duplicates to be kept without Deduplicate, got %d records", report.TotalRecords)
+	}
+	if report := stream(&types.ReportOptions{Deduplicate: true}); report.TotalRecords != 2 {
+		t.Errorf("Expected 2 records after deduplication, got %d", report.TotalRecords)
+	}
+
+	// 使用自訂鍵函式
+	calculator.SetRecordKeyFunc(func(types.UsageRecord) string { return "same" })
+	if report := stream(&types.ReportOptions{Deduplicate: true}); report.TotalRecords != 1 {
+		t.Errorf("Expected custom key func to collapse records, got %d", report.TotalRecords)
+	}
+}
+
+// TestStreamCostReportErrors 測試串流報告的錯誤處理
+func TestStreamCostReportErrors(t *testing.T) {
+	calculator := NewCostCalculator()
+
+	ch := make(chan types.UsageRecord)
+	close(ch)
+	var buf bytes.Buffer
+	if err := calculator.StreamCostReport(ch, nil, &buf); !errors.IsCode(err, errors.ErrCodeReportDataMissing) {
+		t.Errorf("Expected ErrCodeReportDataMissing for empty stream, got %v", err)
+	}
+
+	if err := calculator.StreamCostReport(nil, nil, &buf); err == nil {
+		t.Errorf("Expected error for nil channel")
+	}
+}
+
+// assertSummaryEqual 比較兩個成本摘要
+func assertSummaryEqual(t *testing.T, name string, expected, actual types.CostSummary) {
+	t.Helper()
+	if math.Abs(expected.TotalCost-actual.TotalCost) > 1e-9 ||
+		expected.TotalTokens != actual.TotalTokens ||
+		expected.RecordCount != actual.RecordCount ||
+		math.Abs(expected.AverageCostPerRecord-actual.AverageCostPerRecord) > 1e-9 ||
+		math.Abs(expected.AverageCostPerToken-actual.AverageCostPerToken) > 1e-9 {
+		t.Errorf("%s mismatch: expected %+v, got %+v", name, expected, actual)
+	}
+}
@@ -0,0 +1,126 @@
+package cost
+
+import (
+	"fmt"
+	"sort"
+	"time"
+	"token-monitor/internal/types"
+)
+
+// DefaultSessionGap 預設的會話切分間隔
+const DefaultSessionGap = 24 * time.Hour
+
+// SessionCost 會話成本彙總
+type SessionCost struct {
+	SessionID   string    `json:"session_id"`  // 切分後的會話 ID（重複使用時帶有 "#N" 後綴）
+	OriginalID  string    `json:"original_id"` // 記錄中的原始 SessionID
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	RecordCount int       `json:"record_count"`
+	TotalTokens int       `json:"total_tokens"`
+	TotalCost   float64   `json:"total_cost"`
+}
+
+// SetSessionGap 設定會話切分間隔（<= 0 表示不切分）
+func (cc *CostCalculatorImpl) SetSessionGap(gap time.Duration) {
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	cc.sessionGap = gap
+}
+
+// BuildSessions 從使用記錄建立會話成本彙總
+//
+// 同一 SessionID 的記錄依時間排序後，若相鄰兩筆記錄的間隔超過會話切分間隔，
+// 則視為 ID 被重複使用，後續記錄歸入新的會話。第一段保留原始 ID，之後的
+// 各段依序加上 "#2"、"#3" 等後綴。沒有 SessionID 的記錄會被略過，無法計算
+// 成本的記錄也不計入。結果依會話開始時間排序。
+func (cc *CostCalculatorImpl) BuildSessions(records []types.UsageRecord) []SessionCost {
+	cc.mutex.RLock()
+	gap := cc.sessionGap
+	cc.mutex.RUnlock()
+
+	// 按 SessionID 分組
+	bySession := make(map[string][]types.UsageRecord)
+	for _, record := range records {
+		if record.SessionID == "" {
+			continue
+		}
+		bySession[record.SessionID] = append(bySession[record.SessionID], record)
+	}
+
+	sessions := make([]SessionCost, 0, len(bySession))
+	for sessionID, sessionRecords := range bySession {
+		sort.SliceStable(sessionRecords, func(i, j int) bool {
+			return sessionRecords[i].Timestamp.Before(sessionRecords[j].Timestamp)
+		})
+
+		index := 1
+		current := SessionCost{SessionID: sessionID, OriginalID: sessionID}
+
+		for i, record := range sessionRecords {
+			if i > 0 && gap > 0 && record.Timestamp.Sub(sessionRecords[i-1].Timestamp) > gap {
+				sessions = append(sessions, current)
+				index++
+				current = SessionCost{
+					SessionID:  fmt.Sprintf("%s#%d", sessionID, index),
+					OriginalID: sessionID,
+				}
+			}
+
+			if current.RecordCount == 0 {
+				current.Start = record.Timestamp
+			}
+			current.End = record.Timestamp
+			current.RecordCount++
+			current.TotalTokens += record.Tokens.Total
+
+			inputTokens, outputTokens := cc.recordTokens(record)
+			if breakdown, err := cc.CalculateCost(inputTokens, outputTokens, cc.recordModel(record)); err == nil {
+				current.TotalCost += breakdown.TotalCost
+			}
+		}
+
+		sessions = append(sessions, current)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		if sessions[i].Start.Equal(sessions[j].Start) {
+			return sessions[i].SessionID < sessions[j].SessionID
+		}
+		return sessions[i].Start.Before(sessions[j].Start)
+	})
+
+	return sessions
+}
+
+// unknownSessionID 未標記 SessionID 的記錄所歸屬的鍵
+const unknownSessionID = "unknown"
+
+// GetSessionDailyCosts 依記錄計算每個會話每日的成本（session -> 日期 -> 成本），
+// 日期取自記錄時間戳（YYYY-MM-DD），空 SessionID 歸入 "unknown"
+func (cc *CostCalculatorImpl) GetSessionDailyCosts(records []types.UsageRecord) map[string]map[string]float64 {
+	result := make(map[string]map[string]float64)
+
+	for _, record := range records {
+		inputTokens, outputTokens := cc.recordTokens(record)
+		breakdown, err := cc.CalculateCost(inputTokens, outputTokens, cc.recordModel(record))
+		if err != nil {
+			continue
+		}
+
+		sessionID := record.SessionID
+		if sessionID == "" {
+			sessionID = unknownSessionID
+		}
+
+		daily, exists := result[sessionID]
+		if !exists {
+			daily = make(map[string]float64)
+			result[sessionID] = daily
+		}
+		daily[record.Timestamp.Format("2006-01-02")] += breakdown.TotalCost
+	}
+
+	return result
+}
@@ -0,0 +1,93 @@
+package cost
+
+import (
+	"math"
+	"testing"
+	"time"
+	"token-monitor/internal/mathutil"
+	"token-monitor/internal/types"
+)
+
+// TestBuildSessionsSplitsReusedIDs 測試重複使用的 SessionID 依時間間隔切分
+func TestBuildSessionsSplitsReusedIDs(t *testing.T) {
+	calculator := NewCostCalculator()
+	base := time.Date(2025, 8, 1, 9, 0, 0, 0, time.UTC)
+
+	withSession := func(record types.UsageRecord, sessionID string) types.UsageRecord {
+		record.SessionID = sessionID
+		return record
+	}
+
+	records := []types.UsageRecord{
+		withSession(newTestRecord(base, types.ActivityCoding, 1000, 1000, "claude-sonnet-4.0"), "s1"),
+		withSession(newTestRecord(base.Add(time.Hour), types.ActivityCoding, 1000, 1000, "claude-sonnet-4.0"), "s1"),
+		// 三天後重複使用 s1
+		withSession(newTestRecord(base.AddDate(0, 0, 3), types.ActivityChat, 500, 500, "claude-sonnet-4.0"), "s1"),
+		withSession(newTestRecord(base.Add(30*time.Minute), types.ActivityChat, 500, 500, "claude-sonnet-4.0"), "s2"),
+		newTestRecord(base, types.ActivityChat, 100, 100, "claude-sonnet-4.0"), // 無 SessionID
+	}
+
+	sessions := calculator.BuildSessions(records)
+	if len(sessions) != 3 {
+		t.Fatalf("Expected 3 sessions, got %d: %+v", len(sessions), sessions)
+	}
+
+	if sessions[0].SessionID != "s1" || sessions[0].RecordCount != 2 {
+		t.Errorf("Expected first session s1 with 2 records, got %+v", sessions[0])
+	}
+	if mathutil.AbsFloat(sessions[0].TotalCost-0.036) > 1e-9 {
+		t.Errorf("Expected s1 cost 0.036, got %.6f", sessions[0].TotalCost)
+	}
+	if sessions[1].SessionID != "s2" {
+		t.Errorf("Expected second session s2, got %s", sessions[1].SessionID)
+	}
+	if sessions[2].SessionID != "s1#2" || sessions[2].OriginalID != "s1" || sessions[2].RecordCount != 1 {
+		t.Errorf("Expected split session s1#2 with 1 record, got %+v", sessions[2])
+	}
+
+	// 停用切分後應合併為同一會話
+	calculator.SetSessionGap(0)
+	sessions = calculator.BuildSessions(records)
+	if len(sessions) != 2 {
+		t.Errorf("Expected 2 sessions with splitting disabled, got %d", len(sessions))
+	}
+}
+
+// TestGetSessionDailyCosts 測試會話每日成本
+func TestGetSessionDailyCosts(t *testing.T) {
+	calculator := NewCostCalculator()
+	day1 := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+
+	newRecord := func(sessionID string, ts time.Time, input, output int) types.UsageRecord {
+		record := newTestRecord(ts, types.ActivityCoding, input, output, "claude-sonnet-4.0")
+		record.SessionID = sessionID
+		return record
+	}
+
+	records := []types.UsageRecord{
+		newRecord("s1", day1, 1000, 2000),
+		newRecord("s1", day1.Add(time.Hour), 1000, 1000),
+		newRecord("s1", day2, 1000, 1000),
+		newRecord("s2", day2, 1000, 2000),
+		newRecord("", day1, 1000, 1000),
+	}
+
+	costs := calculator.GetSessionDailyCosts(records)
+	if len(costs) != 3 {
+		t.Fatalf("Expected 3 sessions, got %d", len(costs))
+	}
+
+	if got := costs["s1"]["2026-03-01"]; math.Abs(got-0.051) > 1e-9 {
+		t.Errorf("Expected s1 day 1 cost 0.051, got %f", got)
+	}
+	if got := costs["s1"]["2026-03-02"]; math.Abs(got-0.018) > 1e-9 {
+		t.Errorf("Expected s1 day 2 cost 0.018, got %f", got)
+	}
+	if len(costs["s2"]) != 1 {
+		t.Errorf("Expected s2 to have 1 day, got %d", len(costs["s2"]))
+	}
+	if _, exists := costs["unknown"]["2026-03-01"]; !exists {
+		t.Errorf("Expected empty session ID under unknown, got %v", costs)
+	}
+}
@@ -0,0 +1,59 @@
+package cost
+
+import (
+	"time"
+	"token-monitor/internal/types"
+)
+
+// ActivitySparklines 將記錄的整體時間範圍均分為 buckets 個區間，回傳各活動類型每個區間的成本（無記錄的區間為 0）。
+// 最後一筆記錄計入最後一個區間；時間範圍無法均分為 buckets 個非零寬度區間（如所有記錄時間相同）時，
+// 序列長度仍為 buckets，所有成本計入第一個區間。無法計算成本的記錄會被略過。
+func (cc *CostCalculatorImpl) ActivitySparklines(records []types.UsageRecord, buckets int) map[types.ActivityType][]float64 {
+	sparklines := make(map[types.ActivityType][]float64)
+	if buckets <= 0 || len(records) == 0 {
+		return sparklines
+	}
+
+	cc.mutex.RLock()
+	breakdowns, _ := cc.calculateCostBatchLocked(records)
+	cc.mutex.RUnlock()
+
+	start, end := records[0].Timestamp, records[0].Timestamp
+	for _, record := range records[1:] {
+		if record.Timestamp.Before(start) {
+			start = record.Timestamp
+		}
+		if record.Timestamp.After(end) {
+			end = record.Timestamp
+		}
+	}
+	width := end.Sub(start) / time.Duration(buckets)
+	if width <= 0 {
+		// 退化的時間範圍全部計入第一個區間，避免零寬度區間
+		width = end.Sub(start) + 1
+	}
+
+	for i, record := range records {
+		series, exists := sparklines[record.Activity.Type]
+		if !exists {
+			series = make([]float64, buckets)
+			sparklines[record.Activity.Type] = series
+		}
+
+		if breakdowns[i] == nil {
+			continue
+		}
+		series[sparklineBucket(record.Timestamp.Sub(start), width, buckets)] += breakdowns[i].TotalCost
+	}
+
+	return sparklines
+}
+
+// sparklineBucket 取得距起點 offset 的時間所屬區間（width 需大於 0）
+func sparklineBucket(offset, width time.Duration, buckets int) int {
+	index := int(offset / width)
+	if index >= buckets {
+		index = buckets - 1
+	}
+	return index
+}
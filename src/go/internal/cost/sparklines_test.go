@@ -0,0 +1,61 @@
+package cost
+
+import (
+	"math"
+	"testing"
+	"time"
+	"token-monitor/internal/types"
+)
+
+// TestActivitySparklines 測試依時間區間產生各活動的成本序列
+func TestActivitySparklines(t *testing.T) {
+	calculator := NewCostCalculator()
+	base := time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+
+	records := []types.UsageRecord{
+		newTestRecord(base, types.ActivityCoding, 1_000_000, 0, "claude-sonnet-4.0"),
+		newTestRecord(base.Add(1*time.Hour), types.ActivityCoding, 1_000_000, 0, "claude-sonnet-4.0"),
+		newTestRecord(base.Add(2*time.Hour), types.ActivityChat, 1_000_000, 0, "claude-sonnet-4.0"),
+		newTestRecord(base.Add(4*time.Hour), types.ActivityCoding, 1_000_000, 0, "claude-sonnet-4.0"),
+		newTestRecord(base.Add(3*time.Hour), types.ActivityChat, 100, 0, "unknown-model"), // 無法計算成本
+	}
+
+	sparklines := calculator.ActivitySparklines(records, 4)
+
+	// 每個區間 1 小時；claude-sonnet-4.0 輸入 1M tokens = $3
+	expected := map[types.ActivityType][]float64{
+		types.ActivityCoding: {3, 3, 0, 3},
+		types.ActivityChat:   {0, 0, 3, 0},
+	}
+	for activityType, series := range expected {
+		got := sparklines[activityType]
+		if len(got) != len(series) {
+			t.Fatalf("%s: expected %d buckets, got %d", activityType, len(series), len(got))
+		}
+		for i := range series {
+			if math.Abs(got[i]-series[i]) > 1e-9 {
+				t.Errorf("%s: expected %v, got %v", activityType, series, got)
+				break
+			}
+		}
+	}
+
+	if len(calculator.ActivitySparklines(records, 0)) != 0 || len(calculator.ActivitySparklines(nil, 4)) != 0 {
+		t.Errorf("Expected empty sparklines for invalid input")
+	}
+
+	// 所有記錄時間相同時序列長度不變，成本全部計入第一個區間
+	same := calculator.ActivitySparklines([]types.UsageRecord{records[0], records[0]}, 3)
+	if got := same[types.ActivityCoding]; len(got) != 3 || math.Abs(got[0]-6) > 1e-9 || got[1] != 0 || got[2] != 0 {
+		t.Errorf("Expected 3 buckets with cost 6 in the first for identical timestamps, got %v", got)
+	}
+
+	// 區間數超過時間範圍（奈秒）時同樣全部計入第一個區間
+	narrow := []types.UsageRecord{
+		newTestRecord(base, types.ActivityCoding, 1_000_000, 0, "claude-sonnet-4.0"),
+		newTestRecord(base.Add(3*time.Nanosecond), types.ActivityCoding, 1_000_000, 0, "claude-sonnet-4.0"),
+	}
+	if got := calculator.ActivitySparklines(narrow, 10)[types.ActivityCoding]; len(got) != 10 || math.Abs(got[0]-6) > 1e-9 {
+		t.Errorf("Expected 10 buckets with cost 6 in the first for a range narrower than the bucket count, got %v", got)
+	}
+}
@@ -0,0 +1,37 @@
+package cost
+
+import (
+	"fmt"
+	"math"
+	"token-monitor/internal/types"
+)
+
+// StreamingOutputVariance 串流成本估算中輸出 Token 數的變動比例（±50%）
+const StreamingOutputVariance = 0.5
+
+// EstimateStreamingCost 估算輸出長度未知的串流回應成本。輸入 Token 數固定，輸出 Token 數以
+// expectedOutputTokens 為中心、上下浮動 StreamingOutputVariance，回傳低、預期與高三個成本估算（不記入成本追蹤）。
+func (cc *CostCalculatorImpl) EstimateStreamingCost(inputTokens int, expectedOutputTokens int, model string) (low, expected, high *types.CostBreakdown, err error) {
+	cc.mutex.RLock()
+	defer cc.mutex.RUnlock()
+
+	if inputTokens < 0 || expectedOutputTokens < 0 {
+		return nil, nil, nil, fmt.Errorf("token counts cannot be negative: input=%d, output=%d", inputTokens, expectedOutputTokens)
+	}
+
+	lowOutput := int(math.Floor(float64(expectedOutputTokens) * (1 - StreamingOutputVariance)))
+	highOutput := int(math.Ceil(float64(expectedOutputTokens) * (1 + StreamingOutputVariance)))
+
+	options := &CostOptions{Mode: StandardBilling}
+	if low, err = cc.computeDetailedCost(inputTokens, lowOutput, model, options); err != nil {
+		return nil, nil, nil, err
+	}
+	if expected, err = cc.computeDetailedCost(inputTokens, expectedOutputTokens, model, options); err != nil {
+		return nil, nil, nil, err
+	}
+	if high, err = cc.computeDetailedCost(inputTokens, highOutput, model, options); err != nil {
+		return nil, nil, nil, err
+	}
+
+	return low, expected, high, nil
+}
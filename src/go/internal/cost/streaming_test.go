@@ -0,0 +1,45 @@
+package cost
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// TestEstimateStreamingCost 測試串流回應的成本區間估算
+func TestEstimateStreamingCost(t *testing.T) {
+	calculator := NewCostCalculator()
+
+	low, expected, high, err := calculator.EstimateStreamingCost(1000, 1000, "claude-sonnet-4.0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if low.TokenCounts.Output != 500 || expected.TokenCounts.Output != 1000 || high.TokenCounts.Output != 1500 {
+		t.Errorf("Unexpected output tokens: low=%d expected=%d high=%d",
+			low.TokenCounts.Output, expected.TokenCounts.Output, high.TokenCounts.Output)
+	}
+	if low.InputCost != expected.InputCost || high.InputCost != expected.InputCost {
+		t.Errorf("Expected input cost to be identical across the band")
+	}
+	if !(low.TotalCost < expected.TotalCost && expected.TotalCost < high.TotalCost) {
+		t.Errorf("Expected low < expected < high, got %.6f %.6f %.6f", low.TotalCost, expected.TotalCost, high.TotalCost)
+	}
+
+	// 區間對稱於預期成本
+	if math.Abs((expected.TotalCost-low.TotalCost)-(high.TotalCost-expected.TotalCost)) > 1e-9 {
+		t.Errorf("Expected symmetric band, got %.6f %.6f %.6f", low.TotalCost, expected.TotalCost, high.TotalCost)
+	}
+
+	// 不記入成本追蹤
+	if total := calculator.GetDailyCost(time.Now().Format("2006-01-02")); total != 0 {
+		t.Errorf("Expected estimates not to be tracked, got total %.6f", total)
+	}
+
+	if _, _, _, err := calculator.EstimateStreamingCost(-1, 100, "claude-sonnet-4.0"); err == nil {
+		t.Error("Expected error for negative input tokens")
+	}
+	if _, _, _, err := calculator.EstimateStreamingCost(100, 100, "unknown-model"); err == nil {
+		t.Error("Expected error for unknown model")
+	}
+}
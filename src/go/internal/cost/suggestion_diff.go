@@ -0,0 +1,107 @@
+package cost
+
+import (
+	"math"
+	"sort"
+	"token-monitor/internal/types"
+)
+
+// SuggestionChange 節省金額有變化的優化建議
+type SuggestionChange struct {
+	ID         string  `json:"id"`
+	OldSaving  float64 `json:"old_saving"`
+	NewSaving  float64 `json:"new_saving"`
+	SavingDiff float64 `json:"saving_diff"` // NewSaving - OldSaving
+}
+
+// SuggestionDelta 兩次優化建議快照之間的差異
+type SuggestionDelta struct {
+	Added    []types.OptimizationSuggestion `json:"added"`
+	Resolved []types.OptimizationSuggestion `json:"resolved"`
+	Changed  []SuggestionChange             `json:"changed"`
+}
+
+// savingEpsilon 判斷節省金額是否變化的容許誤差（USD）
+const savingEpsilon = 1e-9
+
+// SuggestionID 取得建議的穩定識別碼，未設定 ID 時由 Type 與 Target 產生
+func SuggestionID(suggestion types.OptimizationSuggestion) string {
+	if suggestion.ID != "" {
+		return suggestion.ID
+	}
+	if suggestion.Target == "" {
+		return suggestion.Type
+	}
+	return suggestion.Type + ":" + suggestion.Target
+}
+
+// GetSuggestionsByType 取得指定類型的優化建議（維持原始順序），無符合項目時回傳空切片
+func GetSuggestionsByType(suggestions *types.OptimizationSuggestions, t string) []types.OptimizationSuggestion {
+	matched := []types.OptimizationSuggestion{}
+	if suggestions == nil {
+		return matched
+	}
+
+	for _, suggestion := range suggestions.Suggestions {
+		if suggestion.Type == t {
+			matched = append(matched, suggestion)
+		}
+	}
+	return matched
+}
+
+// DiffSuggestions 比較新舊優化建議，回傳新增、已解決及節省金額變化的建議（依 ID 排序）
+func DiffSuggestions(previous, current *types.OptimizationSuggestions) SuggestionDelta {
+	delta := SuggestionDelta{
+		Added:    []types.OptimizationSuggestion{},
+		Resolved: []types.OptimizationSuggestion{},
+		Changed:  []SuggestionChange{},
+	}
+
+	oldByID := indexSuggestions(previous)
+	newByID := indexSuggestions(current)
+
+	for id, suggestion := range newByID {
+		before, exists := oldByID[id]
+		if !exists {
+			delta.Added = append(delta.Added, suggestion)
+			continue
+		}
+
+		if math.Abs(suggestion.PotentialSaving-before.PotentialSaving) > savingEpsilon {
+			delta.Changed = append(delta.Changed, SuggestionChange{
+				ID:         id,
+				OldSaving:  before.PotentialSaving,
+				NewSaving:  suggestion.PotentialSaving,
+				SavingDiff: suggestion.PotentialSaving - before.PotentialSaving,
+			})
+		}
+	}
+
+	for id, suggestion := range oldByID {
+		if _, exists := newByID[id]; !exists {
+			delta.Resolved = append(delta.Resolved, suggestion)
+		}
+	}
+
+	sort.Slice(delta.Added, func(i, j int) bool { return SuggestionID(delta.Added[i]) < SuggestionID(delta.Added[j]) })
+	sort.Slice(delta.Resolved, func(i, j int) bool { return SuggestionID(delta.Resolved[i]) < SuggestionID(delta.Resolved[j]) })
+	sort.Slice(delta.Changed, func(i, j int) bool { return delta.Changed[i].ID < delta.Changed[j].ID })
+
+	return delta
+}
+
+// indexSuggestions 以識別碼建立建議索引
+func indexSuggestions(suggestions *types.OptimizationSuggestions) map[string]types.OptimizationSuggestion {
+	index := make(map[string]types.OptimizationSuggestion)
+	if suggestions == nil {
+		return index
+	}
+
+	for _, suggestion := range suggestions.Suggestions {
+		id := SuggestionID(suggestion)
+		suggestion.ID = id
+		index[id] = suggestion
+	}
+	return index
+}
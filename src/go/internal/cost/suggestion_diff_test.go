@@ -0,0 +1,117 @@
+package cost
+
+import (
+	"math"
+	"testing"
+	"token-monitor/internal/types"
+)
+
+// TestDiffSuggestions 測試優化建議快照差異
+func TestDiffSuggestions(t *testing.T) {
+	old := &types.OptimizationSuggestions{
+		Suggestions: []types.OptimizationSuggestion{
+			{Type: "cache", Target: "coding", PotentialSaving: 1.0},
+			{Type: "workflow", Target: "chat", PotentialSaving: 0.5},
+			{Type: "batch", Target: "coding-large", PotentialSaving: 0.2},
+		},
+	}
+	current := &types.OptimizationSuggestions{
+		Suggestions: []types.OptimizationSuggestion{
+			{Type: "cache", Target: "coding", PotentialSaving: 0.4},
+			{Type: "batch", Target: "coding-large", PotentialSaving: 0.2},
+			{Type: "model-switch", Target: "chat", PotentialSaving: 0.3},
+		},
+	}
+
+	delta := DiffSuggestions(old, current)
+
+	if len(delta.Added) != 1 || delta.Added[0].ID != "model-switch:chat" {
+		t.Errorf("Expected model-switch:chat to be added, got %+v", delta.Added)
+	}
+	if len(delta.Resolved) != 1 || delta.Resolved[0].ID != "workflow:chat" {
+		t.Errorf("Expected workflow:chat to be resolved, got %+v", delta.Resolved)
+	}
+	if len(delta.Changed) != 1 {
+		t.Fatalf("Expected 1 changed suggestion, got %+v", delta.Changed)
+	}
+	change := delta.Changed[0]
+	if change.ID != "cache:coding" || change.OldSaving != 1.0 || change.NewSaving != 0.4 {
+		t.Errorf("Unexpected change: %+v", change)
+	}
+
+	// nil 快照視為空
+	delta = DiffSuggestions(nil, current)
+	if len(delta.Added) != 3 || len(delta.Resolved) != 0 {
+		t.Errorf("Expected all suggestions added from nil snapshot, got %+v", delta)
+	}
+}
+
+// TestAnalyzeAndSuggestAssignsIDs 測試產生的建議具有穩定識別碼
+func TestAnalyzeAndSuggestAssignsIDs(t *testing.T) {
+	optimizer := NewOptimizer(NewPricingEngine())
+
+	var records []types.UsageRecord
+	for i := 0; i < 5; i++ {
+		record := types.UsageRecord{Activity: types.Activity{Type: types.ActivityChat}}
+		record.Tokens.Total = 20000
+		record.Cost.Total = 1.0
+		records = append(records, record)
+	}
+
+	suggestions, err := optimizer.AnalyzeAndSuggest(records)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(suggestions.Suggestions) == 0 {
+		t.Fatal("Expected at least one suggestion")
+	}
+
+	expectedWeighted := 0.0
+	for _, suggestion := range suggestions.Suggestions {
+		expectedWeighted += suggestion.PotentialSaving * suggestion.Confidence
+	}
+	if math.Abs(suggestions.ConfidenceWeightedSavings-expectedWeighted) > 1e-12 {
+		t.Errorf("Expected confidence weighted savings %f, got %f", expectedWeighted, suggestions.ConfidenceWeightedSavings)
+	}
+	if suggestions.ConfidenceWeightedSavings > suggestions.TotalSavings {
+		t.Errorf("Expected weighted savings not to exceed total savings")
+	}
+
+	for _, suggestion := range suggestions.Suggestions {
+		if suggestion.ID == "" || suggestion.ID != SuggestionID(types.OptimizationSuggestion{Type: suggestion.Type, Target: suggestion.Target}) {
+			t.Errorf("Expected deterministic ID from type and target, got %+v", suggestion)
+		}
+	}
+}
+
+// TestSuggestionsByType 測試依類型篩選與分組優化建議
+func TestSuggestionsByType(t *testing.T) {
+	suggestions := &types.OptimizationSuggestions{
+		Suggestions: []types.OptimizationSuggestion{
+			{Type: "cache", Target: "coding"},
+			{Type: "batch", Target: "coding-large"},
+			{Type: "cache", Target: "chat"},
+		},
+	}
+
+	cache := GetSuggestionsByType(suggestions, "cache")
+	if len(cache) != 2 || cache[0].Target != "coding" || cache[1].Target != "chat" {
+		t.Errorf("Expected 2 cache suggestions in order, got %+v", cache)
+	}
+	if missing := GetSuggestionsByType(suggestions, "workflow"); missing == nil || len(missing) != 0 {
+		t.Errorf("Expected empty slice for unknown type, got %+v", missing)
+	}
+	if got := GetSuggestionsByType(nil, "cache"); len(got) != 0 {
+		t.Errorf("Expected empty slice for nil suggestions, got %+v", got)
+	}
+
+	grouped := suggestions.SuggestionsByType()
+	if len(grouped) != 2 || len(grouped["cache"]) != 2 || len(grouped["batch"]) != 1 {
+		t.Errorf("Unexpected grouping: %+v", grouped)
+	}
+
+	var empty *types.OptimizationSuggestions
+	if grouped := empty.SuggestionsByType(); len(grouped) != 0 {
+		t.Errorf("Expected empty grouping for nil suggestions, got %+v", grouped)
+	}
+}
@@ -0,0 +1,64 @@
+package cost
+
+import (
+	"fmt"
+	"math"
+	"token-monitor/internal/types"
+)
+
+// DefaultInputFraction 只有總 Token 數的記錄預設的輸入比例
+const DefaultInputFraction = 0.5
+
+// SplitTotalTokens 依輸入比例將總 Token 數拆分為輸入/輸出（輸入四捨五入，其餘歸為輸出）。
+// 比例超出 [0, 1] 時截斷，NaN 視為 DefaultInputFraction。
+func SplitTotalTokens(total int, inputFraction float64) (input, output int) {
+	if total <= 0 {
+		return 0, 0
+	}
+
+	if math.IsNaN(inputFraction) {
+		inputFraction = DefaultInputFraction
+	}
+	inputFraction = math.Max(0, math.Min(1, inputFraction))
+
+	input = int(math.Round(float64(total) * inputFraction))
+	return input, total - input
+}
+
+// SetDefaultInputFraction 設定記錄只有總 Token 數（輸入/輸出皆為 0）時使用的輸入比例
+func (cc *CostCalculatorImpl) SetDefaultInputFraction(fraction float64) error {
+	if math.IsNaN(fraction) || fraction < 0 || fraction > 1 {
+		return fmt.Errorf("input fraction must be between 0 and 1: %v", fraction)
+	}
+
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	cc.inputFraction = fraction
+	return nil
+}
+
+// GetDefaultInputFraction 取得只有總 Token 數的記錄使用的輸入比例
+func (cc *CostCalculatorImpl) GetDefaultInputFraction() float64 {
+	cc.mutex.RLock()
+	defer cc.mutex.RUnlock()
+
+	return cc.inputFraction
+}
+
+// recordTokens 取得記錄用於計價的輸入/輸出 Token 數
+func (cc *CostCalculatorImpl) recordTokens(record types.UsageRecord) (input, output int) {
+	cc.mutex.RLock()
+	defer cc.mutex.RUnlock()
+
+	return cc.recordTokensLocked(record)
+}
+
+// recordTokensLocked 取得記錄用於計價的輸入/輸出 Token 數；輸入/輸出皆為 0 但有總數時
+// 依預設輸入比例拆分（呼叫者須持有讀取鎖）
+func (cc *CostCalculatorImpl) recordTokensLocked(record types.UsageRecord) (input, output int) {
+	if record.Tokens.Input != 0 || record.Tokens.Output != 0 || record.Tokens.Total <= 0 {
+		return record.Tokens.Input, record.Tokens.Output
+	}
+	return SplitTotalTokens(record.Tokens.Total, cc.inputFraction)
+}
@@ -0,0 +1,73 @@
+package cost
+
+import (
+	"math"
+	"testing"
+	"time"
+	"token-monitor/internal/types"
+)
+
+// TestSplitTotalTokens 測試總 Token 數拆分
+func TestSplitTotalTokens(t *testing.T) {
+	tests := []struct {
+		total         int
+		fraction      float64
+		input, output int
+	}{
+		{1000, 0.5, 500, 500},
+		{1001, 0.5, 501, 500},
+		{1000, 0.25, 250, 750},
+		{1000, 1.5, 1000, 0},
+		{1000, -0.2, 0, 1000},
+		{1000, math.NaN(), 500, 500},
+		{0, 0.5, 0, 0},
+		{-10, 0.5, 0, 0},
+	}
+
+	for _, tt := range tests {
+		input, output := SplitTotalTokens(tt.total, tt.fraction)
+		if input != tt.input || output != tt.output {
+			t.Errorf("SplitTotalTokens(%d, %v) = %d/%d, expected %d/%d",
+				tt.total, tt.fraction, input, output, tt.input, tt.output)
+		}
+	}
+}
+
+// TestDefaultInputFractionForTotalOnlyRecords 測試只有總 Token 數的記錄依預設比例計價
+func TestDefaultInputFractionForTotalOnlyRecords(t *testing.T) {
+	calculator := NewCostCalculator()
+	if calculator.GetDefaultInputFraction() != DefaultInputFraction {
+		t.Errorf("Expected default input fraction %v, got %v", DefaultInputFraction, calculator.GetDefaultInputFraction())
+	}
+
+	record := newTestRecord(time.Now(), types.ActivityCoding, 0, 0, "claude-sonnet-4.0")
+	record.Tokens.Total = 2000
+	records := []types.UsageRecord{record}
+
+	expected, _ := calculator.CalculateCost(1000, 1000, "claude-sonnet-4.0")
+	report, err := calculator.GenerateCostReport(records, &types.ReportOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if math.Abs(report.Summary.TotalCost-expected.TotalCost) > 1e-9 {
+		t.Errorf("Expected total-only record to cost %.6f, got %.6f", expected.TotalCost, report.Summary.TotalCost)
+	}
+
+	if err := calculator.SetDefaultInputFraction(0.25); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected, _ = calculator.CalculateCost(500, 1500, "claude-sonnet-4.0")
+	cost, _, err := calculator.GetCostForTimeRange(records, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if math.Abs(cost-expected.TotalCost) > 1e-9 {
+		t.Errorf("Expected %.6f with fraction 0.25, got %.6f", expected.TotalCost, cost)
+	}
+
+	for _, invalid := range []float64{-0.1, 1.1, math.NaN()} {
+		if err := calculator.SetDefaultInputFraction(invalid); err == nil {
+			t.Errorf("Expected error for input fraction %v", invalid)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package cost
+
+import (
+	"fmt"
+	"sort"
+	"time"
+	"token-monitor/internal/types"
+)
+
+// AnalyzeCostTrendsWithOptions 分析成本趨勢；options.FillGaps 為 true 時在首末區間之間補上零成本資料點，
+// 成長率與預測依補齊後的連續序列計算
+func (cc *CostCalculatorImpl) AnalyzeCostTrendsWithOptions(records []types.UsageRecord, timeRange string, options *types.ReportOptions) (*types.CostTrendAnalysis, error) {
+	if options == nil || !options.FillGaps {
+		return cc.AnalyzeCostTrends(records, timeRange)
+	}
+
+	cc.mutex.RLock()
+	defer cc.mutex.RUnlock()
+
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no usage records provided")
+	}
+
+	dataPoints := fillCostGaps(cc.costDataPointsLocked(records, timeRange), timeRange)
+	return buildCostTrends(timeRange, dataPoints), nil
+}
+
+// fillCostGaps 依時間排序資料點，並為首末區間之間沒有記錄的區間插入零成本資料點
+func fillCostGaps(dataPoints []types.CostDataPoint, timeRange string) []types.CostDataPoint {
+	if len(dataPoints) < 2 {
+		return dataPoints
+	}
+
+	sort.Slice(dataPoints, func(i, j int) bool {
+		return dataPoints[i].Timestamp.Before(dataPoints[j].Timestamp)
+	})
+
+	filled := make([]types.CostDataPoint, 0, len(dataPoints))
+	for i, dataPoint := range dataPoints {
+		if i > 0 {
+			for bucket := nextTimeBucket(dataPoints[i-1].Timestamp, timeRange); bucket.Before(dataPoint.Timestamp); bucket = nextTimeBucket(bucket, timeRange) {
+				filled = append(filled, types.CostDataPoint{Timestamp: bucket})
+			}
+		}
+		filled = append(filled, dataPoint)
+	}
+	return filled
+}
+
+// nextTimeBucket 取得下一個時間區間的起點（與 timeBucketKey 對齊）
+func nextTimeBucket(bucket time.Time, timeRange string) time.Time {
+	switch timeRange {
+	case "hourly":
+		return bucket.Add(time.Hour)
+	case "weekly":
+		return timeBucketKey(bucket.AddDate(0, 0, 7), timeRange)
+	case "monthly":
+		return timeBucketKey(bucket.AddDate(0, 1, 0), timeRange)
+	default:
+		return bucket.Add(24 * time.Hour)
+	}
+}
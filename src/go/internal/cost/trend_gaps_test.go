@@ -0,0 +1,77 @@
+package cost
+
+import (
+	"math"
+	"testing"
+	"time"
+	"token-monitor/internal/types"
+)
+
+// TestAnalyzeCostTrendsFillGaps 測試成本趨勢補齊空白區間
+func TestAnalyzeCostTrendsFillGaps(t *testing.T) {
+	calculator := NewCostCalculator()
+	day := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	records := []types.UsageRecord{
+		newTestRecord(day, types.ActivityCoding, 1000, 1000, "claude-sonnet-4.0"),
+		newTestRecord(day.AddDate(0, 0, 1), types.ActivityCoding, 2000, 2000, "claude-sonnet-4.0"),
+		newTestRecord(day.AddDate(0, 0, 4), types.ActivityCoding, 4000, 4000, "claude-sonnet-4.0"),
+	}
+
+	sparse, err := calculator.AnalyzeCostTrendsWithOptions(records, "daily", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(sparse.DataPoints) != 3 {
+		t.Fatalf("Expected 3 sparse data points, got %d", len(sparse.DataPoints))
+	}
+
+	filled, err := calculator.AnalyzeCostTrendsWithOptions(records, "daily", &types.ReportOptions{FillGaps: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(filled.DataPoints) != 5 {
+		t.Fatalf("Expected 5 continuous data points, got %d", len(filled.DataPoints))
+	}
+	for i, dataPoint := range filled.DataPoints {
+		expected := timeBucketKey(day.AddDate(0, 0, i), "daily")
+		if !dataPoint.Timestamp.Equal(expected) {
+			t.Errorf("Data point %d: expected %s, got %s", i, expected, dataPoint.Timestamp)
+		}
+	}
+	if filled.DataPoints[2].Cost != 0 || filled.DataPoints[3].Cost != 0 || filled.DataPoints[2].RecordCount != 0 {
+		t.Errorf("Expected zero-cost gap points, got %+v", filled.DataPoints[2:4])
+	}
+	if math.Abs(filled.TotalCost-sparse.TotalCost) > 1e-12 {
+		t.Errorf("Expected total cost unchanged, got %f vs %f", filled.TotalCost, sparse.TotalCost)
+	}
+	if math.Abs(filled.AverageCost-filled.TotalCost/5) > 1e-12 {
+		t.Errorf("Expected average over the filled series, got %f", filled.AverageCost)
+	}
+
+	report, err := calculator.GenerateCostReport(records, &types.ReportOptions{FillGaps: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(report.Trends.DataPoints) != 5 {
+		t.Errorf("Expected report trends to be gap-filled, got %d points", len(report.Trends.DataPoints))
+	}
+}
+
+// TestNextTimeBucket 測試各時間區間的下一個區間起點
+func TestNextTimeBucket(t *testing.T) {
+	january := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if next := nextTimeBucket(january, "monthly"); !next.Equal(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected February, got %s", next)
+	}
+
+	monday := timeBucketKey(time.Date(2024, 3, 6, 15, 0, 0, 0, time.UTC), "weekly")
+	if next := nextTimeBucket(monday, "weekly"); !next.Equal(monday.AddDate(0, 0, 7)) {
+		t.Errorf("Expected next Monday, got %s", next)
+	}
+
+	hour := january.Add(5 * time.Hour)
+	if next := nextTimeBucket(hour, "hourly"); !next.Equal(hour.Add(time.Hour)) {
+		t.Errorf("Expected next hour, got %s", next)
+	}
+}
@@ -0,0 +1,375 @@
+package cost
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"token-monitor/internal/types"
+)
+
+// 可調整參數鍵名（GetTunables / ApplyTunables）
+const (
+	TunableDefaultModel               = "default_model"
+	TunableSessionGap                 = "session_gap"
+	TunableDailyTrackingEnabled       = "daily_tracking_enabled"
+	TunableDailyRetentionDays         = "daily_retention_days"
+	TunableCacheThreshold             = "cache_threshold"
+	TunableBatchThreshold             = "batch_threshold"
+	TunableConfidenceMin              = "confidence_min"
+	TunableMinSaving                  = "min_saving"
+	TunableWarnOutputBelowInput       = "warn_output_below_input"
+	TunableDefaultInputFraction       = "default_input_fraction"
+	TunableRateUnit                   = "rate_unit"
+	TunableDeprecationWarningInterval = "deprecation_warning_interval"
+	TunableActivityModelOverrides     = "activity_model_overrides"
+	TunableOpenAIModelAliases         = "openai_model_aliases"
+)
+
+// calculatorTunables 計算器可調整參數的快照
+type calculatorTunables struct {
+	defaultModel           string
+	sessionGap             time.Duration
+	dailyTrackingEnabled   bool
+	dailyRetentionDays     int
+	cacheThreshold         int
+	batchThreshold         int
+	confidenceMin          float64
+	minSaving              float64
+	warnOutputBelowInput   bool
+	defaultInputFraction   float64
+	rateUnit               string
+	deprecationInterval    time.Duration
+	activityModelOverrides map[types.ActivityType]string
+	openAIModelAliases     map[string]string
+}
+
+// GetTunables 取得所有可調整參數的快照，可直接傳給 ApplyTunables 還原
+func (cc *CostCalculatorImpl) GetTunables() map[string]interface{} {
+	cc.mutex.RLock()
+	defer cc.mutex.RUnlock()
+
+	current := cc.currentTunablesLocked()
+	return map[string]interface{}{
+		TunableDefaultModel:               current.defaultModel,
+		TunableSessionGap:                 current.sessionGap,
+		TunableDailyTrackingEnabled:       current.dailyTrackingEnabled,
+		TunableDailyRetentionDays:         current.dailyRetentionDays,
+		TunableCacheThreshold:             current.cacheThreshold,
+		TunableBatchThreshold:             current.batchThreshold,
+		TunableConfidenceMin:              current.confidenceMin,
+		TunableMinSaving:                  current.minSaving,
+		TunableWarnOutputBelowInput:       current.warnOutputBelowInput,
+		TunableDefaultInputFraction:       current.defaultInputFraction,
+		TunableRateUnit:                   current.rateUnit,
+		TunableDeprecationWarningInterval: current.deprecationInterval,
+		TunableActivityModelOverrides:     current.activityModelOverrides,
+		TunableOpenAIModelAliases:         current.openAIModelAliases,
+	}
+}
+
+// ApplyTunables 套用可調整參數。未提供的鍵維持原值；任一鍵未知或值無效時回傳錯誤且不套用任何變更。
+// session_gap 與 deprecation_warning_interval 接受 time.Duration、時間字串（如 "30m"）或奈秒數值；
+// activity_model_overrides 與 openai_model_aliases 取代整個對應表。
+func (cc *CostCalculatorImpl) ApplyTunables(values map[string]interface{}) error {
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	next := cc.currentTunablesLocked()
+
+	// 依鍵名排序，確保錯誤訊息穩定
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var problems []string
+	for _, key := range keys {
+		if err := next.set(key, values[key]); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", key, err))
+		}
+	}
+
+	if _, ok := values[TunableDefaultModel]; ok {
+		if _, err := cc.pricingEngine.GetPricingModel(next.defaultModel); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", TunableDefaultModel, err))
+		}
+	}
+	if _, ok := values[TunableActivityModelOverrides]; ok {
+		for _, activityType := range sortedActivityTypes(next.activityModelOverrides) {
+			if _, err := cc.pricingEngine.GetPricingModel(next.activityModelOverrides[activityType]); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %s: %v", TunableActivityModelOverrides, activityType, err))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid tunables: %s", strings.Join(problems, "; "))
+	}
+
+	if err := cc.pricingEngine.SetDefaultModel(next.defaultModel); err != nil {
+		return fmt.Errorf("invalid tunables: %s: %w", TunableDefaultModel, err)
+	}
+	if err := cc.pricingEngine.SetRateUnit(next.rateUnit); err != nil {
+		return fmt.Errorf("invalid tunables: %s: %w", TunableRateUnit, err)
+	}
+	cc.pricingEngine.SetOutputBelowInputWarning(next.warnOutputBelowInput)
+	cc.pricingEngine.SetDeprecationWarningInterval(next.deprecationInterval)
+
+	cc.sessionGap = next.sessionGap
+	cc.dailyTrackingDisabled = !next.dailyTrackingEnabled
+	cc.dailyRetentionDays = next.dailyRetentionDays
+	cc.optimizer.cacheThreshold = next.cacheThreshold
+	cc.optimizer.batchThreshold = next.batchThreshold
+	cc.optimizer.confidenceMin = next.confidenceMin
+	cc.optimizer.minSaving = next.minSaving
+	cc.inputFraction = next.defaultInputFraction
+	cc.activityModelOverrides = next.activityModelOverrides
+	cc.openAIModelAliases = next.openAIModelAliases
+
+	cc.pruneDailyCosts(time.Now())
+
+	return nil
+}
+
+// currentTunablesLocked 讀取目前的參數（呼叫者需持有 mutex）
+func (cc *CostCalculatorImpl) currentTunablesLocked() calculatorTunables {
+	cc.pricingEngine.mutex.RLock()
+	warnOutputBelowInput := cc.pricingEngine.warnOutputBelowInput
+	rateUnit := cc.pricingEngine.rateUnit
+	cc.pricingEngine.mutex.RUnlock()
+
+	cc.pricingEngine.deprecationMutex.Lock()
+	deprecationInterval := cc.pricingEngine.deprecationInterval
+	cc.pricingEngine.deprecationMutex.Unlock()
+
+	overrides := make(map[types.ActivityType]string, len(cc.activityModelOverrides))
+	for activityType, model := range cc.activityModelOverrides {
+		overrides[activityType] = model
+	}
+	aliases := make(map[string]string, len(cc.openAIModelAliases))
+	for openAIModel, pricingModel := range cc.openAIModelAliases {
+		aliases[openAIModel] = pricingModel
+	}
+
+	return calculatorTunables{
+		defaultModel:           cc.pricingEngine.GetDefaultModel(),
+		sessionGap:             cc.sessionGap,
+		dailyTrackingEnabled:   !cc.dailyTrackingDisabled,
+		dailyRetentionDays:     cc.dailyRetentionDays,
+		cacheThreshold:         cc.optimizer.cacheThreshold,
+		batchThreshold:         cc.optimizer.batchThreshold,
+		confidenceMin:          cc.optimizer.confidenceMin,
+		minSaving:              cc.optimizer.minSaving,
+		warnOutputBelowInput:   warnOutputBelowInput,
+		defaultInputFraction:   cc.inputFraction,
+		rateUnit:               rateUnit,
+		deprecationInterval:    deprecationInterval,
+		activityModelOverrides: overrides,
+		openAIModelAliases:     aliases,
+	}
+}
+
+// set 驗證並設定單一參數
+func (t *calculatorTunables) set(key string, value interface{}) error {
+	switch key {
+	case TunableDefaultModel:
+		name, ok := value.(string)
+		if !ok || name == "" {
+			return fmt.Errorf("must be a non-empty string")
+		}
+		t.defaultModel = name
+	case TunableSessionGap:
+		gap, err := tunableDuration(value)
+		if err != nil {
+			return err
+		}
+		t.sessionGap = gap
+	case TunableDailyTrackingEnabled:
+		enabled, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("must be a bool")
+		}
+		t.dailyTrackingEnabled = enabled
+	case TunableDailyRetentionDays:
+		days, err := tunableInt(value)
+		if err != nil {
+			return err
+		}
+		if days < 0 {
+			return fmt.Errorf("must be >= 0")
+		}
+		t.dailyRetentionDays = days
+	case TunableCacheThreshold, TunableBatchThreshold:
+		threshold, err := tunableInt(value)
+		if err != nil {
+			return err
+		}
+		if threshold <= 0 {
+			return fmt.Errorf("must be > 0")
+		}
+		if key == TunableCacheThreshold {
+			t.cacheThreshold = threshold
+		} else {
+			t.batchThreshold = threshold
+		}
+	case TunableConfidenceMin:
+		confidence, err := tunableFloat(value)
+		if err != nil {
+			return err
+		}
+		if math.IsNaN(confidence) || confidence <= 0 || confidence > 1 {
+			return fmt.Errorf("must be in (0, 1]")
+		}
+		t.confidenceMin = confidence
+	case TunableMinSaving:
+		saving, err := tunableFloat(value)
+		if err != nil {
+			return err
+		}
+		if math.IsNaN(saving) || math.IsInf(saving, 0) || saving <= 0 {
+			return fmt.Errorf("must be a finite value > 0")
+		}
+		t.minSaving = saving
+	case TunableWarnOutputBelowInput:
+		enabled, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("must be a bool")
+		}
+		t.warnOutputBelowInput = enabled
+	case TunableDefaultInputFraction:
+		fraction, err := tunableFloat(value)
+		if err != nil {
+			return err
+		}
+		if math.IsNaN(fraction) || fraction < 0 || fraction > 1 {
+			return fmt.Errorf("must be in [0, 1]")
+		}
+		t.defaultInputFraction = fraction
+	case TunableRateUnit:
+		unit, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("must be a string")
+		}
+		normalized, err := normalizeRateUnit(unit)
+		if err != nil {
+			return err
+		}
+		t.rateUnit = normalized
+	case TunableDeprecationWarningInterval:
+		interval, err := tunableDuration(value)
+		if err != nil {
+			return err
+		}
+		t.deprecationInterval = interval
+	case TunableActivityModelOverrides:
+		mapping, err := tunableStringMap(value)
+		if err != nil {
+			return err
+		}
+		overrides := make(map[types.ActivityType]string, len(mapping))
+		for activityType, model := range mapping {
+			if model == "" {
+				return fmt.Errorf("override model for %s must be non-empty", activityType)
+			}
+			overrides[types.ActivityType(activityType)] = model
+		}
+		t.activityModelOverrides = overrides
+	case TunableOpenAIModelAliases:
+		aliases, err := tunableStringMap(value)
+		if err != nil {
+			return err
+		}
+		t.openAIModelAliases = aliases
+	default:
+		return fmt.Errorf("unknown tunable")
+	}
+	return nil
+}
+
+// tunableFloat 將數值型別轉為 float64
+func tunableFloat(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	default:
+		return 0, fmt.Errorf("must be a number, got %T", value)
+	}
+}
+
+// tunableInt 將數值型別轉為 int，浮點數必須為整數值（如 JSON 解碼結果）
+func tunableInt(value interface{}) (int, error) {
+	switch v := value.(type) {
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case float64:
+		if v != math.Trunc(v) {
+			return 0, fmt.Errorf("must be an integer, got %v", v)
+		}
+		return int(v), nil
+	default:
+		return 0, fmt.Errorf("must be an integer, got %T", value)
+	}
+}
+
+// tunableStringMap 將字串對應表（含活動類型鍵或 JSON 解碼結果）轉為 map[string]string
+func tunableStringMap(value interface{}) (map[string]string, error) {
+	result := make(map[string]string)
+	switch v := value.(type) {
+	case map[string]string:
+		for key, item := range v {
+			result[key] = item
+		}
+	case map[types.ActivityType]string:
+		for key, item := range v {
+			result[string(key)] = item
+		}
+	case map[string]interface{}:
+		for key, item := range v {
+			text, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("value for %s must be a string, got %T", key, item)
+			}
+			result[key] = text
+		}
+	default:
+		return nil, fmt.Errorf("must be a string map, got %T", value)
+	}
+	return result, nil
+}
+
+// sortedActivityTypes 取得排序後的活動類型鍵，確保錯誤訊息穩定
+func sortedActivityTypes(overrides map[types.ActivityType]string) []types.ActivityType {
+	keys := make([]types.ActivityType, 0, len(overrides))
+	for activityType := range overrides {
+		keys = append(keys, activityType)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+// tunableDuration 將 time.Duration、時間字串或奈秒數值轉為 time.Duration
+func tunableDuration(value interface{}) (time.Duration, error) {
+	switch v := value.(type) {
+	case time.Duration:
+		return v, nil
+	case string:
+		return time.ParseDuration(v)
+	default:
+		nanos, err := tunableInt(value)
+		if err != nil {
+			return 0, fmt.Errorf("must be a duration, got %T", value)
+		}
+		return time.Duration(nanos), nil
+	}
+}
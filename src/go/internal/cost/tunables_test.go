@@ -0,0 +1,151 @@
+package cost
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"token-monitor/internal/types"
+)
+
+// TestTunablesRoundTrip 測試參數快照可還原，且經 JSON 序列化後仍可套用
+func TestTunablesRoundTrip(t *testing.T) {
+	calculator := NewCostCalculator()
+	snapshot := calculator.GetTunables()
+
+	err := calculator.ApplyTunables(map[string]interface{}{
+		TunableDefaultModel:               "claude-haiku-3.5",
+		TunableSessionGap:                 "45m",
+		TunableDailyTrackingEnabled:       false,
+		TunableDailyRetentionDays:         7,
+		TunableCacheThreshold:             2000,
+		TunableConfidenceMin:              0.8,
+		TunableDefaultInputFraction:       0.7,
+		TunableRateUnit:                   "ktok",
+		TunableDeprecationWarningInterval: "1h",
+		TunableActivityModelOverrides:     map[types.ActivityType]string{types.ActivityDocumentation: "claude-haiku-3.5"},
+		TunableOpenAIModelAliases:         map[string]string{"gpt-4o": "claude-sonnet-4.0"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	changed := calculator.GetTunables()
+	if changed[TunableDefaultModel] != "claude-haiku-3.5" || changed[TunableSessionGap] != 45*time.Minute {
+		t.Errorf("Expected tunables to be applied, got %v", changed)
+	}
+	if calculator.IsDailyTrackingEnabled() {
+		t.Errorf("Expected daily tracking to be disabled")
+	}
+	if calculator.GetDefaultInputFraction() != 0.7 || calculator.pricingEngine.GetRateUnit() != RateUnitKTok {
+		t.Errorf("Expected input fraction and rate unit to be applied, got %v / %s",
+			calculator.GetDefaultInputFraction(), calculator.pricingEngine.GetRateUnit())
+	}
+	if calculator.pricingEngine.deprecationInterval != time.Hour {
+		t.Errorf("Expected deprecation warning interval to be applied, got %v", calculator.pricingEngine.deprecationInterval)
+	}
+	if calculator.GetActivityModelOverrides()[types.ActivityDocumentation] != "claude-haiku-3.5" {
+		t.Errorf("Expected activity override to be applied, got %v", calculator.GetActivityModelOverrides())
+	}
+	if changed[TunableOpenAIModelAliases].(map[string]string)["gpt-4o"] != "claude-sonnet-4.0" {
+		t.Errorf("Expected OpenAI aliases to be applied, got %v", changed[TunableOpenAIModelAliases])
+	}
+	// 未提供的鍵維持原值
+	if changed[TunableBatchThreshold] != snapshot[TunableBatchThreshold] {
+		t.Errorf("Expected batch threshold to be unchanged, got %v", changed[TunableBatchThreshold])
+	}
+
+	// 經 JSON 往返後還原
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := calculator.ApplyTunables(decoded); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if restored := calculator.GetTunables(); !reflect.DeepEqual(restored, snapshot) {
+		t.Errorf("Expected restored tunables %v, got %v", snapshot, restored)
+	}
+}
+
+// TestApplyTunablesValidation 測試任一參數無效時不套用任何變更
+func TestApplyTunablesValidation(t *testing.T) {
+	calculator := NewCostCalculator()
+	snapshot := calculator.GetTunables()
+
+	invalid := []map[string]interface{}{
+		{TunableCacheThreshold: 5000, TunableConfidenceMin: 1.5},
+		{TunableCacheThreshold: 5000, TunableDefaultModel: "unknown-model"},
+		{TunableCacheThreshold: 5000, "unknown_key": 1},
+		{TunableDailyRetentionDays: 2.5},
+		{TunableSessionGap: true},
+		{TunableDefaultInputFraction: 1.5},
+		{TunableConfidenceMin: math.NaN()},
+		{TunableMinSaving: math.NaN()},
+		{TunableMinSaving: math.Inf(1)},
+		{TunableRateUnit: "GTok"},
+		{TunableActivityModelOverrides: map[string]string{"coding": "unknown-model"}},
+		{TunableOpenAIModelAliases: map[string]interface{}{"gpt-4o": 1}},
+	}
+
+	for _, values := range invalid {
+		if err := calculator.ApplyTunables(values); err == nil {
+			t.Errorf("Expected error for %v", values)
+		}
+		if current := calculator.GetTunables(); !reflect.DeepEqual(current, snapshot) {
+			t.Errorf("Expected no changes after invalid apply %v, got %v", values, current)
+		}
+	}
+}
+
+// TestTunablesCoverSetters 測試 CostCalculatorImpl 與 PricingEngine 的每個公開 Set* 方法都有對應的可調整參數
+func TestTunablesCoverSetters(t *testing.T) {
+	// 設定方法對應的參數鍵；新增 Set* 方法時須在此登錄並加入 GetTunables / ApplyTunables
+	registry := map[string][]string{
+		"SetDefaultModel":               {TunableDefaultModel},
+		"SetSessionGap":                 {TunableSessionGap},
+		"SetDailyTrackingEnabled":       {TunableDailyTrackingEnabled},
+		"SetDailyTrackingRetention":     {TunableDailyRetentionDays},
+		"SetOptimizationThresholds":     {TunableCacheThreshold, TunableBatchThreshold, TunableConfidenceMin, TunableMinSaving},
+		"SetOutputBelowInputWarning":    {TunableWarnOutputBelowInput},
+		"SetDefaultInputFraction":       {TunableDefaultInputFraction},
+		"SetRateUnit":                   {TunableRateUnit},
+		"SetDeprecationWarningInterval": {TunableDeprecationWarningInterval},
+		"SetActivityModelOverride":      {TunableActivityModelOverrides},
+		"SetOpenAIModelAliases":         {TunableOpenAIModelAliases},
+	}
+	// 設定函式的方法無法快照與序列化，不列為可調整參數
+	excluded := map[string]bool{
+		"SetOptimizationConfidenceFuncs": true,
+		"SetRecordKeyFunc":               true,
+	}
+
+	calculator := NewCostCalculator()
+	tunables := calculator.GetTunables()
+
+	for _, target := range []interface{}{calculator, calculator.pricingEngine} {
+		targetType := reflect.TypeOf(target)
+		for i := 0; i < targetType.NumMethod(); i++ {
+			name := targetType.Method(i).Name
+			if !strings.HasPrefix(name, "Set") || excluded[name] {
+				continue
+			}
+			keys, ok := registry[name]
+			if !ok {
+				t.Errorf("%s.%s has no tunables entry", targetType.Elem().Name(), name)
+				continue
+			}
+			for _, key := range keys {
+				if _, exists := tunables[key]; !exists {
+					t.Errorf("%s.%s maps to %s, which GetTunables does not report", targetType.Elem().Name(), name, key)
+				}
+			}
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -204,6 +205,109 @@ func TestErrorHandler_Handle(t *testing.T) {
 	}
 }
 
+// TestErrorHandler_ErrorStats 測試錯誤次數統計
+func TestErrorHandler_ErrorStats(t *testing.T) {
+	handler := NewErrorHandler()
+	handler.SetLogger(&discardLogger{})
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			handler.Handle(ctx, New(ErrCodeTiktokenUnavailable, "fallback"))
+		}()
+	}
+	wg.Wait()
+	handler.Handle(ctx, New(ErrCodeTokenCalculation, "failed"))
+	handler.Handle(ctx, fmt.Errorf("plain error"))
+
+	stats := handler.GetErrorStats()
+	if stats[ErrCodeTiktokenUnavailable] != 10 {
+		t.Errorf("Expected 10 tiktoken fallbacks, got %d", stats[ErrCodeTiktokenUnavailable])
+	}
+	if stats[ErrCodeTokenCalculation] != 1 || stats[ErrCodeSystemResource] != 1 {
+		t.Errorf("Unexpected stats: %v", stats)
+	}
+
+	// 回傳的統計為複本
+	stats[ErrCodeTokenCalculation] = 100
+	if handler.GetErrorStats()[ErrCodeTokenCalculation] != 1 {
+		t.Error("Expected GetErrorStats to return a copy")
+	}
+
+	handler.ResetErrorStats()
+	if len(handler.GetErrorStats()) != 0 {
+		t.Errorf("Expected empty stats after reset, got %v", handler.GetErrorStats())
+	}
+}
+
+// TestErrorHandler_SeveritySampling 測試依嚴重級別的日誌取樣
+func TestErrorHandler_SeveritySampling(t *testing.T) {
+	handler := NewErrorHandler()
+	logger := &countingLogger{}
+	handler.SetLogger(logger)
+	handler.SetSeveritySampling(map[ErrorSeverity]int{
+		SeverityMedium:   5,
+		SeverityCritical: 100, // 關鍵錯誤不取樣
+	})
+	ctx := context.Background()
+
+	for i := 0; i < 12; i++ {
+		handler.Handle(ctx, New(ErrCodeTiktokenUnavailable, "fallback")) // medium
+		handler.Handle(ctx, New(ErrCodeTokenCalculation, "failed"))      // high，未設定取樣
+	}
+	for i := 0; i < 3; i++ {
+		handler.Handle(ctx, New(ErrCodeSystemResource, "critical"))
+	}
+
+	// medium 第 1、6、11 筆記錄
+	if logger.warns != 3 {
+		t.Errorf("Expected 3 sampled warnings, got %d", logger.warns)
+	}
+	if logger.errors != 15 {
+		t.Errorf("Expected all 15 high/critical errors to be logged, got %d", logger.errors)
+	}
+
+	// 取樣不影響統計
+	if handler.GetErrorStats()[ErrCodeTiktokenUnavailable] != 12 {
+		t.Errorf("Expected stats to count every error, got %v", handler.GetErrorStats())
+	}
+
+	handler.SetSeveritySampling(nil)
+	logger.warns = 0
+	handler.Handle(ctx, New(ErrCodeTiktokenUnavailable, "fallback"))
+	handler.Handle(ctx, New(ErrCodeTiktokenUnavailable, "fallback"))
+	if logger.warns != 2 {
+		t.Errorf("Expected sampling to be disabled, got %d warnings", logger.warns)
+	}
+}
+
+// countingLogger 計算各級別日誌次數的記錄器
+type countingLogger struct {
+	errors, warns, infos int
+}
+
+func (l *countingLogger) Error(ctx context.Context, err error, fields map[string]interface{}) {
+	l.errors++
+}
+func (l *countingLogger) Warn(ctx context.Context, message string, fields map[string]interface{}) {
+	l.warns++
+}
+func (l *countingLogger) Info(ctx context.Context, message string, fields map[string]interface{}) {
+	l.infos++
+}
+func (l *countingLogger) Debug(ctx context.Context, message string, fields map[string]interface{}) {}
+
+// discardLogger 丟棄所有日誌的記錄器
+type discardLogger struct{}
+
+func (l *discardLogger) Error(ctx context.Context, err error, fields map[string]interface{})      {}
+func (l *discardLogger) Warn(ctx context.Context, message string, fields map[string]interface{})  {}
+func (l *discardLogger) Info(ctx context.Context, message string, fields map[string]interface{})  {}
+func (l *discardLogger) Debug(ctx context.Context, message string, fields map[string]interface{}) {}
+
 // TestRetryManager_Execute 測試重試管理器
 func TestRetryManager_Execute(t *testing.T) {
 	manager := NewRetryManager()
@@ -56,6 +56,13 @@ type DefaultErrorHandler struct {
 	logger         Logger
 	retryManager   *RetryManager
 	mu             sync.RWMutex
+
+	// stats 各錯誤代碼經 Handle 處理的次數
+	stats map[ErrorCode]int
+
+	// 依嚴重級別的日誌取樣：sampling 為每 N 筆記錄 1 筆，sampleCounts 為各級別已處理的筆數
+	sampling     map[ErrorSeverity]int
+	sampleCounts map[ErrorSeverity]int
 }
 
 // NewErrorHandler 建立新的錯誤處理器
@@ -64,6 +71,7 @@ func NewErrorHandler() *DefaultErrorHandler {
 		listeners:    make([]ErrorListener, 0),
 		retryManager: NewRetryManager(),
 		logger:       NewDefaultLogger(),
+		stats:        make(map[ErrorCode]int),
 	}
 }
 
@@ -84,8 +92,13 @@ func (h *DefaultErrorHandler) Handle(ctx context.Context, err error) error {
 	// 清理敏感資訊
 	sanitizedErr := Sanitize(appErr).(*AppError)
 
-	// 記錄錯誤
-	h.logError(ctx, sanitizedErr)
+	// 統計錯誤次數
+	h.recordStat(sanitizedErr.Code)
+
+	// 記錄錯誤（依嚴重級別取樣）
+	if h.shouldLog(sanitizedErr.Severity) {
+		h.logError(ctx, sanitizedErr)
+	}
 
 	// 通知監聽器
 	h.notifyListeners(ctx, sanitizedErr)
@@ -167,6 +180,65 @@ func (h *DefaultErrorHandler) SetLogger(logger Logger) {
 	h.logger = logger
 }
 
+// GetErrorStats 取得各錯誤代碼經 Handle 處理的次數
+func (h *DefaultErrorHandler) GetErrorStats() map[ErrorCode]int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	stats := make(map[ErrorCode]int, len(h.stats))
+	for code, count := range h.stats {
+		stats[code] = count
+	}
+	return stats
+}
+
+// ResetErrorStats 清除錯誤次數統計
+func (h *DefaultErrorHandler) ResetErrorStats() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.stats = make(map[ErrorCode]int)
+}
+
+// recordStat 累計錯誤代碼次數
+func (h *DefaultErrorHandler) recordStat(code ErrorCode) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.stats == nil {
+		h.stats = make(map[ErrorCode]int)
+	}
+	h.stats[code]++
+}
+
+// SetSeveritySampling 設定各嚴重級別的日誌取樣率，n 表示每 n 筆只記錄 1 筆（含第一筆）。
+// n <= 1 或未設定的級別全部記錄；關鍵錯誤一律記錄。取樣只影響日誌，統計與監聽器通知不受影響。
+func (h *DefaultErrorHandler) SetSeveritySampling(sampling map[ErrorSeverity]int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.sampling = make(map[ErrorSeverity]int, len(sampling))
+	for severity, n := range sampling {
+		if n > 1 && severity != SeverityCritical {
+			h.sampling[severity] = n
+		}
+	}
+	h.sampleCounts = make(map[ErrorSeverity]int)
+}
+
+// shouldLog 依取樣設定判斷是否記錄此嚴重級別的錯誤
+func (h *DefaultErrorHandler) shouldLog(severity ErrorSeverity) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	n := h.sampling[severity]
+	if n <= 1 {
+		return true
+	}
+
+	count := h.sampleCounts[severity]
+	h.sampleCounts[severity] = count + 1
+	return count%n == 0
+}
+
 // logError 記錄錯誤
 func (h *DefaultErrorHandler) logError(ctx context.Context, err *AppError) {
 	fields := map[string]interface{}{
@@ -26,12 +26,15 @@ const (
 	ErrCodeInvalidText           ErrorCode = "INVALID_TEXT_INPUT"
 	ErrCodeTokenCountExceeded    ErrorCode = "TOKEN_COUNT_EXCEEDED"
 	ErrCodeCalculationTimeout    ErrorCode = "CALCULATION_TIMEOUT"
+	ErrCodeSlowCalculation       ErrorCode = "SLOW_CALCULATION"
 
 	// 成本計算相關錯誤
 	ErrCodeCostCalculation       ErrorCode = "COST_CALCULATION_FAILED"
 	ErrCodeInvalidPricingModel   ErrorCode = "INVALID_PRICING_MODEL"
 	ErrCodePricingDataMissing    ErrorCode = "PRICING_DATA_MISSING"
 	ErrCodeInvalidTokenCount     ErrorCode = "INVALID_TOKEN_COUNT"
+	ErrCodeBudgetExceeded        ErrorCode = "BUDGET_EXCEEDED"
+	ErrCodeDeprecatedModel       ErrorCode = "DEPRECATED_PRICING_MODEL"
 
 	// 活動分析相關錯誤
 	ErrCodeActivityAnalysis      ErrorCode = "ACTIVITY_ANALYSIS_FAILED"
@@ -150,6 +153,17 @@ var errorMetadataMap = map[ErrorCode]ErrorMetadata{
 		SolutionZH:  "提供有效的非空文本輸入。",
 		Retryable:   false,
 	},
+	ErrCodeSlowCalculation: {
+		Code:        ErrCodeSlowCalculation,
+		Category:    CategoryToken,
+		Severity:    SeverityLow,
+		Message:     "Token calculation exceeded slow threshold",
+		MessageZH:   "Token 計算超過慢速門檻",
+		Description: "A token calculation took longer than the configured slow calculation threshold",
+		Solution:    "Inspect the input length and method, or use estimation for long texts.",
+		SolutionZH:  "檢查輸入長度與計算方法，或對長文本改用估算。",
+		Retryable:   false,
+	},
 	ErrCodeCostCalculation: {
 		Code:        ErrCodeCostCalculation,
 		Category:    CategoryCost,
@@ -178,6 +192,28 @@ var errorMetadataMap = map[ErrorCode]ErrorMetadata{
 		SolutionZH:  "使用支援的定價模型。使用 GetSupportedModels() 檢查可用模型。",
 		Retryable:   false,
 	},
+	ErrCodeBudgetExceeded: {
+		Code:        ErrCodeBudgetExceeded,
+		Category:    CategoryCost,
+		Severity:    SeverityMedium,
+		Message:     "Cost budget exceeded",
+		MessageZH:   "超出成本預算",
+		Description: "The operation would exceed the remaining cost budget",
+		Solution:    "Increase the budget or reduce token usage before retrying.",
+		SolutionZH:  "提高預算或減少 Token 使用量後再試。",
+		Retryable:   false,
+	},
+	ErrCodeDeprecatedModel: {
+		Code:        ErrCodeDeprecatedModel,
+		Category:    CategoryCost,
+		Severity:    SeverityLow,
+		Message:     "Deprecated pricing model in use",
+		MessageZH:   "使用已淘汰的定價模型",
+		Description: "The requested pricing model is marked as deprecated",
+		Solution:    "Switch to the replacement model.",
+		SolutionZH:  "改用替代的定價模型。",
+		Retryable:   false,
+	},
 	ErrCodeDataAccess: {
 		Code:        ErrCodeDataAccess,
 		Category:    CategoryData,
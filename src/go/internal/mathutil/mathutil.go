@@ -0,0 +1,27 @@
+// Package mathutil 提供共用的數值輔助函數
+package mathutil
+
+import "math"
+
+// AbsInt 計算整數絕對值
+func AbsInt(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// AbsFloat 計算浮點數絕對值
+func AbsFloat(x float64) float64 {
+	return math.Abs(x)
+}
+
+// RoundTo 四捨五入至指定小數位數（負數位數表示整數位，例如 -1 取整至十位）
+func RoundTo(x float64, places int) float64 {
+	if math.IsNaN(x) || math.IsInf(x, 0) {
+		return x
+	}
+
+	factor := math.Pow10(places)
+	return math.Round(x*factor) / factor
+}
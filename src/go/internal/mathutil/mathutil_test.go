@@ -0,0 +1,50 @@
+package mathutil
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAbsInt(t *testing.T) {
+	for _, tc := range []struct{ in, want int }{{0, 0}, {5, 5}, {-5, 5}} {
+		if got := AbsInt(tc.in); got != tc.want {
+			t.Errorf("AbsInt(%d) = %d, want %d", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestAbsFloat(t *testing.T) {
+	for _, tc := range []struct{ in, want float64 }{{0, 0}, {1.5, 1.5}, {-1.5, 1.5}} {
+		if got := AbsFloat(tc.in); got != tc.want {
+			t.Errorf("AbsFloat(%f) = %f, want %f", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestRoundTo(t *testing.T) {
+	testCases := []struct {
+		in     float64
+		places int
+		want   float64
+	}{
+		{1.2345, 2, 1.23},
+		{1.235, 0, 1},
+		{2.5, 0, 3},
+		{-2.5, 0, -3},
+		{0.125, 2, 0.13},
+		{1234, -2, 1200},
+	}
+
+	for _, tc := range testCases {
+		if got := RoundTo(tc.in, tc.places); math.Abs(got-tc.want) > 1e-12 {
+			t.Errorf("RoundTo(%f, %d) = %f, want %f", tc.in, tc.places, got, tc.want)
+		}
+	}
+
+	if got := RoundTo(math.Inf(1), 2); !math.IsInf(got, 1) {
+		t.Errorf("Expected +Inf to be returned unchanged, got %f", got)
+	}
+	if got := RoundTo(math.NaN(), 2); !math.IsNaN(got) {
+		t.Errorf("Expected NaN to be returned unchanged, got %f", got)
+	}
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"sort"
+
+	"token-monitor/internal/analyzer"
+	"token-monitor/internal/cost"
+	"token-monitor/internal/types"
+)
+
+// ActivityCostRank 單一活動類型的成本排名資訊
+type ActivityCostRank struct {
+	ActivityType types.ActivityType `json:"activity_type"`
+	TotalCost    float64            `json:"total_cost"`
+	TotalTokens  int                `json:"total_tokens"`
+	RecordCount  int                `json:"record_count"`
+	CostPerToken float64            `json:"cost_per_token"`
+}
+
+// RankActivitiesByCost 依總成本由高到低排列活動類型。
+// 未標記活動類型的記錄會以 ActivityAnalyzer 依內容分類；無法計算成本的記錄會被略過。
+func RankActivitiesByCost(records []types.UsageRecord, cc *cost.CostCalculatorImpl) []ActivityCostRank {
+	if cc == nil || len(records) == 0 {
+		return []ActivityCostRank{}
+	}
+
+	activityAnalyzer := analyzer.NewActivityAnalyzer()
+	breakdowns, _ := cc.CalculateCostBatch(records)
+
+	ranks := make(map[types.ActivityType]*ActivityCostRank)
+	for i, record := range records {
+		breakdown := breakdowns[i]
+		if breakdown == nil {
+			continue
+		}
+
+		activityType := record.Activity.Type
+		if activityType == "" {
+			activityType = activityAnalyzer.ClassifyActivity(record.Activity.Content)
+		}
+
+		rank, exists := ranks[activityType]
+		if !exists {
+			rank = &ActivityCostRank{ActivityType: activityType}
+			ranks[activityType] = rank
+		}
+		rank.TotalCost += breakdown.TotalCost
+		rank.TotalTokens += record.Tokens.Total
+		rank.RecordCount++
+	}
+
+	result := make([]ActivityCostRank, 0, len(ranks))
+	for _, rank := range ranks {
+		if rank.TotalTokens > 0 {
+			rank.CostPerToken = rank.TotalCost / float64(rank.TotalTokens)
+		}
+		result = append(result, *rank)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].TotalCost != result[j].TotalCost {
+			return result[i].TotalCost > result[j].TotalCost
+		}
+		return result[i].ActivityType < result[j].ActivityType
+	})
+
+	return result
+}
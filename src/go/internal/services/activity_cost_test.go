@@ -0,0 +1,63 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"token-monitor/internal/cost"
+	"token-monitor/internal/types"
+)
+
+// newRankTestRecord 建立排名測試用的使用記錄
+func newRankTestRecord(activityType types.ActivityType, content string, input, output int) types.UsageRecord {
+	record := types.UsageRecord{
+		Timestamp: time.Now(),
+		Activity:  types.Activity{Type: activityType, Content: content},
+	}
+	record.Tokens.Input = input
+	record.Tokens.Output = output
+	record.Tokens.Total = input + output
+	record.Cost.PricingModel = "claude-sonnet-4.0"
+	return record
+}
+
+// TestRankActivitiesByCost 測試活動類型成本排名
+func TestRankActivitiesByCost(t *testing.T) {
+	cc := cost.NewCostCalculator()
+
+	records := []types.UsageRecord{
+		newRankTestRecord(types.ActivityChat, "", 1000, 1000),
+		newRankTestRecord(types.ActivityCoding, "", 1000, 5000),
+		newRankTestRecord(types.ActivityCoding, "", 1000, 5000),
+		newRankTestRecord(types.ActivityDebugging, "", 2000, 2000),
+		newRankTestRecord("", "", 500, 500), // 未標記類型，內容為空時分類為 chat
+	}
+
+	ranks := RankActivitiesByCost(records, cc)
+	if len(ranks) != 3 {
+		t.Fatalf("Expected 3 activity types, got %d", len(ranks))
+	}
+
+	expectedOrder := []types.ActivityType{types.ActivityCoding, types.ActivityDebugging, types.ActivityChat}
+	for i, activityType := range expectedOrder {
+		if ranks[i].ActivityType != activityType {
+			t.Errorf("Expected rank %d to be %s, got %s", i, activityType, ranks[i].ActivityType)
+		}
+	}
+
+	coding := ranks[0]
+	if coding.RecordCount != 2 || coding.TotalTokens != 12000 {
+		t.Errorf("Expected coding to have 2 records and 12000 tokens, got %+v", coding)
+	}
+	if coding.CostPerToken != coding.TotalCost/float64(coding.TotalTokens) {
+		t.Errorf("Expected cost per token to be total cost / total tokens, got %f", coding.CostPerToken)
+	}
+
+	if ranks[2].RecordCount != 2 {
+		t.Errorf("Expected unlabeled record to be classified as chat, got %d chat records", ranks[2].RecordCount)
+	}
+
+	if len(RankActivitiesByCost(nil, cc)) != 0 {
+		t.Errorf("Expected empty ranking for no records")
+	}
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"fmt"
+
+	"token-monitor/internal/cost"
+	"token-monitor/internal/errors"
+	"token-monitor/internal/interfaces"
+)
+
+// EstimationCostBias 以估算與 tiktoken 兩種方法計算文本的 Token 數，並以指定模型的輸入價格計算成本。
+// estCost - tikCost 即為計數方法造成的成本差異（正值表示估算高估）。需要 tiktoken 可用。
+func EstimationCostBias(texts []string, model string, tc interfaces.TokenCalculator, cc *cost.CostCalculatorImpl) (estCost, tikCost float64, err error) {
+	if tc == nil || cc == nil {
+		return 0, 0, fmt.Errorf("token calculator and cost calculator are required")
+	}
+	if !tc.IsTiktokenAvailable() {
+		return 0, 0, errors.New(errors.ErrCodeTiktokenUnavailable, "計算方法成本差異需要 tiktoken")
+	}
+
+	estTokens, tikTokens := 0, 0
+	for i, text := range texts {
+		est, err := tc.CalculateTokens(text, "estimation")
+		if err != nil {
+			return 0, 0, fmt.Errorf("text %d: estimation failed: %w", i, err)
+		}
+		tik, err := tc.CalculateTokens(text, "tiktoken")
+		if err != nil {
+			return 0, 0, fmt.Errorf("text %d: tiktoken failed: %w", i, err)
+		}
+		estTokens += est
+		tikTokens += tik
+	}
+
+	estBreakdown, err := cc.CalculateCost(estTokens, 0, model)
+	if err != nil {
+		return 0, 0, err
+	}
+	tikBreakdown, err := cc.CalculateCost(tikTokens, 0, model)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return estBreakdown.TotalCost, tikBreakdown.TotalCost, nil
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"token-monitor/internal/cost"
+	"token-monitor/internal/errors"
+	"token-monitor/internal/types"
+)
+
+// fakeTokenCalculator 測試用 Token 計算器：估算以字元數計，tiktoken 以單字數計
+type fakeTokenCalculator struct {
+	tiktoken bool
+}
+
+func (f *fakeTokenCalculator) CalculateTokens(text string, method string) (int, error) {
+	if method == "tiktoken" {
+		return len(strings.Fields(text)), nil
+	}
+	return len(text), nil
+}
+
+func (f *fakeTokenCalculator) AnalyzeTokenDistribution(text string) (*types.TokenDistribution, error) {
+	return &types.TokenDistribution{}, nil
+}
+
+func (f *fakeTokenCalculator) IsTiktokenAvailable() bool { return f.tiktoken }
+func (f *fakeTokenCalculator) ClearCache()               {}
+func (f *fakeTokenCalculator) GetSupportedMethods() []string {
+	return []string{"estimation", "tiktoken"}
+}
+
+// TestEstimationCostBias 測試估算與 tiktoken 計數的成本差異
+func TestEstimationCostBias(t *testing.T) {
+	cc := cost.NewCostCalculator()
+	tc := &fakeTokenCalculator{tiktoken: true}
+	texts := []string{"hello world", "one two three"} // 估算 24，tiktoken 5
+
+	estCost, tikCost, err := EstimationCostBias(texts, "claude-sonnet-4.0", tc, cc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expectedEst, _ := cc.CalculateCost(24, 0, "claude-sonnet-4.0")
+	expectedTik, _ := cc.CalculateCost(5, 0, "claude-sonnet-4.0")
+	if math.Abs(estCost-expectedEst.TotalCost) > 1e-12 || math.Abs(tikCost-expectedTik.TotalCost) > 1e-12 {
+		t.Errorf("Expected %.8f/%.8f, got %.8f/%.8f", expectedEst.TotalCost, expectedTik.TotalCost, estCost, tikCost)
+	}
+	if estCost <= tikCost {
+		t.Error("Expected estimation to overcount in this fixture")
+	}
+
+	if _, _, err := EstimationCostBias(texts, "unknown-model", tc, cc); err == nil {
+		t.Error("Expected error for unknown model")
+	}
+
+	_, _, err = EstimationCostBias(texts, "claude-sonnet-4.0", &fakeTokenCalculator{}, cc)
+	if !errors.IsCode(err, errors.ErrCodeTiktokenUnavailable) {
+		t.Errorf("Expected ErrCodeTiktokenUnavailable, got %v", err)
+	}
+}
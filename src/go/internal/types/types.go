@@ -1,6 +1,9 @@
 package types
 
-import "time"
+import (
+	"sort"
+	"time"
+)
 
 // ActivityType 定義活動類型
 type ActivityType string
@@ -14,6 +17,11 @@ const (
 	ActivityTypeChat      ActivityType = "chat" // Alias for consistency
 )
 
+// AllActivityTypes 返回所有已知的活動類型（不含別名）
+func AllActivityTypes() []ActivityType {
+	return []ActivityType{ActivityCoding, ActivityDebugging, ActivityDocumentation, ActivitySpecDev, ActivityChat}
+}
+
 // TokenDistribution Token 分佈資訊
 type TokenDistribution struct {
 	EnglishTokens int    `json:"english_tokens"`
@@ -53,6 +61,18 @@ type ActivitySummary struct {
 	ByType          map[ActivityType]int           `json:"by_type"`        // Keep for backward compatibility
 	TokensByType    map[ActivityType]int           `json:"tokens_by_type"` // Keep for backward compatibility
 	CostByType      map[ActivityType]float64       `json:"cost_by_type"`   // Keep for backward compatibility
+
+	// 各活動類型佔總 Token 數與總活動數的百分比（0-100）
+	TokenPercentages map[ActivityType]float64 `json:"token_percentages"`
+	CountPercentages map[ActivityType]float64 `json:"count_percentages"`
+}
+
+// WeightedActivitySummary 套用活動權重後的摘要統計
+type WeightedActivitySummary struct {
+	ActivitySummary
+	Weights              map[ActivityType]float64 `json:"weights"`
+	WeightedTokensByType map[ActivityType]float64 `json:"weighted_tokens_by_type"`
+	WeightedTokenTotal   float64                  `json:"weighted_token_total"`
 }
 
 // ActivityData 活動資料集合
@@ -70,22 +90,57 @@ type TimeRange struct {
 
 // UsageRecord 使用記錄
 type UsageRecord struct {
-	Timestamp time.Time `json:"timestamp"`
-	SessionID string    `json:"session_id"`
-	Activity  Activity  `json:"activity"`
-	Tokens    struct {
-		Input             int    `json:"input"`
-		Output            int    `json:"output"`
-		Total             int    `json:"total"`
-		CalculationMethod string `json:"calculation_method"`
-	} `json:"tokens"`
-	Cost struct {
-		Input        float64 `json:"input"`
-		Output       float64 `json:"output"`
-		Total        float64 `json:"total"`
-		Currency     string  `json:"currency"`
-		PricingModel string  `json:"pricing_model"`
-	} `json:"cost"`
+	Timestamp time.Time    `json:"timestamp"`
+	SessionID string       `json:"session_id"`
+	Activity  Activity     `json:"activity"`
+	Tokens    RecordTokens `json:"tokens"`
+	Cost      RecordCost   `json:"cost"`
+	Billing   UsageBilling `json:"billing"`
+}
+
+// RecordTokens 使用記錄的 Token 數量
+type RecordTokens struct {
+	Input             int    `json:"input"`
+	Output            int    `json:"output"`
+	Total             int    `json:"total"`
+	CalculationMethod string `json:"calculation_method"`
+}
+
+// NewRecordTokens 建立 Token 數量，Total 為輸入與輸出之和
+func NewRecordTokens(input, output int, method string) RecordTokens {
+	return RecordTokens{
+		Input:             input,
+		Output:            output,
+		Total:             input + output,
+		CalculationMethod: method,
+	}
+}
+
+// RecordCost 使用記錄的成本資訊
+type RecordCost struct {
+	Input        float64 `json:"input"`
+	Output       float64 `json:"output"`
+	Total        float64 `json:"total"`
+	Currency     string  `json:"currency"`
+	PricingModel string  `json:"pricing_model"`
+}
+
+// NewRecordCost 建立成本資訊，Total 為輸入與輸出成本之和
+func NewRecordCost(input, output float64, currency, pricingModel string) RecordCost {
+	return RecordCost{
+		Input:        input,
+		Output:       output,
+		Total:        input + output,
+		Currency:     currency,
+		PricingModel: pricingModel,
+	}
+}
+
+// UsageBilling 使用記錄的快取與批次計費資訊
+type UsageBilling struct {
+	CacheReadTokens  int  `json:"cache_read_tokens,omitempty"`
+	CacheWriteTokens int  `json:"cache_write_tokens,omitempty"`
+	Batch            bool `json:"batch,omitempty"`
 }
 
 // ActivityTotals 活動總和統計
@@ -95,6 +150,9 @@ type ActivityTotals struct {
 	TotalTime       time.Duration                      `json:"total_time"`
 	ByType          map[ActivityType]ActivityTypeTotal `json:"by_type"`
 	CalculatedAt    time.Time                          `json:"calculated_at"`
+
+	// ClampedActivities 時間被截斷的活動數（結束早於開始或超過最長時間）
+	ClampedActivities int `json:"clamped_activities,omitempty"`
 }
 
 // ActivityTypeTotal 活動類型總計
@@ -130,6 +188,8 @@ type EfficiencyMetrics struct {
 	AverageTokensPerActivity map[ActivityType]float64       `json:"average_tokens_per_activity"`
 	AverageTimePerActivity   map[ActivityType]time.Duration `json:"average_time_per_activity"`
 	TokensPerMinute          map[ActivityType]float64       `json:"tokens_per_minute"`
+	// AverageTimePerActivityTrimmed 忽略非正值並去除頭尾極端值後的平均時間
+	AverageTimePerActivityTrimmed map[ActivityType]time.Duration `json:"average_time_per_activity_trimmed"`
 }
 
 // ActivityFrequency 活動頻率分析
@@ -148,6 +208,7 @@ type CostBreakdown struct {
 	CacheReadCost  float64      `json:"cache_read_cost,omitempty"`
 	CacheWriteCost float64      `json:"cache_write_cost,omitempty"`
 	BatchDiscount  float64      `json:"batch_discount,omitempty"`
+	ReasoningCost  float64      `json:"reasoning_cost,omitempty"`
 	TotalCost      float64      `json:"total_cost"`
 	Currency       string       `json:"currency"`
 	PricingModel   string       `json:"pricing_model"`
@@ -156,6 +217,9 @@ type CostBreakdown struct {
 	Timestamp      time.Time    `json:"timestamp"`
 	SessionID      string       `json:"session_id,omitempty"`
 	ActivityType   ActivityType `json:"activity_type,omitempty"`
+
+	// EffectiveDiscountPercent 相對於定價表標準價格的實際折扣百分比（快取與批次優化的總效果）
+	EffectiveDiscountPercent float64 `json:"effective_discount_percent"`
 }
 
 // TokenCounts Token 數量詳細資訊
@@ -164,6 +228,7 @@ type TokenCounts struct {
 	Output     int `json:"output"`
 	CacheRead  int `json:"cache_read,omitempty"`
 	CacheWrite int `json:"cache_write,omitempty"`
+	Reasoning  int `json:"reasoning,omitempty"` // 推理（thinking）Token
 	Total      int `json:"total"`
 }
 
@@ -174,6 +239,7 @@ type CostDetails struct {
 	CacheReadRate  float64 `json:"cache_read_rate,omitempty"`  // USD per 1M tokens
 	CacheWriteRate float64 `json:"cache_write_rate,omitempty"` // USD per 1M tokens
 	DiscountRate   float64 `json:"discount_rate,omitempty"`    // Discount percentage
+	ReasoningRate  float64 `json:"reasoning_rate,omitempty"`   // USD per 1M tokens
 	BillingMode    string  `json:"billing_mode"`               // standard, cache, batch
 }
 
@@ -185,11 +251,20 @@ type PricingModel struct {
 	CacheRead     float64 `json:"cache_read"`     // USD per 1M tokens
 	CacheWrite    float64 `json:"cache_write"`    // USD per 1M tokens
 	BatchDiscount float64 `json:"batch_discount"` // Discount percentage
+	// ReasoningPrice 推理 Token 價格（USD per 1M tokens），為 0 時不另計
+	ReasoningPrice float64 `json:"reasoning_price,omitempty"`
+	// BatchDiscountScope 批次折扣適用範圍：all（預設）、output、input
+	BatchDiscountScope string `json:"batch_discount_scope,omitempty"`
+	// Deprecated 模型已淘汰，使用時發出警告；ReplacedBy 為建議的替代模型
+	Deprecated bool   `json:"deprecated,omitempty"`
+	ReplacedBy string `json:"replaced_by,omitempty"`
 }
 
 // OptimizationSuggestion 優化建議
 type OptimizationSuggestion struct {
+	ID              string  `json:"id,omitempty"` // 由 Type 與 Target 產生的穩定識別碼
 	Type            string  `json:"type"`
+	Target          string  `json:"target,omitempty"` // 建議針對的對象（活動類型、使用模式等）
 	Description     string  `json:"description"`
 	PotentialSaving float64 `json:"potential_saving"`
 	Confidence      float64 `json:"confidence"`
@@ -201,6 +276,21 @@ type OptimizationSuggestions struct {
 	TotalSavings  float64                  `json:"total_savings"`
 	CurrentCost   float64                  `json:"current_cost"`
 	OptimizedCost float64                  `json:"optimized_cost"`
+	// ConfidenceWeightedSavings 依信心度加權的預期節省（PotentialSaving * Confidence 之和）
+	ConfidenceWeightedSavings float64 `json:"confidence_weighted_savings"`
+}
+
+// SuggestionsByType 依建議類型分組，各組維持原始順序
+func (s *OptimizationSuggestions) SuggestionsByType() map[string][]OptimizationSuggestion {
+	grouped := make(map[string][]OptimizationSuggestion)
+	if s == nil {
+		return grouped
+	}
+
+	for _, suggestion := range s.Suggestions {
+		grouped[suggestion.Type] = append(grouped[suggestion.Type], suggestion)
+	}
+	return grouped
 }
 
 // TrendAnalysis 趨勢分析
@@ -241,6 +331,19 @@ type ReportOptions struct {
 	IncludeTrends       bool      `json:"include_trends"`
 	IncludeOptimization bool      `json:"include_optimization"`
 	GroupBy             string    `json:"group_by"`
+
+	// IncludeAllActivityTypes 讓 ByActivity 包含所有已知活動類型（無記錄者為零值）
+	IncludeAllActivityTypes bool `json:"include_all_activity_types,omitempty"`
+
+	// Deduplicate 在彙總成本前移除重複投遞的使用記錄
+	Deduplicate bool `json:"deduplicate,omitempty"`
+
+	// FillGaps 讓成本趨勢在首末區間之間補上零成本資料點
+	FillGaps bool `json:"fill_gaps,omitempty"`
+
+	// 成本報告幣別；FXRates 為各記錄幣別換算為報告幣別的匯率（1 單位記錄幣別 = rate 單位報告幣別）
+	Currency string             `json:"currency,omitempty"`
+	FXRates  map[string]float64 `json:"fx_rates,omitempty"`
 }
 
 // CostTrendAnalysis 成本趨勢分析
@@ -276,17 +379,42 @@ type CostReport struct {
 	Summary      CostSummary                  `json:"summary"`
 	ByActivity   map[ActivityType]CostSummary `json:"by_activity"`
 	ByModel      map[string]CostSummary       `json:"by_model"`
+	ByMethod     map[string]CostSummary       `json:"by_method"` // 依 Token 計算方法（estimation、tiktoken）
 	Optimization *OptimizationSuggestions     `json:"optimization"`
 	Trends       *CostTrendAnalysis           `json:"trends"`
 }
 
+// SortedActivities 返回依字母排序的活動類型，確保輸出順序穩定
+func (r *CostReport) SortedActivities() []ActivityType {
+	activities := make([]ActivityType, 0, len(r.ByActivity))
+	for activityType := range r.ByActivity {
+		activities = append(activities, activityType)
+	}
+	sort.Slice(activities, func(i, j int) bool { return activities[i] < activities[j] })
+	return activities
+}
+
+// SortedModels 返回依字母排序的模型名稱，確保輸出順序穩定
+func (r *CostReport) SortedModels() []string {
+	models := make([]string, 0, len(r.ByModel))
+	for model := range r.ByModel {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+	return models
+}
+
 // CostSummary 成本摘要
 type CostSummary struct {
 	TotalCost            float64 `json:"total_cost"`
 	TotalTokens          int     `json:"total_tokens"`
 	RecordCount          int     `json:"record_count"`
 	AverageCostPerRecord float64 `json:"average_cost_per_record"`
-	AverageCostPerToken  float64 `json:"average_cost_per_token"` // USD per 1M tokens
+	AverageCostPerToken  float64 `json:"average_cost_per_token"`   // USD per 1M tokens
+	CacheCost            float64 `json:"cache_cost,omitempty"`     // 快取讀寫成本
+	NonCacheCost         float64 `json:"non_cache_cost,omitempty"` // 快取以外的成本
+	CacheSavings         float64 `json:"cache_savings,omitempty"`  // 相較以輸入價格計費快取 Token 的節省（寫入溢價可能使其為負）
+	Currency             string  `json:"currency,omitempty"`
 }
 
 // CostEfficiencyAnalysis 成本效率分析